@@ -0,0 +1,352 @@
+package caddy2k8s
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/ysicing/caddy2-gitspace/auth"
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+)
+
+func init() {
+	caddy.RegisterModule(AccessControl{})
+}
+
+const (
+	// shareTokenParam 分享链接中携带令牌的查询参数
+	shareTokenParam = "gitspace_share"
+
+	// shareTokenCookie 保存分享令牌的 Cookie（只对签发的预览域名有效）
+	shareTokenCookie = "gitspace_share"
+)
+
+// 签发分享链接失败的原因，shareErrorStatus 据此返回对应的状态码
+var (
+	// errShareDisabled 未配置 share_token_key
+	errShareDisabled = errors.New("share tokens are not enabled")
+
+	// errShareForbidden 登录用户不是 gitspace 的所有者或用户组成员
+	errShareForbidden = errors.New("not allowed to share this gitspace")
+)
+
+// AccessControl 按 gitspace 可见性和分享令牌控制访问的 HTTP 处理器
+// 由 k8s_router 注入到工作负载路由的最前面，不需要在 Caddyfile 中手动配置
+type AccessControl struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	router *K8sRouter
+	logger *zap.Logger
+}
+
+// CaddyModule 返回模块信息
+func (AccessControl) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.gitspace_access",
+		New: func() caddy.Module { return new(AccessControl) },
+	}
+}
+
+// Provision 获取 k8s_router 应用实例
+func (a *AccessControl) Provision(ctx caddy.Context) error {
+	a.logger = ctx.Logger()
+
+	app, err := ctx.App("k8s_router")
+	if err != nil {
+		return fmt.Errorf("gitspace_access requires the k8s_router app: %w", err)
+	}
+	a.router = app.(*K8sRouter)
+	return nil
+}
+
+// Validate 验证配置
+func (a *AccessControl) Validate() error {
+	if a.Kind == "" || a.Namespace == "" || a.Name == "" {
+		return fmt.Errorf("gitspace_access requires kind, namespace and name")
+	}
+	return nil
+}
+
+// ServeHTTP 检查请求是否可以访问 gitspace，拒绝时不再执行后续处理器
+func (a *AccessControl) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	controller := a.router.controller.Load()
+	if controller == nil {
+		return caddyhttp.Error(http.StatusServiceUnavailable, fmt.Errorf("k8s router is not started"))
+	}
+
+	workload, err := controller.watcher.GetWorkload(a.Kind, a.Namespace, a.Name)
+	if err != nil {
+		return caddyhttp.Error(http.StatusServiceUnavailable, fmt.Errorf("failed to load gitspace: %w", err))
+	}
+
+	handled, err := controller.authorize(w, r, workload, controller.workloadVisibility(workload))
+	if err != nil || handled {
+		return err
+	}
+	return next.ServeHTTP(w, r)
+}
+
+// accessHandlerConfig 返回注入到工作负载路由中的访问控制处理器配置
+func accessHandlerConfig(workload k8s.Workload) map[string]any {
+	return map[string]any{
+		"handler":   "gitspace_access",
+		"kind":      workload.Kind(),
+		"namespace": workload.GetNamespace(),
+		"name":      workload.GetName(),
+	}
+}
+
+// workloadVisibility 返回 gitspace 主域名的可见性，注解无效时按 private 处理
+func (c *routerController) workloadVisibility(workload k8s.Workload) string {
+	visibility, err := k8s.WorkloadVisibility(workload.GetAnnotations(), c.config().Visibility)
+	if err != nil {
+		c.logger.Warn("Invalid visibility annotation, treating gitspace as private",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+		return k8s.VisibilityPrivate
+	}
+	return visibility
+}
+
+// authorize 按可见性检查请求，返回 handled=true 时响应已写入或请求被拒绝
+// public 直接放行；其余可见性需要有效的分享令牌或已登录用户（private 还需是所有者或所属用户组成员）。
+// 查询参数中的令牌校验通过后写入 Cookie 并重定向到去掉令牌的地址，避免令牌留在地址栏和 Referer 中
+func (c *routerController) authorize(w http.ResponseWriter, r *http.Request, workload k8s.Workload, visibility string) (bool, error) {
+	// 用户身份只能来自会话，会话 Cookie 作用于整个 base_domain，不转发给上游
+	r.Header.Del("X-Forwarded-User")
+	r.Header.Del("X-Forwarded-Email")
+	var user *auth.User
+	if c.authenticator != nil {
+		user, _ = c.authenticator.User(r)
+		stripCookie(r, auth.SessionCookie)
+	}
+	if user != nil {
+		setForwardedUser(r, user)
+	}
+
+	// 地址段和客户端证书限制对所有可见性生效，分享令牌和登录都不能绕过
+	if err := c.checkNetworkPolicy(r, workload); err != nil {
+		return true, caddyhttp.Error(http.StatusForbidden, err)
+	}
+
+	if visibility == k8s.VisibilityPublic {
+		return false, nil
+	}
+
+	if token := r.URL.Query().Get(shareTokenParam); token != "" {
+		claims, err := c.verifyShareToken(token, workload, r.Host)
+		if err != nil {
+			return true, caddyhttp.Error(http.StatusForbidden, err)
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareTokenCookie,
+			Value:    token,
+			Path:     "/",
+			Expires:  time.Unix(claims.ExpiresAt, 0),
+			Secure:   r.TLS != nil,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			http.Redirect(w, r, shareRedirectLocation(r.URL), http.StatusSeeOther)
+			return true, nil
+		}
+		stripCookie(r, shareTokenCookie)
+		return false, nil
+	}
+
+	if cookie, err := r.Cookie(shareTokenCookie); err == nil {
+		if _, err := c.verifyShareToken(cookie.Value, workload, r.Host); err == nil {
+			// 分享令牌只用于访问控制，不转发给上游
+			stripCookie(r, shareTokenCookie)
+			return false, nil
+		}
+	}
+
+	if user != nil {
+		if visibility == k8s.VisibilityOrg || k8s.IsGitspaceMember(workload.GetLabels(), user.Name, user.Groups) {
+			return false, nil
+		}
+		return true, caddyhttp.Error(http.StatusForbidden,
+			fmt.Errorf("user %s is not allowed to access %s", user.Name, requestHost(r.Host)))
+	}
+
+	if c.authenticator != nil {
+		return c.startLogin(w, r)
+	}
+
+	return true, caddyhttp.Error(http.StatusForbidden,
+		fmt.Errorf("%s is %s and requires authentication", requestHost(r.Host), visibility))
+}
+
+// shareRedirectLocation 返回去掉分享令牌后的重定向地址
+// 路径开头的多个斜杠合并为一个，避免 //evil.example/x 被浏览器当作其他站点（开放重定向）
+func shareRedirectLocation(u *url.URL) string {
+	query := u.Query()
+	query.Del(shareTokenParam)
+
+	location := "/" + strings.TrimLeft(u.EscapedPath(), "/")
+	if encoded := query.Encode(); encoded != "" {
+		location += "?" + encoded
+	}
+	return location
+}
+
+// verifyShareToken 校验分享令牌的签名、有效期、预览域名和 gitspace 当前的 nonce
+func (c *routerController) verifyShareToken(token string, workload k8s.Workload, host string) (*router.ShareClaims, error) {
+	key := c.config().ShareTokenKey
+	if key == "" {
+		return nil, errShareDisabled
+	}
+
+	claims, err := router.VerifyShareToken([]byte(key), token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if claims.Identifier != k8s.GetGitspaceIdentifier(workload) || claims.Host != requestHost(host) {
+		return nil, fmt.Errorf("%w: issued for another host", router.ErrShareTokenInvalid)
+	}
+	if claims.Nonce != workload.GetAnnotations()[k8s.AnnotationShareNonce] {
+		return nil, fmt.Errorf("%w: revoked", router.ErrShareTokenInvalid)
+	}
+	return claims, nil
+}
+
+// shareLink 签发的分享链接
+type shareLink struct {
+	URL       string    `json:"url"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// shareRequest 签发分享链接的请求
+type shareRequest struct {
+	Identifier string `json:"identifier"`
+	Port       int    `json:"port,omitempty"`
+	TTL        string `json:"ttl,omitempty"`
+}
+
+// issueShareLink 按请求签发分享链接，未指定有效期时使用 share_token_ttl，超过 max_share_token_ttl 时按最长有效期签发
+// user 不为 nil 时只允许 gitspace 所有者和用户组成员签发
+func (c *routerController) issueShareLink(req shareRequest, user *auth.User) (*shareLink, error) {
+	cfg := c.config()
+	ttl := cfg.GetShareTokenTTLDuration()
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid ttl %q", req.TTL)
+		}
+	}
+	ttl = min(ttl, cfg.GetMaxShareTokenTTLDuration())
+
+	return c.mintShareLink(req.Identifier, req.Port, ttl, user)
+}
+
+// shareErrorStatus 返回签发分享链接失败时的响应状态码
+func shareErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errGitspaceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errShareForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// mintShareLink 为 gitspace 主域名（port 为 0）或指定端口签发有效期为 ttl 的分享链接
+// user 不为 nil 时检查其是否为 gitspace 所有者或用户组成员
+func (c *routerController) mintShareLink(identifier string, port int, ttl time.Duration, user *auth.User) (*shareLink, error) {
+	cfg := c.config()
+	if cfg.ShareTokenKey == "" {
+		return nil, errShareDisabled
+	}
+	if identifier == "" {
+		return nil, fmt.Errorf("identifier is required")
+	}
+
+	workload, err := findWorkloadByIdentifier(c.watcher, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if workload == nil {
+		return nil, fmt.Errorf("%w: %s", errGitspaceNotFound, identifier)
+	}
+	if user != nil && !k8s.IsGitspaceMember(workload.GetLabels(), user.Name, user.Groups) {
+		return nil, fmt.Errorf("%w: user %s", errShareForbidden, user.Name)
+	}
+
+	host := fmt.Sprintf("%s.%s", identifier, cfg.BaseDomain)
+	if port != 0 {
+		if !cfg.PortForwarding || !cfg.GetPortForwardingRanges().Contains(port) {
+			return nil, fmt.Errorf("%w: %d", errPortNotAllowed, port)
+		}
+		host = fmt.Sprintf("%d-%s", port, host)
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	token, err := router.SignShareToken([]byte(cfg.ShareTokenKey), router.ShareClaims{
+		Identifier: identifier,
+		Host:       strings.ToLower(host),
+		Nonce:      workload.GetAnnotations()[k8s.AnnotationShareNonce],
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []zap.Field{
+		zap.String("workload", k8s.WorkloadKey(workload)),
+		zap.String("host", host),
+		zap.Time("expires_at", expiresAt),
+	}
+	if user != nil {
+		fields = append(fields, zap.String("user", user.Name))
+	}
+	c.logger.Info("Share link issued", fields...)
+
+	return &shareLink{
+		URL:       fmt.Sprintf("https://%s/?%s=%s", host, shareTokenParam, token),
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// requestHost 返回去掉端口并转为小写的请求 Host
+func requestHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// stripCookie 从请求中移除指定名称的 Cookie
+func stripCookie(r *http.Request, name string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != name {
+			r.AddCookie(cookie)
+		}
+	}
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner           = (*AccessControl)(nil)
+	_ caddy.Validator             = (*AccessControl)(nil)
+	_ caddyhttp.MiddlewareHandler = (*AccessControl)(nil)
+)
@@ -0,0 +1,33 @@
+package caddy2k8s
+
+import (
+	"net/url"
+	"testing"
+)
+
+// TestShareRedirectLocation 测试消费分享令牌后的重定向地址只能指向当前站点
+func TestShareRedirectLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{"只有令牌", "/?gitspace_share=t", "/"},
+		{"保留路径和其他参数", "/files/a%20b?folder=%2Fwork&gitspace_share=t", "/files/a%20b?folder=%2Fwork"},
+		{"双斜杠开头", "//evil.example/x?gitspace_share=t", "/evil.example/x"},
+		{"多个斜杠开头", "///evil.example?gitspace_share=t&a=1", "/evil.example?a=1"},
+		{"反斜杠开头", "/%5Cevil.example?gitspace_share=t", "/%5Cevil.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.ParseRequestURI(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := shareRedirectLocation(u); got != tt.want {
+				t.Errorf("shareRedirectLocation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
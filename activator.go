@@ -0,0 +1,253 @@
+package caddy2k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func init() {
+	caddy.RegisterModule(Activator{})
+}
+
+const (
+	// activatorUpstreamVar gitspace_activator 写入上游地址的请求变量名
+	activatorUpstreamVar = "gitspace_upstream"
+
+	// activatorUpstream 唤醒路由中 reverse_proxy 使用的上游占位符
+	activatorUpstream = "{http.vars." + activatorUpstreamVar + "}"
+
+	// wakePollInterval 唤醒期间轮询工作负载状态的间隔
+	wakePollInterval = 500 * time.Millisecond
+)
+
+// 唤醒失败的原因
+var (
+	// errAutowakeDisabled 工作负载已关闭自动唤醒
+	errAutowakeDisabled = errors.New("autowake is not enabled for workload")
+
+	// errWakeStopped 等待期间工作负载又被缩容到 0
+	errWakeStopped = errors.New("workload was scaled to zero while waking")
+
+	// errWakeNotFound 工作负载不存在或已被删除
+	errWakeNotFound = errors.New("workload not found")
+)
+
+// Activator 唤醒缩容到 0 的工作负载的 HTTP 处理器
+// 由 k8s_router 注入到唤醒路由中，不需要在 Caddyfile 中手动配置
+type Activator struct {
+	// Kind 工作负载类型，默认 Deployment
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name,omitempty"`
+
+	// Deployment 旧版本唤醒路由使用的字段，等同于 Kind=Deployment 时的 Name
+	Deployment string `json:"deployment,omitempty"`
+
+	router      *K8sRouter
+	placeholder *Placeholder
+	logger      *zap.Logger
+}
+
+// CaddyModule 返回模块信息
+func (Activator) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.gitspace_activator",
+		New: func() caddy.Module { return new(Activator) },
+	}
+}
+
+// Provision 获取 k8s_router 应用实例
+func (a *Activator) Provision(ctx caddy.Context) error {
+	a.logger = ctx.Logger()
+
+	app, err := ctx.App("k8s_router")
+	if err != nil {
+		return fmt.Errorf("gitspace_activator requires the k8s_router app: %w", err)
+	}
+	a.router = app.(*K8sRouter)
+
+	// 兼容旧版本写入的唤醒路由
+	if a.Name == "" {
+		a.Name = a.Deployment
+	}
+	if a.Kind == "" {
+		a.Kind = k8s.KindDeployment
+	}
+
+	// 唤醒期间工作负载被停止或删除时使用默认模板渲染占位页面
+	templates, err := parsePlaceholderTemplates(nil)
+	if err != nil {
+		return err
+	}
+	a.placeholder = &Placeholder{router: a.router, templates: templates, logger: a.logger}
+
+	return nil
+}
+
+// Validate 验证配置
+func (a *Activator) Validate() error {
+	if a.Namespace == "" || a.Name == "" {
+		return fmt.Errorf("gitspace_activator requires namespace and name")
+	}
+	if a.Kind != k8s.KindDeployment && a.Kind != k8s.KindStatefulSet {
+		return fmt.Errorf("gitspace_activator: unsupported workload kind %q", a.Kind)
+	}
+	return nil
+}
+
+// ServeHTTP 唤醒工作负载并在就绪后把上游地址交给后续的 reverse_proxy
+func (a *Activator) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	controller := a.router.controller.Load()
+	if controller == nil {
+		return caddyhttp.Error(http.StatusServiceUnavailable, fmt.Errorf("k8s router is not started"))
+	}
+
+	addr, err := controller.eventHandler.WakeWorkload(r.Context(), a.Kind, a.Namespace, a.Name)
+	switch {
+	case errors.Is(err, errWakeStopped):
+		return a.renderPlaceholder(w, r, placeholderStopped)
+	case errors.Is(err, errWakeNotFound):
+		return a.renderPlaceholder(w, r, placeholderNotFound)
+	case err != nil:
+		a.logger.Warn("Failed to wake workload",
+			zap.String("workload", k8s.BuildWorkloadKey(a.Kind, a.Namespace, a.Name)),
+			zap.Error(err),
+		)
+		return caddyhttp.Error(http.StatusServiceUnavailable, err)
+	}
+
+	caddyhttp.SetVar(r.Context(), activatorUpstreamVar, addr)
+	return next.ServeHTTP(w, r)
+}
+
+// renderPlaceholder 渲染工作负载被停止或删除时的占位页面
+func (a *Activator) renderPlaceholder(w http.ResponseWriter, r *http.Request, state string) error {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	identifier, _ := a.placeholder.identifierFromHost(host)
+
+	return a.placeholder.render(w, placeholderData{
+		State:      state,
+		Host:       host,
+		Identifier: identifier,
+		Kind:       a.Kind,
+		Workload:   a.Name,
+	})
+}
+
+// wakeCall 一次正在进行的唤醒，同一工作负载的并发请求共享结果
+type wakeCall struct {
+	done chan struct{}
+	addr string
+	err  error
+}
+
+// WakeWorkload 唤醒工作负载并等待其就绪，返回可代理的上游地址
+// 同一工作负载的并发请求只触发一次扩容，请求在 ctx 结束前排队等待结果
+func (h *EventHandler) WakeWorkload(ctx context.Context, kind, namespace, name string) (string, error) {
+	workloadKey := k8s.BuildWorkloadKey(kind, namespace, name)
+
+	h.wakeMu.Lock()
+	call, exists := h.wakeCalls[workloadKey]
+	if !exists {
+		call = &wakeCall{done: make(chan struct{})}
+		h.wakeCalls[workloadKey] = call
+		h.wg.Go(func() {
+			call.addr, call.err = h.wake(kind, namespace, name)
+
+			h.wakeMu.Lock()
+			delete(h.wakeCalls, workloadKey)
+			h.wakeMu.Unlock()
+			close(call.done)
+		})
+	}
+	h.wakeMu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.addr, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// wake 将工作负载扩容到 1 并轮询直到存在就绪的 Pod，最长等待 wakeTimeout
+// 每次轮询时工作负载已被删除、或扩容后又被缩容到 0 时立即返回，不再等待超时
+func (h *EventHandler) wake(kind, namespace, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(h.ctx, h.settings().wakeTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(wakePollInterval)
+	defer ticker.Stop()
+
+	workloadKey := k8s.BuildWorkloadKey(kind, namespace, name)
+	scaled := false
+	for {
+		workload, err := k8s.GetWorkload(ctx, h.k8sClient, kind, namespace, name)
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("%w: %s", errWakeNotFound, workloadKey)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if !k8s.IsAutowakeEnabled(workload.GetAnnotations()) {
+			return "", errAutowakeDisabled
+		}
+
+		switch replicas := workload.DesiredReplicas(); {
+		case replicas == 0 && !scaled:
+			if err := k8s.ScaleWorkload(ctx, h.k8sClient, kind, namespace, name, 1); err != nil {
+				return "", err
+			}
+			scaled = true
+			h.logger.Info("Waking workload on request",
+				zap.String("workload", workloadKey),
+			)
+
+		case replicas == 0:
+			return "", fmt.Errorf("%w: %s", errWakeStopped, workloadKey)
+
+		case replicas == 1 && h.workloadRoutable(workload):
+			pod, err := h.findReadyPod(workload)
+			if err != nil {
+				return "", err
+			}
+			if pod != nil {
+				port, err := k8s.ResolveWorkloadPort(workload, h.settings().defaultPort)
+				if err != nil {
+					return "", err
+				}
+				return h.podTarget(pod, port), nil
+			}
+
+		case replicas > 1:
+			return "", fmt.Errorf("%s is not single-replica", workloadKey)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for %s to become ready", workloadKey)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner           = (*Activator)(nil)
+	_ caddy.Validator             = (*Activator)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Activator)(nil)
+)
@@ -0,0 +1,63 @@
+package caddy2k8s
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/ysicing/caddy2-gitspace/router"
+)
+
+func init() {
+	caddy.RegisterModule(ActivityRecorder{})
+}
+
+// routeActivity 记录所有生成路由的活动状态
+// 使用包级变量，使其跨越 Caddy 配置重载：每次 Admin API 写入路由都会重建应用和处理器实例，
+// 而旧处理器上仍在进行的 WebSocket 连接需要继续计入同一份状态
+var routeActivity = router.NewActivityTracker()
+
+// ActivityRecorder 记录路由请求与连接活动的 HTTP 处理器
+// 由 k8s_router 注入到生成路由的 reverse_proxy 之前，不需要在 Caddyfile 中手动配置
+type ActivityRecorder struct {
+	RouteID string `json:"route_id"`
+}
+
+// CaddyModule 返回模块信息
+func (ActivityRecorder) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.gitspace_activity",
+		New: func() caddy.Module { return new(ActivityRecorder) },
+	}
+}
+
+// Validate 验证配置
+func (a *ActivityRecorder) Validate() error {
+	if a.RouteID == "" {
+		return fmt.Errorf("gitspace_activity requires route_id")
+	}
+	return nil
+}
+
+// ServeHTTP 在请求（包括升级后的 WebSocket 连接）处理期间计为活跃
+// reverse_proxy 会阻塞到升级连接关闭，因此整个连接生命周期都会被记录
+func (a *ActivityRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	routeActivity.Begin(a.RouteID)
+	defer routeActivity.End(a.RouteID)
+	return next.ServeHTTP(w, r)
+}
+
+// activityHandlerConfig 返回注入到生成路由中的活动记录处理器配置
+func activityHandlerConfig(routeID string) map[string]any {
+	return map[string]any{
+		"handler":  "gitspace_activity",
+		"route_id": routeID,
+	}
+}
+
+// Interface guards
+var (
+	_ caddy.Validator             = (*ActivityRecorder)(nil)
+	_ caddyhttp.MiddlewareHandler = (*ActivityRecorder)(nil)
+)
@@ -0,0 +1,113 @@
+package caddy2k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(AdminGitspace{})
+}
+
+// AdminGitspace 在 Caddy Admin API 上提供 gitspace 管理接口
+// GET /gitspace/upstreams 返回所有生成路由的上游地址和健康状态；
+// POST /gitspace/share 为 gitspace 签发限时分享链接
+type AdminGitspace struct{}
+
+// upstreamStatus 单个路由的上游状态
+type upstreamStatus struct {
+	Workload string `json:"workload"`
+	RouteID  string `json:"route_id"`
+	Target   string `json:"target"`
+	Health   string `json:"health"`
+}
+
+// CaddyModule 返回模块信息
+func (AdminGitspace) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "admin.api.gitspace",
+		New: func() caddy.Module { return new(AdminGitspace) },
+	}
+}
+
+// Routes 返回 Admin API 路由
+func (a AdminGitspace) Routes() []caddy.AdminRoute {
+	return []caddy.AdminRoute{
+		{
+			Pattern: "/gitspace/upstreams",
+			Handler: caddy.AdminHandlerFunc(a.handleUpstreams),
+		},
+		{
+			Pattern: "/gitspace/share",
+			Handler: caddy.AdminHandlerFunc(a.handleShare),
+		},
+	}
+}
+
+// handleUpstreams 返回当前控制器跟踪的路由及其上游健康状态
+func (AdminGitspace) handleUpstreams(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return caddy.APIError{
+			HTTPStatus: http.StatusMethodNotAllowed,
+			Err:        fmt.Errorf("method not allowed"),
+		}
+	}
+
+	statuses := []upstreamStatus{}
+	if c := activeController.Load(); c != nil {
+		for workloadKey, routeInfo := range c.tracker.List() {
+			if routeInfo.TargetAddr == activatorUpstream {
+				continue
+			}
+			statuses = append(statuses, upstreamStatus{
+				Workload: workloadKey,
+				RouteID:  routeInfo.RouteID,
+				Target:   routeInfo.TargetAddr,
+				Health:   upstreamHealth.Status(routeInfo.TargetAddr),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(statuses)
+}
+
+// handleShare 为 gitspace 主域名或指定端口签发分享链接
+func (AdminGitspace) handleShare(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return caddy.APIError{
+			HTTPStatus: http.StatusMethodNotAllowed,
+			Err:        fmt.Errorf("method not allowed"),
+		}
+	}
+
+	c := activeController.Load()
+	if c == nil {
+		return caddy.APIError{
+			HTTPStatus: http.StatusServiceUnavailable,
+			Err:        fmt.Errorf("k8s router is not started"),
+		}
+	}
+
+	var req shareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return caddy.APIError{
+			HTTPStatus: http.StatusBadRequest,
+			Err:        fmt.Errorf("invalid request body: %w", err),
+		}
+	}
+
+	link, err := c.issueShareLink(req, nil)
+	if err != nil {
+		return caddy.APIError{HTTPStatus: shareErrorStatus(err), Err: err}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(link)
+}
+
+// Interface guard
+var _ caddy.AdminRouter = AdminGitspace{}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errInvalidCookie Cookie 签名或格式无效
+var errInvalidCookie = errors.New("invalid signed cookie")
+
+// sealCookie 将 v 编码为 JSON 并用 HMAC-SHA256 签名，格式为 base64url(payload).base64url(signature)
+func sealCookie(key []byte, v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cookie: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(cookieSignature(key, encoded)), nil
+}
+
+// openCookie 校验签名并将内容解码到 v
+func openCookie(key []byte, value string, v any) error {
+	encoded, signature, found := strings.Cut(value, ".")
+	if !found {
+		return errInvalidCookie
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, cookieSignature(key, encoded)) {
+		return errInvalidCookie
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return errInvalidCookie
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return errInvalidCookie
+	}
+	return nil
+}
+
+// cookieSignature 计算 Cookie 内容的 HMAC-SHA256 签名
+func cookieSignature(key []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// SessionCookie 登录会话的 Cookie 名称（作用于 base_domain 及其所有子域名）
+	SessionCookie = "gitspace_session"
+
+	// stateCookie 授权码流程进行中保存 state、nonce 和 PKCE verifier 的 Cookie
+	stateCookie = "gitspace_oidc_state"
+
+	// loginTimeout 从跳转到身份提供方到回调完成的最长时间
+	loginTimeout = 10 * time.Minute
+)
+
+// ErrLoginFailed 授权码回调校验失败
+var ErrLoginFailed = errors.New("oidc login failed")
+
+// Config OIDC 登录配置
+type Config struct {
+	// Issuer 身份提供方的 issuer URL（用于 discovery）
+	Issuer string
+	// ClientID、ClientSecret 在身份提供方注册的客户端凭据
+	ClientID     string
+	ClientSecret string
+	// RedirectURL 授权码回调地址
+	RedirectURL string
+	// Scopes 额外请求的 scope，openid 总是包含在内
+	Scopes []string
+
+	// UsernameClaim 作为用户名的 ID Token claim，为空时使用 preferred_username，缺失时退回 sub
+	UsernameClaim string
+	// GroupsClaim 作为用户组的 ID Token claim，为空时使用 groups
+	GroupsClaim string
+
+	// CookieDomain 会话 Cookie 的 Domain（base_domain）
+	CookieDomain string
+	// CookieSecret 签名会话 Cookie 的 HMAC 密钥
+	CookieSecret []byte
+	// SessionTTL 会话有效期
+	SessionTTL time.Duration
+}
+
+// User 已登录的用户
+type User struct {
+	Name   string   `json:"name"`
+	Email  string   `json:"email,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// session 会话 Cookie 的内容
+type session struct {
+	User
+	ExpiresAt int64 `json:"exp"`
+}
+
+// loginState 授权码流程进行中的状态
+type loginState struct {
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	ReturnTo  string `json:"return_to"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Authenticator 处理 OIDC 授权码流程和会话 Cookie
+// 身份提供方的 discovery 在首次使用时进行，失败后下次请求重试
+type Authenticator struct {
+	config Config
+	secure bool
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// NewAuthenticator 创建 Authenticator
+func NewAuthenticator(config Config) (*Authenticator, error) {
+	redirect, err := url.Parse(config.RedirectURL)
+	if err != nil || redirect.Host == "" {
+		return nil, fmt.Errorf("invalid oidc redirect url %q", config.RedirectURL)
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "preferred_username"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	return &Authenticator{
+		config: config,
+		secure: redirect.Scheme == "https",
+	}, nil
+}
+
+// User 返回请求会话 Cookie 对应的用户，未登录或会话过期时返回 false
+func (a *Authenticator) User(r *http.Request) (*User, bool) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil, false
+	}
+	var s session
+	if err := openCookie(a.config.CookieSecret, cookie.Value, &s); err != nil {
+		return nil, false
+	}
+	if time.Now().Unix() >= s.ExpiresAt {
+		return nil, false
+	}
+	return &s.User, true
+}
+
+// StartLogin 将浏览器重定向到身份提供方，登录完成后回到 returnTo
+func (a *Authenticator) StartLogin(w http.ResponseWriter, r *http.Request, returnTo string) error {
+	provider, err := a.oidcProvider(r.Context())
+	if err != nil {
+		return err
+	}
+
+	state := loginState{
+		State:     randomString(),
+		Nonce:     randomString(),
+		Verifier:  oauth2.GenerateVerifier(),
+		ReturnTo:  returnTo,
+		ExpiresAt: time.Now().Add(loginTimeout).Unix(),
+	}
+	value, err := sealCookie(a.config.CookieSecret, state)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, a.cookie(stateCookie, value, time.Unix(state.ExpiresAt, 0)))
+
+	authURL := a.oauth2Config(provider).AuthCodeURL(state.State,
+		oidc.Nonce(state.Nonce),
+		oauth2.S256ChallengeOption(state.Verifier),
+	)
+	http.Redirect(w, r, authURL, http.StatusFound)
+	return nil
+}
+
+// HandleCallback 处理授权码回调：校验 state、换取并验证 ID Token，写入会话 Cookie 后重定向回原地址
+func (a *Authenticator) HandleCallback(w http.ResponseWriter, r *http.Request) (*User, error) {
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing login state", ErrLoginFailed)
+	}
+	var state loginState
+	if err := openCookie(a.config.CookieSecret, cookie.Value, &state); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+	if time.Now().Unix() >= state.ExpiresAt || r.URL.Query().Get("state") != state.State {
+		return nil, fmt.Errorf("%w: state mismatch", ErrLoginFailed)
+	}
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrLoginFailed, errCode, r.URL.Query().Get("error_description"))
+	}
+
+	provider, err := a.oidcProvider(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	token, err := a.oauth2Config(provider).Exchange(r.Context(), r.URL.Query().Get("code"),
+		oauth2.VerifierOption(state.Verifier),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to exchange code: %v", ErrLoginFailed, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrLoginFailed)
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: a.config.ClientID}).Verify(r.Context(), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+	if idToken.Nonce != state.Nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrLoginFailed)
+	}
+
+	user, err := a.userFromToken(idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(a.config.SessionTTL)
+	value, err := sealCookie(a.config.CookieSecret, session{User: *user, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return nil, err
+	}
+	http.SetCookie(w, a.cookie(SessionCookie, value, expiresAt))
+	http.SetCookie(w, a.cookie(stateCookie, "", time.Unix(0, 0)))
+
+	http.Redirect(w, r, state.ReturnTo, http.StatusSeeOther)
+	return user, nil
+}
+
+// Logout 清除会话 Cookie
+func (a *Authenticator) Logout(w http.ResponseWriter) {
+	http.SetCookie(w, a.cookie(SessionCookie, "", time.Unix(0, 0)))
+}
+
+// AllowedReturnTo 登录完成后的跳转地址是否为 base_domain 下的域名，避免开放重定向
+func (a *Authenticator) AllowedReturnTo(returnTo string) bool {
+	u, err := url.Parse(returnTo)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == a.config.CookieDomain || strings.HasSuffix(host, "."+a.config.CookieDomain)
+}
+
+// userFromToken 从 ID Token 中读取用户名、邮箱和用户组
+func (a *Authenticator) userFromToken(idToken *oidc.IDToken) (*User, error) {
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode claims: %v", ErrLoginFailed, err)
+	}
+
+	user := &User{}
+	user.Name, _ = claims[a.config.UsernameClaim].(string)
+	if user.Name == "" {
+		user.Name = idToken.Subject
+	}
+	user.Email, _ = claims["email"].(string)
+
+	switch groups := claims[a.config.GroupsClaim].(type) {
+	case []any:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				user.Groups = append(user.Groups, name)
+			}
+		}
+	case string:
+		user.Groups = []string{groups}
+	}
+	return user, nil
+}
+
+// oidcProvider 返回身份提供方，首次调用时进行 discovery
+func (a *Authenticator) oidcProvider(ctx context.Context) (*oidc.Provider, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.provider != nil {
+		return a.provider, nil
+	}
+	// provider 会保留 discovery 使用的 context 获取签名密钥，不能随请求取消
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), a.config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider %s: %w", a.config.Issuer, err)
+	}
+	a.provider = provider
+	return provider, nil
+}
+
+// oauth2Config 返回授权码流程的 OAuth2 配置
+func (a *Authenticator) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     a.config.ClientID,
+		ClientSecret: a.config.ClientSecret,
+		RedirectURL:  a.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, a.config.Scopes...),
+	}
+}
+
+// cookie 构造作用于 base_domain 的 Cookie，expires 早于当前时间时删除 Cookie
+func (a *Authenticator) cookie(name, value string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   a.config.CookieDomain,
+		Expires:  expires,
+		Secure:   a.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if !expires.After(time.Now()) {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+// randomString 返回 URL 安全的随机字符串
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
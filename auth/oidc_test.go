@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// standInProvider 测试用的本地 OIDC 身份提供方
+// 支持 discovery、JWKS 和授权码换取 ID Token（校验 PKCE）
+type standInProvider struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+}
+
+// issuedCode 已签发的授权码
+type issuedCode struct {
+	challenge string
+	claims    map[string]any
+}
+
+// newStandInProvider 启动本地身份提供方
+func newStandInProvider(t *testing.T, clientID string) *standInProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	p := &standInProvider{key: key, clientID: clientID, codes: make(map[string]issuedCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		p.mu.Lock()
+		code, ok := p.codes[r.PostForm.Get("code")]
+		delete(p.codes, r.PostForm.Get("code"))
+		p.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeJSON(w, map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.sign(t, code.claims),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize 模拟用户在身份提供方登录，返回授权码
+func (p *standInProvider) authorize(t *testing.T, authURL string, claims map[string]any) (code, state string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil || !strings.HasPrefix(authURL, p.URL+"/authorize") {
+		t.Fatalf("unexpected authorization url %q", authURL)
+	}
+	query := u.Query()
+	if query.Get("client_id") != p.clientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request %q", authURL)
+	}
+
+	idClaims := map[string]any{
+		"iss":   p.URL,
+		"aud":   p.clientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range claims {
+		idClaims[k] = v
+	}
+
+	code = "code-" + query.Get("state")
+	p.mu.Lock()
+	p.codes[code] = issuedCode{challenge: query.Get("code_challenge"), claims: idClaims}
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// sign 用 RS256 签发 ID Token
+func (p *standInProvider) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	sum := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatalf("failed to sign id token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// responseCookie 从响应中取出指定 Cookie
+func responseCookie(t *testing.T, rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	t.Helper()
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	t.Fatalf("response has no %s cookie", name)
+	return nil
+}
+
+// TestAuthorizationCodeFlow 测试完整的授权码流程：跳转登录、回调换取 ID Token、会话 Cookie
+func TestAuthorizationCodeFlow(t *testing.T) {
+	provider := newStandInProvider(t, "gitspace")
+	a, err := NewAuthenticator(Config{
+		Issuer:       provider.URL,
+		ClientID:     "gitspace",
+		ClientSecret: "secret",
+		RedirectURL:  "https://auth.example.com/_gitspace/oidc/callback",
+		CookieDomain: "example.com",
+		CookieSecret: []byte("0123456789abcdef0123456789abcdef"),
+		SessionTTL:   time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	// 1. 未登录的请求跳转到身份提供方
+	returnTo := "https://vscode.example.com/workspace?folder=src"
+	rec := httptest.NewRecorder()
+	if err := a.StartLogin(rec, httptest.NewRequest(http.MethodGet, returnTo, nil), returnTo); err != nil {
+		t.Fatalf("StartLogin() error = %v", err)
+	}
+	if rec.Code != http.StatusFound {
+		t.Fatalf("StartLogin() status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	stateCookie := responseCookie(t, rec, stateCookie)
+	if stateCookie.Domain != "example.com" || !stateCookie.Secure {
+		t.Errorf("state cookie = %+v, want secure cookie for example.com", stateCookie)
+	}
+
+	// 2. 用户在身份提供方登录后带着授权码回调
+	code, state := provider.authorize(t, rec.Header().Get("Location"), map[string]any{
+		"sub":                "u-1",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"groups":             []string{"platform", "dev"},
+	})
+
+	callback := func(state string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodGet,
+			"https://auth.example.com/_gitspace/oidc/callback?code="+code+"&state="+state, nil)
+		req.AddCookie(stateCookie)
+		rec := httptest.NewRecorder()
+		_, err := a.HandleCallback(rec, req)
+		return rec, err
+	}
+
+	if _, err := callback("forged"); err == nil {
+		t.Fatal("Expected callback with mismatched state to fail")
+	}
+
+	rec, err = callback(state)
+	if err != nil {
+		t.Fatalf("HandleCallback() error = %v", err)
+	}
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != returnTo {
+		t.Fatalf("HandleCallback() = %d %q, want redirect to %q", rec.Code, rec.Header().Get("Location"), returnTo)
+	}
+
+	// 3. 会话 Cookie 对 base_domain 下的所有 gitspace 域名有效
+	session := responseCookie(t, rec, SessionCookie)
+	req := httptest.NewRequest(http.MethodGet, "https://3000-vscode.example.com/", nil)
+	req.AddCookie(session)
+	user, ok := a.User(req)
+	if !ok {
+		t.Fatal("Expected session cookie to authenticate the user")
+	}
+	if user.Name != "alice" || user.Email != "alice@example.com" || strings.Join(user.Groups, ",") != "platform,dev" {
+		t.Errorf("User() = %+v", user)
+	}
+
+	// 授权码只能使用一次
+	if _, err := callback(state); err == nil {
+		t.Error("Expected replayed authorization code to fail")
+	}
+
+	// 篡改的会话 Cookie 无效
+	session.Value = "e30" + session.Value[3:]
+	req = httptest.NewRequest(http.MethodGet, "https://vscode.example.com/", nil)
+	req.AddCookie(session)
+	if _, ok := a.User(req); ok {
+		t.Error("Expected tampered session cookie to be rejected")
+	}
+}
+
+// TestAllowedReturnTo 测试登录后跳转地址只能是 base_domain 下的域名
+func TestAllowedReturnTo(t *testing.T) {
+	a, err := NewAuthenticator(Config{RedirectURL: "https://auth.example.com/cb", CookieDomain: "example.com"})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	tests := []struct {
+		returnTo string
+		want     bool
+	}{
+		{"https://vscode.example.com/", true},
+		{"https://3000-vscode.example.com/path", true},
+		{"https://evil.com/?example.com", false},
+		{"https://example.com.evil.com/", false},
+		{"javascript:alert(1)", false},
+	}
+	for _, tt := range tests {
+		if got := a.AllowedReturnTo(tt.returnTo); got != tt.want {
+			t.Errorf("AllowedReturnTo(%q) = %v, want %v", tt.returnTo, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -33,6 +34,143 @@ type Config struct {
 
 	// CaddyServerName Caddy Server 名称
 	CaddyServerName string `json:"caddy_server_name,omitempty"`
+
+	// WakeTimeout 自动唤醒时请求等待 Deployment 就绪的最长时间
+	WakeTimeout string `json:"wake_timeout,omitempty"`
+
+	// IdleCheckPeriod 写回最近活动时间并检查空闲缩容的周期
+	IdleCheckPeriod string `json:"idle_check_period,omitempty"`
+
+	// DrainPeriod 路由被替换或删除时保留进行中连接的最长时间，0 表示立即切换
+	DrainPeriod string `json:"drain_period,omitempty"`
+
+	// UnreadyGracePeriod 工作负载变为未就绪后保留路由的宽限期，期间恢复就绪则不删除路由
+	UnreadyGracePeriod string `json:"unready_grace_period,omitempty"`
+
+	// MinRouteChangeInterval 同一工作负载两次路由变化（创建、切换上游、删除）之间的最小间隔
+	MinRouteChangeInterval string `json:"min_route_change_interval,omitempty"`
+
+	// KeepUnreadyPods 路由指向的 Pod 仍在运行但暂时未就绪时保留路由，并标记为降级
+	KeepUnreadyPods bool `json:"keep_unready_pods,omitempty"`
+
+	// HealthCheckPath 生成路由的主动健康检查路径，为空时不启用主动检查
+	HealthCheckPath string `json:"health_check_path,omitempty"`
+
+	// HealthCheckInterval 主动健康检查的间隔
+	HealthCheckInterval string `json:"health_check_interval,omitempty"`
+
+	// HealthCheckStatus 主动健康检查期望的状态码，0 表示 2xx
+	HealthCheckStatus int `json:"health_check_status,omitempty"`
+
+	// HealthCheckFailDuration 被动健康检查记住一次失败的时长，0 表示不启用被动检查
+	HealthCheckFailDuration string `json:"health_check_fail_duration,omitempty"`
+
+	// HealthCheckUnhealthyStatus 被动健康检查视为失败的响应状态码
+	HealthCheckUnhealthyStatus []int `json:"health_check_unhealthy_status,omitempty"`
+
+	// Visibility 未设置可见性注解的 gitspace 主域名的默认可见性：private、org、public
+	Visibility string `json:"visibility,omitempty"`
+
+	// ShareTokenKey 签发和校验分享令牌的 HMAC 密钥，为空时不支持分享令牌
+	ShareTokenKey string `json:"share_token_key,omitempty"`
+
+	// ShareTokenTTL 签发分享令牌时未指定有效期使用的默认有效期
+	ShareTokenTTL string `json:"share_token_ttl,omitempty"`
+
+	// MaxShareTokenTTL 分享令牌的最长有效期，请求的有效期超过时按该值签发
+	MaxShareTokenTTL string `json:"max_share_token_ttl,omitempty"`
+
+	// OIDCIssuer OIDC 身份提供方的 issuer URL，为空时不启用登录
+	OIDCIssuer string `json:"oidc_issuer,omitempty"`
+
+	// OIDCClientID、OIDCClientSecret 在身份提供方注册的客户端凭据
+	OIDCClientID     string `json:"oidc_client_id,omitempty"`
+	OIDCClientSecret string `json:"oidc_client_secret,omitempty"`
+
+	// OIDCRedirectURL 授权码回调地址，默认 https://auth.<base_domain>/_gitspace/oidc/callback
+	OIDCRedirectURL string `json:"oidc_redirect_url,omitempty"`
+
+	// OIDCScopes 除 openid 外额外请求的 scope
+	OIDCScopes []string `json:"oidc_scopes,omitempty"`
+
+	// OIDCUsernameClaim、OIDCGroupsClaim 用户名和用户组的 ID Token claim
+	OIDCUsernameClaim string `json:"oidc_username_claim,omitempty"`
+	OIDCGroupsClaim   string `json:"oidc_groups_claim,omitempty"`
+
+	// OIDCCookieSecret 签名会话 Cookie 的 HMAC 密钥
+	OIDCCookieSecret string `json:"oidc_cookie_secret,omitempty"`
+
+	// OIDCSessionTTL 登录会话的有效期
+	OIDCSessionTTL string `json:"oidc_session_ttl,omitempty"`
+
+	// ClientCAFiles 校验客户端证书的 CA（PEM 文件），声明 require-client-cert 注解的 gitspace 需要
+	ClientCAFiles []string `json:"client_ca_files,omitempty"`
+
+	// RateLimitClient 每个客户端 IP 访问单个 gitspace 的限流参数（"<rps>[,<burst>]"），为空时不限流
+	RateLimitClient string `json:"rate_limit_client,omitempty"`
+
+	// RateLimitRoute 单个 gitspace 所有请求的限流参数（"<rps>[,<burst>]"），为空时不限流
+	RateLimitRoute string `json:"rate_limit_route,omitempty"`
+
+	// BandwidthLimit 单个 gitspace 响应的总带宽上限（每秒字节数，如 "5MB"），为空时不限速
+	BandwidthLimit string `json:"bandwidth_limit,omitempty"`
+
+	// UpstreamTLS 生成的工作负载路由是否使用 HTTPS 和客户端证书访问上游
+	UpstreamTLS bool `json:"upstream_tls,omitempty"`
+
+	// UpstreamTLSCA 签发上游证书的 pki CA ID，默认为 local
+	UpstreamTLSCA string `json:"upstream_tls_ca,omitempty"`
+
+	// UpstreamTLSLifetime 签发的 gitspace 和客户端证书有效期，剩余不足三分之一时轮换
+	UpstreamTLSLifetime string `json:"upstream_tls_lifetime,omitempty"`
+
+	// PortForwarding 是否开启按需端口转发（<port>-<identifier>.<base_domain>）
+	PortForwarding bool `json:"port_forwarding,omitempty"`
+
+	// PortForwardingPorts 允许转发的端口和端口范围（如 "80,3000-9999"）
+	PortForwardingPorts string `json:"port_forwarding_ports,omitempty"`
+
+	// PortForwardingVisibility 未通过注解声明可见性的端口使用的默认可见性：private、org、public
+	PortForwardingVisibility string `json:"port_forwarding_visibility,omitempty"`
+
+	// IPFamily 双栈集群中选择 Pod IP 的地址族偏好：ipv4、ipv6、any（使用 Pod 主 IP）
+	IPFamily string `json:"ip_family,omitempty"`
+
+	// UseFinalizers 是否为工作负载添加 finalizer，保证删除工作负载前先删除路由
+	UseFinalizers bool `json:"use_finalizers,omitempty"`
+
+	// FinalizerTimeout 删除路由持续失败时，超过该时长后强制移除 finalizer
+	FinalizerTimeout string `json:"finalizer_timeout,omitempty"`
+
+	// ReadinessProbePath 设置 route-ready 条件前探测上游的 HTTP 路径，为空时不探测
+	ReadinessProbePath string `json:"readiness_probe_path,omitempty"`
+
+	// ReadinessProbeTimeout 上游探测的最长等待时间，超时后 route-ready 条件保持 False
+	ReadinessProbeTimeout string `json:"readiness_probe_timeout,omitempty"`
+
+	// WatchIngress 是否将 IngressClass 为 IngressClass 的 Ingress 转换为路由
+	WatchIngress bool `json:"watch_ingress,omitempty"`
+
+	// IngressClass 处理的 Ingress 的 ingressClassName
+	IngressClass string `json:"ingress_class,omitempty"`
+
+	// IngressStatusAddress 写回 Ingress status.loadBalancer 的地址（IP 或主机名），为空时不写回
+	IngressStatusAddress string `json:"ingress_status_address,omitempty"`
+
+	// WatchHTTPRoutes 是否将挂载到 GatewayName 的 Gateway API HTTPRoute 转换为路由
+	WatchHTTPRoutes bool `json:"watch_httproutes,omitempty"`
+
+	// GatewayName HTTPRoute parentRefs 中引用的 Gateway 名称
+	GatewayName string `json:"gateway_name,omitempty"`
+
+	// IdentifierSource gitspace identifier 的来源：label（默认）、annotation、name
+	IdentifierSource string `json:"identifier_source,omitempty"`
+
+	// IdentifierKey 读取 identifier 的 label 或注解键（label 默认为 "gitspace"）
+	IdentifierKey string `json:"identifier_key,omitempty"`
+
+	// IdentifierNameSuffix 来源为 name 时从工作负载名称中去掉的后缀（正则表达式）
+	IdentifierNameSuffix string `json:"identifier_name_suffix,omitempty"`
 }
 
 // Validate 验证配置有效性
@@ -96,6 +234,269 @@ func (c *Config) Validate() error {
 		c.CaddyServerName = "srv0"
 	}
 
+	// 验证 WakeTimeout 格式
+	if c.WakeTimeout != "" {
+		if d, err := time.ParseDuration(c.WakeTimeout); err != nil {
+			return fmt.Errorf("invalid wake_timeout format: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("wake_timeout must be positive, got %s", c.WakeTimeout)
+		}
+	} else {
+		// 设置默认唤醒超时为 2 分钟
+		c.WakeTimeout = "2m"
+	}
+
+	// 验证 IdleCheckPeriod 格式
+	if c.IdleCheckPeriod != "" {
+		if d, err := time.ParseDuration(c.IdleCheckPeriod); err != nil {
+			return fmt.Errorf("invalid idle_check_period format: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("idle_check_period must be positive, got %s", c.IdleCheckPeriod)
+		}
+	} else {
+		// 设置默认空闲检查周期为 1 分钟
+		c.IdleCheckPeriod = "1m"
+	}
+
+	// 验证 DrainPeriod 格式
+	if c.DrainPeriod != "" {
+		if d, err := time.ParseDuration(c.DrainPeriod); err != nil {
+			return fmt.Errorf("invalid drain_period format: %w", err)
+		} else if d < 0 {
+			return fmt.Errorf("drain_period must not be negative, got %s", c.DrainPeriod)
+		}
+	} else {
+		// 默认不排空，保持立即切换的行为
+		c.DrainPeriod = "0s"
+	}
+
+	// 验证 UnreadyGracePeriod 和 MinRouteChangeInterval 格式，默认不抑制抖动
+	if c.UnreadyGracePeriod != "" {
+		if d, err := time.ParseDuration(c.UnreadyGracePeriod); err != nil {
+			return fmt.Errorf("invalid unready_grace_period format: %w", err)
+		} else if d < 0 {
+			return fmt.Errorf("unready_grace_period must not be negative, got %s", c.UnreadyGracePeriod)
+		}
+	} else {
+		c.UnreadyGracePeriod = "0s"
+	}
+	if c.MinRouteChangeInterval != "" {
+		if d, err := time.ParseDuration(c.MinRouteChangeInterval); err != nil {
+			return fmt.Errorf("invalid min_route_change_interval format: %w", err)
+		} else if d < 0 {
+			return fmt.Errorf("min_route_change_interval must not be negative, got %s", c.MinRouteChangeInterval)
+		}
+	} else {
+		c.MinRouteChangeInterval = "0s"
+	}
+
+	// 验证健康检查配置
+	if c.HealthCheckPath != "" && !strings.HasPrefix(c.HealthCheckPath, "/") {
+		return fmt.Errorf("health_check_path must start with /, got %s", c.HealthCheckPath)
+	}
+	if c.HealthCheckInterval != "" {
+		if d, err := time.ParseDuration(c.HealthCheckInterval); err != nil {
+			return fmt.Errorf("invalid health_check_interval format: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("health_check_interval must be positive, got %s", c.HealthCheckInterval)
+		}
+	} else {
+		// 设置默认主动健康检查间隔为 10 秒
+		c.HealthCheckInterval = "10s"
+	}
+	if c.HealthCheckStatus < 0 || c.HealthCheckStatus > 599 {
+		return fmt.Errorf("health_check_status out of range, got %d", c.HealthCheckStatus)
+	}
+	if c.HealthCheckFailDuration != "" {
+		if d, err := time.ParseDuration(c.HealthCheckFailDuration); err != nil {
+			return fmt.Errorf("invalid health_check_fail_duration format: %w", err)
+		} else if d < 0 {
+			return fmt.Errorf("health_check_fail_duration must not be negative, got %s", c.HealthCheckFailDuration)
+		}
+	} else {
+		c.HealthCheckFailDuration = "0s"
+	}
+	for _, status := range c.HealthCheckUnhealthyStatus {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("invalid health_check_unhealthy_status code: %d", status)
+		}
+	}
+
+	// 验证默认可见性和分享令牌配置
+	switch c.Visibility {
+	case "":
+		c.Visibility = "public"
+	case "private", "org", "public":
+	default:
+		return fmt.Errorf("visibility must be one of private, org, public, got %s", c.Visibility)
+	}
+	if c.ShareTokenKey != "" && len(c.ShareTokenKey) < 32 {
+		return fmt.Errorf("share_token_key must be at least 32 bytes")
+	}
+	if c.ShareTokenTTL != "" {
+		if d, err := time.ParseDuration(c.ShareTokenTTL); err != nil {
+			return fmt.Errorf("invalid share_token_ttl format: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("share_token_ttl must be positive, got %s", c.ShareTokenTTL)
+		}
+	} else {
+		// 设置默认分享令牌有效期为 24 小时
+		c.ShareTokenTTL = "24h"
+	}
+	if c.MaxShareTokenTTL != "" {
+		if d, err := time.ParseDuration(c.MaxShareTokenTTL); err != nil {
+			return fmt.Errorf("invalid max_share_token_ttl format: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("max_share_token_ttl must be positive, got %s", c.MaxShareTokenTTL)
+		}
+	} else {
+		// 设置默认分享令牌最长有效期为 7 天
+		c.MaxShareTokenTTL = "168h"
+	}
+	if c.GetShareTokenTTLDuration() > c.GetMaxShareTokenTTLDuration() {
+		return fmt.Errorf("share_token_ttl (%s) must not exceed max_share_token_ttl (%s)", c.ShareTokenTTL, c.MaxShareTokenTTL)
+	}
+
+	// 验证 OIDC 登录配置
+	if c.OIDCIssuer != "" {
+		if c.OIDCClientID == "" {
+			return fmt.Errorf("oidc_client_id is required when oidc_issuer is set")
+		}
+		if len(c.OIDCCookieSecret) < 32 {
+			return fmt.Errorf("oidc_cookie_secret must be at least 32 bytes")
+		}
+		if c.OIDCRedirectURL == "" {
+			c.OIDCRedirectURL = "https://auth." + c.BaseDomain + "/_gitspace/oidc/callback"
+		}
+		if u, err := url.Parse(c.OIDCRedirectURL); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid oidc_redirect_url: %s", c.OIDCRedirectURL)
+		}
+		if c.OIDCSessionTTL != "" {
+			if d, err := time.ParseDuration(c.OIDCSessionTTL); err != nil {
+				return fmt.Errorf("invalid oidc_session_ttl format: %w", err)
+			} else if d <= 0 {
+				return fmt.Errorf("oidc_session_ttl must be positive, got %s", c.OIDCSessionTTL)
+			}
+		} else {
+			// 设置默认会话有效期为 12 小时
+			c.OIDCSessionTTL = "12h"
+		}
+	}
+
+	// 验证限流和带宽配置
+	if c.RateLimitClient != "" {
+		if _, err := ParseRateLimit(c.RateLimitClient); err != nil {
+			return fmt.Errorf("invalid rate_limit_client: %w", err)
+		}
+	}
+	if c.RateLimitRoute != "" {
+		if _, err := ParseRateLimit(c.RateLimitRoute); err != nil {
+			return fmt.Errorf("invalid rate_limit_route: %w", err)
+		}
+	}
+	if c.BandwidthLimit != "" {
+		if _, err := ParseBandwidth(c.BandwidthLimit); err != nil {
+			return fmt.Errorf("invalid bandwidth_limit: %w", err)
+		}
+	}
+
+	// 验证上游 TLS 的 CA 和证书有效期
+	if c.UpstreamTLS {
+		if c.UpstreamTLSCA == "" {
+			c.UpstreamTLSCA = "local"
+		}
+		if c.UpstreamTLSLifetime != "" {
+			if d, err := time.ParseDuration(c.UpstreamTLSLifetime); err != nil {
+				return fmt.Errorf("invalid upstream_tls_lifetime format: %w", err)
+			} else if d < time.Hour {
+				return fmt.Errorf("upstream_tls_lifetime must be at least 1h, got %s", c.UpstreamTLSLifetime)
+			}
+		} else {
+			// 设置默认证书有效期为 24 小时
+			c.UpstreamTLSLifetime = "24h"
+		}
+	}
+
+	// 验证端口转发白名单和默认可见性
+	if c.PortForwardingPorts == "" {
+		c.PortForwardingPorts = "1024-65535"
+	}
+	if _, err := ParsePortRanges(c.PortForwardingPorts); err != nil {
+		return fmt.Errorf("invalid port_forwarding_ports: %w", err)
+	}
+	switch c.PortForwardingVisibility {
+	case "":
+		c.PortForwardingVisibility = "private"
+	case "private", "org", "public":
+	default:
+		return fmt.Errorf("port_forwarding_visibility must be one of private, org, public, got %s", c.PortForwardingVisibility)
+	}
+
+	// 验证 IPFamily，默认使用 Pod 主 IP
+	switch c.IPFamily {
+	case "":
+		c.IPFamily = "any"
+	case "any", "ipv4", "ipv6":
+	default:
+		return fmt.Errorf("ip_family must be one of ipv4, ipv6, any, got %s", c.IPFamily)
+	}
+
+	// 验证 FinalizerTimeout 格式
+	if c.FinalizerTimeout != "" {
+		if d, err := time.ParseDuration(c.FinalizerTimeout); err != nil {
+			return fmt.Errorf("invalid finalizer_timeout format: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("finalizer_timeout must be positive, got %s", c.FinalizerTimeout)
+		}
+	} else {
+		// 设置默认强制移除超时为 10 分钟
+		c.FinalizerTimeout = "10m"
+	}
+
+	// 验证 ReadinessProbePath 和 ReadinessProbeTimeout
+	if c.ReadinessProbePath != "" && !strings.HasPrefix(c.ReadinessProbePath, "/") {
+		return fmt.Errorf("readiness_probe_path must start with /, got %s", c.ReadinessProbePath)
+	}
+	if c.ReadinessProbeTimeout != "" {
+		if d, err := time.ParseDuration(c.ReadinessProbeTimeout); err != nil {
+			return fmt.Errorf("invalid readiness_probe_timeout format: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("readiness_probe_timeout must be positive, got %s", c.ReadinessProbeTimeout)
+		}
+	} else {
+		// 设置默认探测超时为 30 秒
+		c.ReadinessProbeTimeout = "30s"
+	}
+
+	// 设置默认 IngressClass 和 Gateway 名称
+	if c.IngressClass == "" {
+		c.IngressClass = "caddy-gitspace"
+	}
+	if c.GatewayName == "" {
+		c.GatewayName = "caddy-gitspace"
+	}
+
+	// 验证 identifier 来源
+	switch c.IdentifierSource {
+	case "", "label":
+		c.IdentifierSource = "label"
+		if c.IdentifierKey == "" {
+			c.IdentifierKey = "gitspace"
+		}
+	case "annotation":
+		if c.IdentifierKey == "" {
+			return fmt.Errorf("identifier_key is required when identifier_source is annotation")
+		}
+	case "name":
+		if c.IdentifierNameSuffix != "" {
+			if _, err := regexp.Compile(c.IdentifierNameSuffix); err != nil {
+				return fmt.Errorf("invalid identifier_name_suffix: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("identifier_source must be one of label, annotation, name, got %s", c.IdentifierSource)
+	}
+
 	return nil
 }
 
@@ -113,6 +514,71 @@ func Load(data []byte) (*Config, error) {
 	return &config, nil
 }
 
+// WatchKey 返回必须重建控制器才能生效的设置
+// 包括 Kubernetes 连接和监听范围、Admin API 地址、OIDC 登录、上游证书签发、
+// identifier 来源以及后台循环周期；其余设置可直接交给运行中的控制器
+func (c *Config) WatchKey() (string, error) {
+	key, err := json.Marshal(struct {
+		Namespace            string   `json:"namespace"`
+		BaseDomain           string   `json:"base_domain"`
+		KubeConfig           string   `json:"kubeconfig"`
+		ResyncPeriod         string   `json:"resync_period"`
+		ReconcilePeriod      string   `json:"reconcile_period"`
+		IdleCheckPeriod      string   `json:"idle_check_period"`
+		CaddyAdminURL        string   `json:"caddy_admin_url"`
+		CaddyServerName      string   `json:"caddy_server_name"`
+		OIDCIssuer           string   `json:"oidc_issuer"`
+		OIDCClientID         string   `json:"oidc_client_id"`
+		OIDCClientSecret     string   `json:"oidc_client_secret"`
+		OIDCRedirectURL      string   `json:"oidc_redirect_url"`
+		OIDCScopes           []string `json:"oidc_scopes"`
+		OIDCUsernameClaim    string   `json:"oidc_username_claim"`
+		OIDCGroupsClaim      string   `json:"oidc_groups_claim"`
+		OIDCCookieSecret     string   `json:"oidc_cookie_secret"`
+		OIDCSessionTTL       string   `json:"oidc_session_ttl"`
+		UpstreamTLS          bool     `json:"upstream_tls"`
+		UpstreamTLSLifetime  string   `json:"upstream_tls_lifetime"`
+		WatchIngress         bool     `json:"watch_ingress"`
+		IngressClass         string   `json:"ingress_class"`
+		WatchHTTPRoutes      bool     `json:"watch_httproutes"`
+		GatewayName          string   `json:"gateway_name"`
+		IdentifierSource     string   `json:"identifier_source"`
+		IdentifierKey        string   `json:"identifier_key"`
+		IdentifierNameSuffix string   `json:"identifier_name_suffix"`
+	}{
+		Namespace:            c.Namespace,
+		BaseDomain:           c.BaseDomain,
+		KubeConfig:           c.KubeConfig,
+		ResyncPeriod:         c.ResyncPeriod,
+		ReconcilePeriod:      c.ReconcilePeriod,
+		IdleCheckPeriod:      c.IdleCheckPeriod,
+		CaddyAdminURL:        c.CaddyAdminURL,
+		CaddyServerName:      c.CaddyServerName,
+		OIDCIssuer:           c.OIDCIssuer,
+		OIDCClientID:         c.OIDCClientID,
+		OIDCClientSecret:     c.OIDCClientSecret,
+		OIDCRedirectURL:      c.OIDCRedirectURL,
+		OIDCScopes:           c.OIDCScopes,
+		OIDCUsernameClaim:    c.OIDCUsernameClaim,
+		OIDCGroupsClaim:      c.OIDCGroupsClaim,
+		OIDCCookieSecret:     c.OIDCCookieSecret,
+		OIDCSessionTTL:       c.OIDCSessionTTL,
+		UpstreamTLS:          c.UpstreamTLS,
+		UpstreamTLSLifetime:  c.UpstreamTLSLifetime,
+		WatchIngress:         c.WatchIngress,
+		IngressClass:         c.IngressClass,
+		WatchHTTPRoutes:      c.WatchHTTPRoutes,
+		GatewayName:          c.GatewayName,
+		IdentifierSource:     c.IdentifierSource,
+		IdentifierKey:        c.IdentifierKey,
+		IdentifierNameSuffix: c.IdentifierNameSuffix,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return string(key), nil
+}
+
 // GetResyncPeriodDuration 返回解析后的重新同步周期
 func (c *Config) GetResyncPeriodDuration() time.Duration {
 	duration, _ := time.ParseDuration(c.ResyncPeriod)
@@ -125,6 +591,108 @@ func (c *Config) GetReconcilePeriodDuration() time.Duration {
 	return duration
 }
 
+// GetWakeTimeoutDuration 返回解析后的唤醒超时
+func (c *Config) GetWakeTimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.WakeTimeout)
+	return duration
+}
+
+// GetIdleCheckPeriodDuration 返回解析后的空闲检查周期
+func (c *Config) GetIdleCheckPeriodDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.IdleCheckPeriod)
+	return duration
+}
+
+// GetDrainPeriodDuration 返回解析后的连接排空时长
+func (c *Config) GetDrainPeriodDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.DrainPeriod)
+	return duration
+}
+
+// GetUnreadyGracePeriodDuration 返回解析后的未就绪宽限期
+func (c *Config) GetUnreadyGracePeriodDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.UnreadyGracePeriod)
+	return duration
+}
+
+// GetMinRouteChangeIntervalDuration 返回解析后的路由变化最小间隔
+func (c *Config) GetMinRouteChangeIntervalDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.MinRouteChangeInterval)
+	return duration
+}
+
+// GetShareTokenTTLDuration 返回解析后的分享令牌默认有效期
+func (c *Config) GetShareTokenTTLDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.ShareTokenTTL)
+	return duration
+}
+
+// GetMaxShareTokenTTLDuration 返回解析后的分享令牌最长有效期
+func (c *Config) GetMaxShareTokenTTLDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.MaxShareTokenTTL)
+	return duration
+}
+
+// GetOIDCSessionTTLDuration 返回解析后的登录会话有效期
+func (c *Config) GetOIDCSessionTTLDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.OIDCSessionTTL)
+	return duration
+}
+
+// GetRateLimitClient 返回解析后的客户端限流参数
+func (c *Config) GetRateLimitClient() RateLimit {
+	limit, _ := ParseRateLimit(c.RateLimitClient)
+	return limit
+}
+
+// GetRateLimitRoute 返回解析后的 gitspace 限流参数
+func (c *Config) GetRateLimitRoute() RateLimit {
+	limit, _ := ParseRateLimit(c.RateLimitRoute)
+	return limit
+}
+
+// GetBandwidthLimit 返回解析后的带宽上限（每秒字节数）
+func (c *Config) GetBandwidthLimit() int64 {
+	bandwidth, _ := ParseBandwidth(c.BandwidthLimit)
+	return bandwidth
+}
+
+// GetUpstreamTLSLifetimeDuration 返回解析后的上游证书有效期
+func (c *Config) GetUpstreamTLSLifetimeDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.UpstreamTLSLifetime)
+	return duration
+}
+
+// GetPortForwardingRanges 返回解析后的端口转发白名单
+func (c *Config) GetPortForwardingRanges() PortRanges {
+	ranges, _ := ParsePortRanges(c.PortForwardingPorts)
+	return ranges
+}
+
+// GetHealthCheckIntervalDuration 返回解析后的主动健康检查间隔
+func (c *Config) GetHealthCheckIntervalDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.HealthCheckInterval)
+	return duration
+}
+
+// GetHealthCheckFailDurationDuration 返回解析后的被动健康检查失败记忆时长
+func (c *Config) GetHealthCheckFailDurationDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.HealthCheckFailDuration)
+	return duration
+}
+
+// GetFinalizerTimeoutDuration 返回解析后的 finalizer 强制移除超时
+func (c *Config) GetFinalizerTimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.FinalizerTimeout)
+	return duration
+}
+
+// GetReadinessProbeTimeoutDuration 返回解析后的上游探测超时
+func (c *Config) GetReadinessProbeTimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(c.ReadinessProbeTimeout)
+	return duration
+}
+
 // GetLabelSelector 返回硬编码的 Label Selector
 // 固定为 "gitspace.app.io/managed-by=caddy"
 func (c *Config) GetLabelSelector() string {
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortRange 闭区间端口范围
+type PortRange struct {
+	From int
+	To   int
+}
+
+// PortRanges 端口白名单（单个端口或端口范围）
+type PortRanges []PortRange
+
+// ParsePortRanges 解析逗号分隔的端口和端口范围（如 "80,3000-9999"）
+func ParsePortRanges(value string) (PortRanges, error) {
+	var ranges PortRanges
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(field, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				return nil, fmt.Errorf("invalid port range %q", field)
+			}
+		}
+		if start < 1 || end > 65535 || start > end {
+			return nil, fmt.Errorf("port range %q out of bounds", field)
+		}
+		ranges = append(ranges, PortRange{From: start, To: end})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no ports in %q", value)
+	}
+	return ranges, nil
+}
+
+// Contains 端口是否在白名单内
+func (r PortRanges) Contains(port int) bool {
+	for _, pr := range r {
+		if port >= pr.From && port <= pr.To {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParsePortRanges 测试解析端口白名单
+func TestParsePortRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    PortRanges
+		wantErr bool
+	}{
+		{"单个端口", "8080", PortRanges{{8080, 8080}}, false},
+		{"端口和范围", "80, 3000-9999", PortRanges{{80, 80}, {3000, 9999}}, false},
+		{"忽略空项", "80,,443,", PortRanges{{80, 80}, {443, 443}}, false},
+		{"范围两端带空格", "3000 - 3010", PortRanges{{3000, 3010}}, false},
+		{"全部端口", "1-65535", PortRanges{{1, 65535}}, false},
+		{"空字符串", "", nil, true},
+		{"非数字", "http", nil, true},
+		{"范围终点非数字", "3000-x", nil, true},
+		{"端口为 0", "0", nil, true},
+		{"端口超出范围", "65536", nil, true},
+		{"范围起点大于终点", "9000-8000", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePortRanges(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePortRanges(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePortRanges(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPortRangesContains 测试端口白名单检查
+func TestPortRangesContains(t *testing.T) {
+	ranges, err := ParsePortRanges("80,3000-3999")
+	if err != nil {
+		t.Fatalf("ParsePortRanges() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		port int
+		want bool
+	}{
+		{"单个端口", 80, true},
+		{"范围起点", 3000, true},
+		{"范围终点", 3999, true},
+		{"范围内", 3456, true},
+		{"不在白名单", 8080, false},
+		{"紧邻范围", 4000, false},
+		{"端口为 0", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ranges.Contains(tt.port); got != tt.want {
+				t.Errorf("Contains(%d) = %v, want %v", tt.port, got, tt.want)
+			}
+		})
+	}
+}
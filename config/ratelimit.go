@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// RateLimit 令牌桶限流参数
+type RateLimit struct {
+	// Rate 每秒补充的令牌数（请求数），0 表示不限流
+	Rate float64
+	// Burst 桶容量，允许的突发请求数
+	Burst int
+}
+
+// Enabled 是否启用限流
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0
+}
+
+// ParseRateLimit 解析 "<rps>[,<burst>]" 格式的限流参数（如 "10"、"10,40"），"0" 表示不限流
+// 未指定 burst 时使用向上取整的 rps（至少为 1）
+func ParseRateLimit(value string) (RateLimit, error) {
+	rateValue, burstValue, hasBurst := strings.Cut(strings.TrimSpace(value), ",")
+
+	rate, err := strconv.ParseFloat(strings.TrimSpace(rateValue), 64)
+	if err != nil || rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+	if rate == 0 {
+		return RateLimit{}, nil
+	}
+
+	burst := max(int(math.Ceil(rate)), 1)
+	if hasBurst {
+		if burst, err = strconv.Atoi(strings.TrimSpace(burstValue)); err != nil || burst < 1 {
+			return RateLimit{}, fmt.Errorf("invalid rate limit burst %q", value)
+		}
+	}
+	return RateLimit{Rate: rate, Burst: burst}, nil
+}
+
+// ParseBandwidth 解析每秒字节数（如 "512KiB"、"5MB"），"0" 表示不限速
+func ParseBandwidth(value string) (int64, error) {
+	bytes, err := humanize.ParseBytes(strings.TrimSpace(value))
+	if err != nil || bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid bandwidth %q", value)
+	}
+	return int64(bytes), nil
+}
@@ -0,0 +1,800 @@
+package caddy2k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/ysicing/caddy2-gitspace/auth"
+	"github.com/ysicing/caddy2-gitspace/config"
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+// controllers 跨 Caddy 配置重载共享的控制器，键为 config.WatchKey
+//
+// 插件每次通过 Admin API 写入路由都会触发配置重载并创建新的 K8sRouter 实例。
+// WatchKey 不变时新实例复用同一个控制器（Informer 缓存、Tracker、事件处理器），
+// 其余设置的变化通过 reconfigure 交给运行中的控制器；
+// 只有最后一个引用释放时控制器才会停止。
+var controllers = caddy.NewUsagePool()
+
+var (
+	// activeController 当前允许写入路由的控制器
+	// 配置变化时新旧控制器会短暂共存，只有最近启动的控制器可以写入
+	activeController atomic.Pointer[routerController]
+
+	// routeWriteSlot 串行化所有控制器对 Caddy 路由的写入（容量为 1 的信号量）
+	routeWriteSlot = make(chan struct{}, 1)
+)
+
+// errControllerInactive 控制器已被新配置取代，不再允许写入路由
+var errControllerInactive = errors.New("k8s router controller is no longer active")
+
+// routerController 持有 K8sRouter 的运行时状态和所有后台 goroutine
+type routerController struct {
+	// current 当前生效的配置，通过 config 读取；只有 WatchKey 之外的设置会被替换
+	current atomic.Pointer[config.Config]
+	// identifierSource 本控制器配置的 identifier 来源，成为活跃控制器时才设置为全局来源
+	identifierSource k8s.IdentifierSource
+	// previous 本控制器成为活跃控制器前的活跃控制器，本控制器停止时恢复（配置加载失败回滚）
+	previous atomic.Pointer[routerController]
+	// stopped Destruct 已执行，停止的控制器不会被恢复为活跃控制器
+	stopped      atomic.Bool
+	adminClient  *router.AdminAPIClient
+	tracker      *router.RouteIDTracker
+	watcher      *k8s.Watcher
+	routeWatcher *k8s.GitspaceRouteWatcher
+	// ingressWatcher、httpRouteWatcher 未启用时为 nil
+	ingressWatcher   *k8s.IngressWatcher
+	httpRouteWatcher *k8s.HTTPRouteWatcher
+	// authenticator 未配置 oidc_issuer 时为 nil
+	authenticator    *auth.Authenticator
+	eventHandler     *EventHandler
+	eventBroadcaster record.EventBroadcaster
+	k8sClient        kubernetes.Interface
+	dynClient        dynamic.Interface
+	ctx              context.Context
+	cancel           context.CancelFunc
+	wg               sync.WaitGroup
+	logger           *zap.Logger
+}
+
+// newRouterController 创建控制器及其 Watcher、事件处理器
+// 后台 goroutine 在 start 中启动，identifier 来源和活跃状态在 activate 中发布
+func newRouterController(cfg *config.Config, logger *zap.Logger) (*routerController, error) {
+	// 0. 解析 gitspace identifier 来源（路由 ID、域名和恢复逻辑共用）
+	identifierSource, err := k8s.NewIdentifierSource(cfg.IdentifierSource, cfg.IdentifierKey, cfg.IdentifierNameSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 创建 Kubernetes client
+	clientset, err := k8s.NewKubernetesClient(cfg.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	dynClient, err := k8s.NewDynamicClient(cfg.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &routerController{
+		identifierSource: identifierSource,
+		k8sClient:        clientset,
+		dynClient:        dynClient,
+		ctx:              ctx,
+		cancel:           cancel,
+		logger:           logger,
+	}
+	c.current.Store(cfg)
+
+	// 1.1 创建 OIDC 登录（按配置启用，身份提供方在首次登录时 discovery）
+	if cfg.OIDCIssuer != "" {
+		c.authenticator, err = auth.NewAuthenticator(auth.Config{
+			Issuer:        cfg.OIDCIssuer,
+			ClientID:      cfg.OIDCClientID,
+			ClientSecret:  cfg.OIDCClientSecret,
+			RedirectURL:   cfg.OIDCRedirectURL,
+			Scopes:        cfg.OIDCScopes,
+			UsernameClaim: cfg.OIDCUsernameClaim,
+			GroupsClaim:   cfg.OIDCGroupsClaim,
+			CookieDomain:  cfg.BaseDomain,
+			CookieSecret:  []byte(cfg.OIDCCookieSecret),
+			SessionTTL:    cfg.GetOIDCSessionTTLDuration(),
+		})
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// 2. 创建 AdminAPIClient，写入前检查控制器是否仍处于活跃状态
+	c.adminClient = router.NewAdminAPIClient(cfg.CaddyAdminURL, cfg.CaddyServerName)
+	c.adminClient.SetWriteGuard(c.guardRouteWrite)
+
+	// 3. 创建 RouteIDTracker
+	c.tracker = router.NewRouteIDTracker()
+
+	// 4. 创建 EventHandler
+	c.eventHandler = NewEventHandler(
+		ctx,
+		c.adminClient,
+		c.tracker,
+		clientset,
+		dynClient,
+		cfg,
+		logger,
+	)
+	c.eventHandler.active = c.isActive
+
+	var recorder record.EventRecorder
+	c.eventBroadcaster, recorder = k8s.NewEventRecorder(clientset)
+	c.eventHandler.recorder = recorder
+
+	// 5. 创建 Watcher
+	c.watcher = k8s.NewWatcher(
+		clientset,
+		cfg.Namespace,
+		cfg.GetLabelSelector(), // 使用硬编码的 label selector
+		cfg.GetResyncPeriodDuration(),
+		c.eventHandler,
+	)
+	c.eventHandler.workloads = c.watcher
+
+	// 6. 创建 GitspaceRoute Watcher（CRD 未安装时不启用）
+	c.routeWatcher = k8s.NewGitspaceRouteWatcher(
+		dynClient,
+		clientset.Discovery(),
+		cfg.Namespace,
+		cfg.GetResyncPeriodDuration(),
+		c.eventHandler,
+	)
+	c.eventHandler.gitspaceRoutes = c.routeWatcher
+
+	// 7. 创建 Ingress、HTTPRoute Watcher（按配置启用）
+	if cfg.WatchIngress {
+		c.ingressWatcher = k8s.NewIngressWatcher(
+			clientset,
+			cfg.Namespace,
+			cfg.IngressClass,
+			cfg.GetResyncPeriodDuration(),
+			c.eventHandler,
+		)
+	}
+	if cfg.WatchHTTPRoutes {
+		c.httpRouteWatcher = k8s.NewHTTPRouteWatcher(
+			dynClient,
+			clientset.Discovery(),
+			cfg.Namespace,
+			cfg.GetResyncPeriodDuration(),
+			c.eventHandler,
+		)
+	}
+
+	return c, nil
+}
+
+// activate 将控制器发布为活跃控制器，并设置其 identifier 来源
+// 在 K8sRouter 成功启动后调用，记录此前的活跃控制器以便本控制器停止时恢复
+func (c *routerController) activate() {
+	k8s.SetIdentifierSource(c.identifierSource)
+	if previous := activeController.Swap(c); previous != c {
+		c.previous.Store(previous)
+	}
+}
+
+// start 启动 Watcher、Tracker 恢复和对账等后台 goroutine
+// 在 activate 之后调用，事件处理和路由写入需要控制器处于活跃状态
+func (c *routerController) start() {
+	ctx := c.ctx
+	cfg := c.config()
+
+	// 8. 延迟恢复 Tracker（等待 Caddy Admin API 启动完成）
+	c.wg.Go(c.recoverTrackerWithRetry)
+
+	// 9. 在后台启动 Watcher
+	c.wg.Go(func() {
+		if err := c.watcher.Start(ctx); err != nil {
+			c.logger.Error("Watcher stopped with error", zap.Error(err))
+		}
+	})
+	c.wg.Go(func() {
+		err := c.routeWatcher.Start(ctx)
+		switch {
+		case errors.Is(err, k8s.ErrResourceNotInstalled):
+			c.logger.Info("GitspaceRoute CRD not installed, declarative routes disabled")
+		case err != nil:
+			c.logger.Error("GitspaceRoute watcher stopped with error", zap.Error(err))
+		}
+	})
+	if c.ingressWatcher != nil {
+		c.wg.Go(func() {
+			if err := c.ingressWatcher.Start(ctx); err != nil {
+				c.logger.Error("Ingress watcher stopped with error", zap.Error(err))
+			}
+		})
+	}
+	if c.httpRouteWatcher != nil {
+		c.wg.Go(func() {
+			err := c.httpRouteWatcher.Start(ctx)
+			switch {
+			case errors.Is(err, k8s.ErrResourceNotInstalled):
+				c.logger.Warn("Gateway API HTTPRoute CRD not installed, HTTPRoute support disabled")
+			case err != nil:
+				c.logger.Error("HTTPRoute watcher stopped with error", zap.Error(err))
+			}
+		})
+	}
+
+	// 10. 启动时执行一次对账
+	c.wg.Go(func() {
+		if err := c.reconcileRoutesWithK8s(); err != nil {
+			c.logger.Warn("Initial reconciliation failed", zap.Error(err))
+		}
+	})
+
+	// 11. 启动定期对账 goroutine
+	c.wg.Go(c.runPeriodicReconciliation)
+
+	// 12. 启动空闲检测 goroutine（写回最近活动时间、空闲缩容）
+	c.wg.Go(c.runIdleMonitor)
+
+	// 13. 启动上游证书轮换 goroutine（按配置启用）
+	if cfg.UpstreamTLS {
+		c.wg.Go(c.runUpstreamCertRotation)
+	}
+}
+
+// Destruct 停止控制器并等待所有后台 goroutine 退出（实现 caddy.Destructor 接口）
+// 在最后一个使用该控制器的 K8sRouter 实例停止时调用
+func (c *routerController) Destruct() error {
+	c.stopped.Store(true)
+
+	// 本控制器仍是活跃控制器时（新配置加载失败回滚），恢复仍在运行的上一个控制器及其 identifier 来源
+	previous := c.previous.Load()
+	if previous != nil && previous.stopped.Load() {
+		previous = nil
+	}
+	if activeController.CompareAndSwap(c, previous) && previous != nil {
+		k8s.SetIdentifierSource(previous.identifierSource)
+	}
+
+	// 取消 context：所有 Admin API 和 K8s 调用都基于它，阻塞中的调用会立即返回
+	c.cancel()
+	c.watcher.Stop()
+	c.routeWatcher.Stop()
+	if c.ingressWatcher != nil {
+		c.ingressWatcher.Stop()
+	}
+	if c.httpRouteWatcher != nil {
+		c.httpRouteWatcher.Stop()
+	}
+	c.wg.Wait()
+	c.eventHandler.Wait()
+	c.eventBroadcaster.Shutdown()
+
+	c.logger.Info("K8s router controller stopped")
+	return nil
+}
+
+// config 返回控制器当前生效的配置
+func (c *routerController) config() *config.Config {
+	return c.current.Load()
+}
+
+// reconfigure 将重载后的配置交给运行中的控制器，返回此前的配置
+// 调用方保证 WatchKey 相同；设置替换后异步重新同步所有工作负载路由，
+// 使默认端口、健康检查、限流等变化应用到已有路由。
+// Start 运行在 Caddy 的配置加载中，此时 Admin API 写入会等待加载完成，因此不能同步执行。
+func (c *routerController) reconfigure(cfg *config.Config) *config.Config {
+	previous := c.current.Swap(cfg)
+	// 写入路由触发的重载配置不变，无需重新同步
+	if sameConfig(previous, cfg) {
+		return previous
+	}
+	c.eventHandler.applyConfig(cfg)
+
+	c.logger.Info("K8s router controller reconfigured")
+	c.wg.Go(c.resyncWorkloadRoutes)
+	return previous
+}
+
+// sameConfig 判断两份配置是否完全相同
+func sameConfig(a, b *config.Config) bool {
+	if a == b {
+		return true
+	}
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
+
+// resyncWorkloadRoutes 按当前设置重新同步缓存中所有工作负载的路由
+// 路由写入是幂等的，设置未影响的路由不会产生写入
+func (c *routerController) resyncWorkloadRoutes() {
+	if !c.watcher.IsReady() {
+		// Informer 尚未同步时，初次同步的事件会使用新设置
+		return
+	}
+	workloads, err := c.watcher.ListWorkloads()
+	if err != nil {
+		c.logger.Warn("Failed to list workloads for resync", zap.Error(err))
+		return
+	}
+	for _, workload := range workloads {
+		if c.ctx.Err() != nil {
+			return
+		}
+		if err := c.eventHandler.OnWorkloadAdd(workload); err != nil {
+			c.logger.Warn("Failed to resync workload route",
+				zap.String("workload", k8s.WorkloadKey(workload)),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// isActive 判断控制器是否仍是当前活跃的控制器
+func (c *routerController) isActive() bool {
+	return activeController.Load() == c
+}
+
+// guardRouteWrite 串行化路由写入，并拒绝已被取代的控制器写入
+//
+// 写入期间一直持有写入槽，保证被取代的控制器不会在活跃控制器之后覆盖路由。
+// Admin API 写入会等待 Caddy 的配置锁，而持有配置锁的重载可能在 Destruct 中等待本控制器的 goroutine：
+// 等待写入槽时监听 c.ctx，Destruct 先取消 ctx，等待中的 goroutine 立即返回；
+// 持有写入槽的请求同样基于 c.ctx 发出，取消后也会返回，因此重载不会与路由写入互相等待。
+func (c *routerController) guardRouteWrite() (func(), error) {
+	select {
+	case routeWriteSlot <- struct{}{}:
+	case <-c.ctx.Done():
+		return nil, errControllerInactive
+	}
+	release := func() { <-routeWriteSlot }
+	if !c.isActive() {
+		release()
+		return nil, errControllerInactive
+	}
+	return release, nil
+}
+
+// sleep 等待指定时长，控制器停止时提前返回 false
+func (c *routerController) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// recoverTrackerWithRetry 带重试机制的异步恢复 Tracker
+func (c *routerController) recoverTrackerWithRetry() {
+	const (
+		maxRetries         = 5
+		initialDelay       = 2 * time.Second
+		maxDelay           = 30 * time.Second
+		healthCheckURL     = "/config/"
+		healthCheckTimeout = 2 * time.Second // 快速健康检查,避免阻塞
+	)
+
+	c.logger.Info("Starting delayed tracker recovery...")
+
+	// 首次延迟,等待 Caddy Admin API 启动
+	if !c.sleep(initialDelay) {
+		return
+	}
+
+	delay := initialDelay
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		c.logger.Info("Attempting to recover tracker",
+			zap.Int("attempt", attempt),
+			zap.Int("max_retries", maxRetries),
+		)
+
+		// 健康检查:先测试 Admin API 是否可访问(使用短超时快速失败)
+		ctx, cancel := context.WithTimeout(c.ctx, healthCheckTimeout)
+		if err := c.adminClient.HealthCheck(ctx, healthCheckURL); err != nil {
+			cancel()
+			c.logger.Warn("Admin API health check failed",
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+				zap.Duration("retry_after", delay),
+			)
+
+			if attempt < maxRetries {
+				if !c.sleep(delay) {
+					return
+				}
+				// 指数退避,但不超过 maxDelay
+				delay *= 2
+				if delay > maxDelay {
+					delay = maxDelay
+				}
+			}
+			continue
+		}
+		cancel()
+
+		// Admin API 健康,先清理重复路由
+		ctx2, cancel2 := context.WithTimeout(c.ctx, 15*time.Second)
+		deletedCount, err := c.adminClient.CleanupDuplicateRoutes(ctx2)
+		cancel2()
+
+		if err != nil {
+			c.logger.Warn("Failed to cleanup duplicate routes",
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			// 清理失败不阻塞恢复流程,继续尝试恢复
+		} else if deletedCount > 0 {
+			c.logger.Info("Cleaned up duplicate routes",
+				zap.Int("deleted_count", deletedCount),
+			)
+		}
+
+		// 尝试恢复 Tracker
+		// 注意：不再创建基础路由，它们由 Caddyfile 定义
+		if err := c.recoverTracker(); err != nil {
+			c.logger.Warn("Failed to recover tracker",
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+
+			if attempt < maxRetries {
+				if !c.sleep(delay) {
+					return
+				}
+				delay *= 2
+				if delay > maxDelay {
+					delay = maxDelay
+				}
+			}
+			continue
+		}
+
+		// 恢复成功
+		c.logger.Info("Tracker recovery completed successfully",
+			zap.Int("attempt", attempt),
+		)
+		return
+	}
+
+	// 所有重试都失败
+	c.logger.Error("Failed to recover tracker after all retries",
+		zap.Int("max_retries", maxRetries),
+	)
+}
+
+// recoverTracker 从 Caddy Admin API 和 K8s 恢复 RouteIDTracker
+// 参考 gitness 的修复思路：不从 routeID 反推，而是通过 K8s 工作负载匹配
+// 使用 gitspace identifier（来自工作负载 label）而不是工作负载名称
+//
+// 简化架构：不再管理基础路由（healthz, catch-all）
+// 基础路由由 Caddyfile 定义，插件只管理动态工作负载路由
+func (c *routerController) recoverTracker() error {
+	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer cancel()
+
+	// 1. 从 Caddy 获取所有路由
+	routes, err := c.adminClient.ListRoutes(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 构建 routeID -> route 映射（只关注有 ID 的动态路由）
+	routeMap := make(map[string]*router.RouteConfig)
+	for _, route := range routes {
+		// 只处理有 @id 的路由（动态创建的 工作负载路由）
+		// Caddyfile 创建的路由没有 @id，我们不管理它们
+		if route.ID != "" {
+			routeMap[route.ID] = route
+		}
+	}
+
+	// 2. 从 K8s 获取所有工作负载（Deployment、StatefulSet、独立 Pod）
+	workloads, err := k8s.ListWorkloads(ctx, c.k8sClient, c.config().Namespace, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	// 3. 遍历工作负载，恢复 tracker 映射
+	// identifier 重复时只有所有者（creationTimestamp 最早）持有路由
+	owners := k8s.IdentifierOwners(workloads)
+	recoveredCount := 0
+	skippedCount := 0
+	for _, workload := range workloads {
+		workloadKey := k8s.WorkloadKey(workload)
+
+		// 从工作负载 labels 获取 gitspace identifier
+		gitspaceIdentifier := k8s.GetGitspaceIdentifier(workload)
+		if gitspaceIdentifier == "" {
+			c.logger.Debug("Workload missing gitspace identifier, skipping recovery",
+				zap.String("workload", workloadKey),
+			)
+			skippedCount++
+			continue
+		}
+		if owner := owners[gitspaceIdentifier]; owner == nil || k8s.WorkloadKey(owner) != workloadKey {
+			skippedCount++
+			continue
+		}
+
+		// 使用 gitspace identifier 构造期望的 routeID
+		routeID := router.BuildRouteID(gitspaceIdentifier)
+
+		// 检查 Caddy 中是否存在对应的路由
+		if route, exists := routeMap[routeID]; exists {
+			c.tracker.Set(workloadKey, route.ID, route.TargetAddr)
+			c.logger.Info("Recovered route",
+				zap.String("route_id", route.ID),
+				zap.String("workload", workloadKey),
+				zap.String("gitspace_identifier", gitspaceIdentifier),
+				zap.String("target_addr", route.TargetAddr),
+			)
+			recoveredCount++
+		}
+	}
+
+	// 4. 恢复 GitspaceRoute、Ingress、HTTPRoute 的路由映射
+	for routeID, key := range c.listDeclarativeRoutes(ctx, routeMap) {
+		if route, exists := routeMap[routeID]; exists {
+			c.tracker.Set(key, route.ID, route.TargetAddr)
+			recoveredCount++
+		}
+	}
+
+	c.logger.Info("Tracker recovered",
+		zap.Int("total_routes", len(routes)),
+		zap.Int("recovered_mappings", recoveredCount),
+		zap.Int("skipped_workloads", skippedCount),
+	)
+
+	return nil
+}
+
+// listDeclarativeRoutes 列出 GitspaceRoute、Ingress、HTTPRoute 对应的路由
+// 返回 routeID -> Tracker 键；未启用或未安装的资源不参与
+// 某类资源列举失败（如无权限、API 暂时不可用）时记录日志，并保留 existing 中该类资源的路由，
+// 避免一次失败导致整体对账中止或误删路由
+func (c *routerController) listDeclarativeRoutes(ctx context.Context, existing map[string]*router.RouteConfig) map[string]string {
+	cfg := c.config()
+	result := make(map[string]string)
+
+	keepExisting := func(kind, prefix string, err error) {
+		c.logger.Warn("Failed to list declarative routes, keeping existing ones",
+			zap.String("kind", kind),
+			zap.Error(err),
+		)
+		for routeID := range existing {
+			rest, found := strings.CutPrefix(routeID, prefix)
+			if !found {
+				continue
+			}
+			if namespace, name, found := strings.Cut(rest, ":"); found {
+				result[routeID] = k8s.BuildWorkloadKey(kind, namespace, name)
+			}
+		}
+	}
+
+	if gitspaceRoutes, err := k8s.ListGitspaceRoutes(ctx, c.dynClient, cfg.Namespace); err != nil {
+		keepExisting(k8s.KindGitspaceRoute, "gitspaceroute:", err)
+	} else {
+		for _, route := range gitspaceRoutes {
+			result[gitspaceRouteID(route)] = gitspaceRouteKey(route)
+		}
+	}
+
+	if cfg.WatchIngress {
+		ingresses, err := c.k8sClient.NetworkingV1().Ingresses(cfg.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			keepExisting(k8s.KindIngress, "ingress:", fmt.Errorf("failed to list ingresses: %w", err))
+		} else {
+			for i := range ingresses.Items {
+				ingress := &ingresses.Items[i]
+				if k8s.IsIngressClass(ingress, cfg.IngressClass) {
+					result[ingressRouteID(ingress)] = ingressKey(ingress)
+				}
+			}
+		}
+	}
+
+	if cfg.WatchHTTPRoutes {
+		if httpRoutes, err := k8s.ListHTTPRoutes(ctx, c.dynClient, cfg.Namespace); err != nil {
+			keepExisting(k8s.KindHTTPRoute, "httproute:", err)
+		} else {
+			for _, route := range httpRoutes {
+				if route.AttachedToGateway(cfg.GatewayName) {
+					result[httpRouteID(route)] = httpRouteKey(route)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// reconcileRoutesWithK8s 全量对账 Caddy 路由与 K8s 工作负载状态
+// 简化架构：只处理动态工作负载路由，不管理 Caddyfile 定义的基础路由
+func (c *routerController) reconcileRoutesWithK8s() error {
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	c.logger.Info("Starting route reconciliation...")
+
+	// 1. 获取 Caddy 中所有管理的路由（只包含有 @id 的动态路由）
+	routes, err := c.adminClient.ListRoutes(ctx)
+	if err != nil {
+		c.logger.Error("Failed to list Caddy routes during reconciliation", zap.Error(err))
+		return err
+	}
+
+	// 构建 Caddy 路由集合 (routeID -> route)
+	// IsManagedRouteID 会过滤掉 Caddyfile 路由（它们没有 @id 或不符合命名规则）
+	caddyRoutes := make(map[string]*router.RouteConfig)
+	for _, route := range routes {
+		if router.IsManagedRouteID(route.ID) {
+			caddyRoutes[route.ID] = route
+		}
+	}
+
+	// 2. 获取 K8s 中所有符合条件的工作负载 (replicas=1 && ready，或自动唤醒)
+	workloads, err := k8s.ListWorkloads(ctx, c.k8sClient, c.config().Namespace, metav1.ListOptions{})
+	if err != nil {
+		c.logger.Error("Failed to list K8s workloads during reconciliation", zap.Error(err))
+		return err
+	}
+
+	// 构建期望的路由集合
+	expectedRoutes := make(map[string]bool)
+	// gitspaceIdentifierToWorkloadKey 映射，用于清理时查找 workloadKey
+	gitspaceIdentifierToWorkloadKey := make(map[string]string)
+	// 仍要求客户端证书的路由，其余客户端证书策略作为孤立策略删除
+	clientCertRoutes := make(map[string]bool)
+
+	for _, workload := range workloads {
+		// 只处理就绪的单副本工作负载，以及使用唤醒路由的工作负载
+		if !wantsActivatorRoute(workload) {
+			if workload.DesiredReplicas() != 1 {
+				continue
+			}
+			// 开启抖动抑制时，未就绪工作负载仍由事件处理持有的路由暂时保留
+			if !c.eventHandler.workloadRoutable(workload) {
+				if _, held := c.tracker.Get(k8s.WorkloadKey(workload)); !held || !c.eventHandler.dampsUnready() {
+					continue
+				}
+			}
+		}
+
+		// 使用 gitspaceIdentifier 而不是工作负载名称
+		gitspaceIdentifier := k8s.GetGitspaceIdentifier(workload)
+		if gitspaceIdentifier == "" {
+			c.logger.Warn("Workload missing gitspace identifier, skipping",
+				zap.String("workload", k8s.WorkloadKey(workload)),
+			)
+			continue
+		}
+
+		routeID := router.BuildRouteID(gitspaceIdentifier)
+		expectedRoutes[routeID] = true
+		if policy, err := k8s.ParseNetworkPolicy(workload.GetAnnotations()); err == nil && policy.RequireClientCert {
+			clientCertRoutes[routeID] = true
+		}
+
+		// 记录映射关系，用于后续清理 tracker
+		gitspaceIdentifierToWorkloadKey[gitspaceIdentifier] = k8s.WorkloadKey(workload)
+	}
+
+	// GitspaceRoute、Ingress、HTTPRoute 的路由由其事件处理负责增删，对账时全部保留
+	for routeID := range c.listDeclarativeRoutes(ctx, caddyRoutes) {
+		expectedRoutes[routeID] = true
+	}
+
+	// 开启按需端口转发时保留并修复端口转发路由，关闭后作为孤立路由删除
+	if c.config().PortForwarding {
+		expectedRoutes[portForwardRouteID] = true
+		if err := c.ensurePortForwardRoute(); err != nil {
+			c.logger.Warn("Failed to ensure port forwarding route", zap.Error(err))
+		}
+	}
+	// 开启 OIDC 登录时保留并修复登录回调路由
+	if c.authenticator != nil {
+		expectedRoutes[oidcRouteID] = true
+		if err := c.ensureOIDCRoute(); err != nil {
+			c.logger.Warn("Failed to ensure oidc callback route", zap.Error(err))
+		}
+	}
+
+	// 3. 删除 Caddy 中存在但 K8s 中不存在的路由（清理孤立路由）
+	deletedCount := 0
+	for routeID := range caddyRoutes {
+		// 正在排空的路由由排空流程负责删除，不在此删除
+		if c.eventHandler.isDraining(routeID) {
+			continue
+		}
+		if !expectedRoutes[routeID] {
+			c.logger.Info("Reconciliation: deleting orphaned route",
+				zap.String("route_id", routeID),
+			)
+
+			if err := c.adminClient.DeleteRoute(ctx, routeID); err != nil {
+				c.logger.Warn("Failed to delete orphaned route during reconciliation",
+					zap.String("route_id", routeID),
+					zap.Error(err),
+				)
+			} else {
+				// 从 tracker 中清理
+				// 从 routeID 解析出 gitspaceIdentifier
+				gitspaceIdentifier, err := router.ParseRouteID(routeID)
+				if err == nil {
+					// 使用映射找到对应的 workloadKey
+					if workloadKey, exists := gitspaceIdentifierToWorkloadKey[gitspaceIdentifier]; exists {
+						c.tracker.Delete(workloadKey)
+					}
+				}
+				deletedCount++
+			}
+		}
+	}
+
+	// 清理路由已删除或不再要求客户端证书的 TLS 连接策略
+	deletedPolicies, err := c.cleanupClientCertPolicies(ctx, clientCertRoutes)
+	if err != nil {
+		c.logger.Warn("Failed to clean up client certificate policies", zap.Error(err))
+	}
+
+	// 4. 对于 K8s 中存在但 Caddy 中缺失的路由，由 Informer 的 resync 机制自动创建
+	// 这里不主动创建，避免与事件处理冲突
+
+	c.logger.Info("Route reconciliation completed",
+		zap.Int("caddy_routes", len(caddyRoutes)),
+		zap.Int("expected_routes", len(expectedRoutes)),
+		zap.Int("deleted_orphaned", deletedCount),
+		zap.Int("deleted_tls_policies", deletedPolicies),
+	)
+
+	return nil
+}
+
+// runPeriodicReconciliation 定期执行对账
+func (c *routerController) runPeriodicReconciliation() {
+	ticker := time.NewTicker(c.config().GetReconcilePeriodDuration())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.logger.Debug("Running periodic reconciliation...")
+			if err := c.reconcileRoutesWithK8s(); err != nil {
+				c.logger.Warn("Periodic reconciliation failed", zap.Error(err))
+			}
+		case <-c.ctx.Done():
+			c.logger.Info("Stopping periodic reconciliation")
+			return
+		}
+	}
+}
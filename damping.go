@@ -0,0 +1,196 @@
+package caddy2k8s
+
+import (
+	"context"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// degradedHeader 路由指向运行中但未就绪的 Pod 时添加的响应头
+const degradedHeader = "X-Gitspace-Degraded"
+
+// degradedHandlerConfig 返回为降级路由添加响应头的处理器配置
+func degradedHandlerConfig() map[string]any {
+	return map[string]any{
+		"handler": "headers",
+		"response": map[string]any{
+			"set": map[string][]string{
+				degradedHeader: {"true"},
+			},
+		},
+	}
+}
+
+// dampsUnready 是否开启了抖动抑制：未就绪工作负载的路由可能暂时保留
+func (h *EventHandler) dampsUnready() bool {
+	settings := h.settings()
+	return settings.unreadyGracePeriod > 0 || settings.minRouteChangeInterval > 0 || settings.keepUnreadyPods
+}
+
+// handleUnready 单副本工作负载变为未就绪时决定如何处理路由（调用方需持有工作负载锁）
+// 开启 keep_unready_pods 且路由指向的 Pod 仍在运行时保留路由并标记为降级；
+// 设置了 unready_grace_period 时等待宽限期结束再重新判断；否则立即移除路由
+func (h *EventHandler) handleUnready(workload k8s.Workload) error {
+	workloadKey := k8s.WorkloadKey(workload)
+	settings := h.settings()
+
+	var pod *corev1.Pod
+	if settings.keepUnreadyPods {
+		pod = h.routedRunningPod(workload)
+	}
+	_, routed := h.tracker.Get(workloadKey)
+
+	switch router.DecideUnready(pod != nil, routed, settings.unreadyGracePeriod) {
+	case router.UnreadyKeepDegraded:
+		h.logger.Info("Workload became not ready, keeping degraded route",
+			zap.String("workload", workloadKey),
+			zap.String("pod", pod.Name),
+		)
+		return h.createRoute(workload, pod)
+
+	case router.UnreadyDelay:
+		h.logger.Info("Workload became not ready, delaying route removal",
+			zap.String("workload", workloadKey),
+			zap.Duration("unready_grace_period", settings.unreadyGracePeriod),
+		)
+		h.scheduleRouteResync(workload, settings.unreadyGracePeriod)
+		return nil
+
+	default:
+		h.logger.Info("Workload became not ready, removing route",
+			zap.String("workload", workloadKey),
+		)
+		return h.removeUnreadyRoute(workload)
+	}
+}
+
+// removeUnreadyRoute 移除未就绪工作负载的路由，距上次路由变化过近时延迟执行
+func (h *EventHandler) removeUnreadyRoute(workload k8s.Workload) error {
+	if h.deferRouteChange(workload) {
+		return nil
+	}
+	return h.parkOrDeleteRoute(workload)
+}
+
+// reconcileRoute 按工作负载的最新状态重新同步路由（延迟同步时使用，调用方需持有工作负载锁）
+func (h *EventHandler) reconcileRoute(workload k8s.Workload) error {
+	if workload.DesiredReplicas() == 1 && !wantsActivatorRoute(workload) && !h.workloadRoutable(workload) {
+		if h.settings().keepUnreadyPods {
+			if pod := h.routedRunningPod(workload); pod != nil {
+				return h.createRoute(workload, pod)
+			}
+		}
+		return h.removeUnreadyRoute(workload)
+	}
+	return h.syncWorkload(workload)
+}
+
+// routedRunningPod 返回当前路由指向、仍在运行且未被删除的 Pod，找不到时返回 nil
+func (h *EventHandler) routedRunningPod(workload k8s.Workload) *corev1.Pod {
+	routeInfo, exists := h.tracker.Get(k8s.WorkloadKey(workload))
+	if !exists || routeInfo == nil || routeInfo.TargetAddr == activatorUpstream {
+		return nil
+	}
+
+	var pods []corev1.Pod
+	if w, ok := workload.(k8s.PodWorkload); ok {
+		pods = []corev1.Pod{*w.Pod}
+	} else {
+		ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+		defer cancel()
+
+		list, err := h.k8sClient.CoreV1().Pods(workload.GetNamespace()).List(ctx, metav1.ListOptions{
+			LabelSelector: metav1.FormatLabelSelector(workload.PodSelector()),
+		})
+		if err != nil {
+			h.logger.Warn("Failed to list pods of unready workload",
+				zap.String("workload", k8s.WorkloadKey(workload)),
+				zap.Error(err),
+			)
+			return nil
+		}
+		pods = list.Items
+	}
+
+	// 端口无法确定时路由不应继续保留
+	port, err := k8s.ResolveWorkloadPort(workload, h.settings().defaultPort)
+	if err != nil {
+		return nil
+	}
+	return k8s.FindRunningPod(pods, func(pod *corev1.Pod) bool {
+		return router.SameTarget(h.podTarget(pod, port), routeInfo.TargetAddr)
+	})
+}
+
+// deferRouteChange 距上次路由变化不足 min_route_change_interval 时安排延迟同步并返回 true
+func (h *EventHandler) deferRouteChange(workload k8s.Workload) bool {
+	workloadKey := k8s.WorkloadKey(workload)
+	var lastChange time.Time
+	if last, exists := h.routeChanges.Load(workloadKey); exists {
+		lastChange = last.(time.Time)
+	}
+
+	remaining := router.RouteChangeDelay(lastChange, h.settings().minRouteChangeInterval, time.Now())
+	if remaining <= 0 {
+		return false
+	}
+
+	h.logger.Debug("Route changed recently, deferring change",
+		zap.String("workload", workloadKey),
+		zap.Duration("remaining", remaining),
+	)
+	h.scheduleRouteResync(workload, remaining)
+	return true
+}
+
+// recordRouteChange 记录工作负载的路由变化时间
+func (h *EventHandler) recordRouteChange(workloadKey string) {
+	if h.settings().minRouteChangeInterval > 0 {
+		h.routeChanges.Store(workloadKey, time.Now())
+	}
+}
+
+// scheduleRouteResync 在 delay 之后按最新状态重新同步工作负载的路由，同一工作负载只保留一个待执行的同步
+func (h *EventHandler) scheduleRouteResync(workload k8s.Workload, delay time.Duration) {
+	workloadKey := k8s.WorkloadKey(workload)
+
+	if _, pending := h.routeResyncPending.LoadOrStore(workloadKey, struct{}{}); pending {
+		return
+	}
+
+	h.wg.Go(func() {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		h.routeResyncPending.Delete(workloadKey)
+
+		if !h.active() {
+			return
+		}
+
+		lock := h.getWorkloadLock(workloadKey)
+		lock.Lock()
+		defer lock.Unlock()
+
+		// 获取锁后从缓存读取最新状态，工作负载已删除时跳过
+		latest := workload
+		if h.workloads != nil {
+			var err error
+			latest, err = h.workloads.GetWorkload(workload.Kind(), workload.GetNamespace(), workload.GetName())
+			if err != nil {
+				return
+			}
+		}
+		if latest.GetDeletionTimestamp() != nil {
+			return
+		}
+		_ = h.reconcileRoute(latest)
+	})
+}
@@ -0,0 +1,134 @@
+package caddy2k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+)
+
+// drainPollInterval 排空期间检查进行中请求数的间隔
+const drainPollInterval = 200 * time.Millisecond
+
+// startDrain 开始排空路由：保留旧路由直到进行中的请求完成或超过 drainPeriod，
+// 然后将路由切换为 "gitspace stopping" 响应，由下一次同步或重新创建路由时移除
+func (h *EventHandler) startDrain(workloadKey, routeID string) {
+	task := h.drains.Start(h.ctx, workloadKey, routeID, h.settings().drainPeriod)
+
+	h.wg.Go(func() {
+		h.drainRoute(workloadKey, task)
+	})
+}
+
+// cancelDrain 取消工作负载正在进行的排空（路由被重新创建时调用）
+func (h *EventHandler) cancelDrain(workloadKey string) {
+	h.drains.Cancel(workloadKey)
+}
+
+// isDraining 判断路由是否正在排空
+func (h *EventHandler) isDraining(routeID string) bool {
+	return h.drains.IsDraining(routeID)
+}
+
+// drainRoute 等待进行中的请求完成后，将路由切换为 stopping 响应
+func (h *EventHandler) drainRoute(workloadKey string, task *router.DrainTask) {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+wait:
+	for routeActivity.Active(task.RouteID) > 0 {
+		select {
+		case <-task.Done():
+			break wait
+		case <-ticker.C:
+		}
+	}
+
+	// 与事件处理串行，避免覆盖排空期间重新创建的路由
+	lock := h.getWorkloadLock(workloadKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// 排空期间路由已被重新创建或被新的排空取代
+	if !h.drains.Finish(workloadKey, task) {
+		return
+	}
+
+	// identifier 已被其他工作负载接管时，路由属于新的所有者
+	if len(h.tracker.Holders(task.RouteID, "")) > 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	if err := h.applyStoppingRoute(ctx, task.RouteID); err != nil {
+		h.logger.Warn("Failed to switch drained route to stopping response, deleting it",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", task.RouteID),
+			zap.Error(err),
+		)
+		if err := h.adminClient.DeleteRoute(ctx, task.RouteID); err != nil {
+			h.logger.Error("Failed to delete drained route",
+				zap.String("workload", workloadKey),
+				zap.String("route_id", task.RouteID),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+	h.drains.SetStopping(workloadKey, task.RouteID)
+
+	h.logger.Info("Route drained",
+		zap.String("workload", workloadKey),
+		zap.String("route_id", task.RouteID),
+	)
+}
+
+// applyStoppingRoute 将路由替换为 gitspace_placeholder 的 stopping 页面（503）
+func (h *EventHandler) applyStoppingRoute(ctx context.Context, routeID string) error {
+	gitspaceIdentifier, err := router.ParseRouteID(routeID)
+	if err != nil {
+		return err
+	}
+
+	spec := router.RouteSpec{
+		ID:     routeID,
+		Domain: fmt.Sprintf("%s.%s", gitspaceIdentifier, h.baseDomain),
+		Handlers: []map[string]any{
+			{
+				"handler": "gitspace_placeholder",
+				"state":   placeholderStopping,
+			},
+		},
+	}
+	return h.adminClient.ApplyRoute(ctx, spec)
+}
+
+// clearStoppingRoute 移除工作负载排空后留下的 stopping 路由（调用方需持有工作负载锁）
+// 路由已被本工作负载重新创建或已由其他工作负载接管时只清除记录
+func (h *EventHandler) clearStoppingRoute(workloadKey string) {
+	routeID, exists := h.drains.TakeStopping(workloadKey)
+	if !exists || len(h.tracker.Holders(routeID, "")) > 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	if err := h.adminClient.DeleteRoute(ctx, routeID); err != nil {
+		h.logger.Warn("Failed to delete stopping route",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	h.logger.Debug("Stopping route removed",
+		zap.String("workload", workloadKey),
+		zap.String("route_id", routeID),
+	)
+}
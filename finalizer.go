@@ -0,0 +1,178 @@
+package caddy2k8s
+
+import (
+	"context"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// eventReasonFinalizerForceRemoved 超时后强制移除 finalizer 时记录的事件原因
+const eventReasonFinalizerForceRemoved = "RouteCleanupTimeout"
+
+// ensureFinalizer 为工作负载添加路由清理 finalizer（调用方需持有工作负载锁）
+func (h *EventHandler) ensureFinalizer(workload k8s.Workload) {
+	if !k8s.NeedsFinalizer(workload, h.settings().useFinalizers) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := k8s.AddWorkloadFinalizer(ctx, h.k8sClient, workload.Kind(), workload.GetNamespace(), workload.GetName(), k8s.FinalizerRouteCleanup); err != nil {
+		h.logger.Warn("Failed to add route cleanup finalizer",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+	}
+}
+
+// finalizeWorkload 处理删除中的工作负载：先删除路由，成功后移除 finalizer（调用方需持有工作负载锁）
+// 删除路由失败时按退避重试；超过 finalizerTimeout 后强制移除 finalizer，避免工作负载无法删除。
+// 关闭 use_finalizers 后，遗留的 finalizer 仍会按此流程移除
+func (h *EventHandler) finalizeWorkload(workload k8s.Workload) error {
+	workloadKey := k8s.WorkloadKey(workload)
+
+	if !k8s.HasFinalizer(workload, k8s.FinalizerRouteCleanup) {
+		return h.deleteRoute(workload)
+	}
+
+	if err := h.deleteRouteNow(workload); err != nil {
+		finalizerTimeout := h.settings().finalizerTimeout
+		if !k8s.FinalizerTimedOut(workload, time.Now(), finalizerTimeout) {
+			h.scheduleFinalizeRetry(workload)
+			return err
+		}
+
+		h.logger.Warn("Route cleanup timed out, force removing finalizer",
+			zap.String("workload", workloadKey),
+			zap.Duration("finalizer_timeout", finalizerTimeout),
+			zap.Error(err),
+		)
+		if h.recorder != nil {
+			h.recorder.Eventf(workload.Object(), corev1.EventTypeWarning, eventReasonFinalizerForceRemoved,
+				"route could not be deleted within %s, removing finalizer %s: %v", finalizerTimeout, k8s.FinalizerRouteCleanup, err)
+		}
+	}
+
+	h.finalizeRetries.Delete(workloadKey)
+
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := k8s.RemoveWorkloadFinalizer(ctx, h.k8sClient, workload.Kind(), workload.GetNamespace(), workload.GetName(), k8s.FinalizerRouteCleanup); err != nil {
+		h.logger.Error("Failed to remove route cleanup finalizer",
+			zap.String("workload", workloadKey),
+			zap.Error(err),
+		)
+		h.scheduleFinalizeRetry(workload)
+		return err
+	}
+
+	h.logger.Info("Route cleanup finalizer removed",
+		zap.String("workload", workloadKey),
+	)
+	return nil
+}
+
+// deleteRouteNow 立即删除工作负载的路由（不排空）
+// Tracker 中没有记录时（例如重启后尚未恢复），按 identifier 删除其持有的路由
+func (h *EventHandler) deleteRouteNow(workload k8s.Workload) error {
+	workloadKey := k8s.WorkloadKey(workload)
+
+	var routeID string
+	if routeInfo, exists := h.tracker.Get(workloadKey); exists && routeInfo != nil {
+		routeID = routeInfo.RouteID
+	} else if identifier := k8s.GetGitspaceIdentifier(workload); identifier != "" {
+		if k8s.OwnsIdentifier(h.identifierOwner(identifier), workload) {
+			routeID = router.BuildRouteID(identifier)
+		}
+	}
+
+	h.cancelDrain(workloadKey)
+
+	if routeID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	if err := h.adminClient.DeleteRoute(ctx, routeID); err != nil {
+		h.logger.Error("Failed to delete route of deleting workload",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	h.tracker.Delete(workloadKey)
+	h.clearRouteReady(workload)
+
+	h.logger.Info("Route deleted before workload deletion",
+		zap.String("workload", workloadKey),
+		zap.String("route_id", routeID),
+	)
+	return nil
+}
+
+// scheduleFinalizeRetry 按指数退避在后台重试 finalize，同一工作负载只保留一个待执行的重试
+func (h *EventHandler) scheduleFinalizeRetry(workload k8s.Workload) {
+	workloadKey := k8s.WorkloadKey(workload)
+
+	if _, pending := h.finalizePending.LoadOrStore(workloadKey, struct{}{}); pending {
+		return
+	}
+
+	var previous time.Duration
+	if value, exists := h.finalizeRetries.Load(workloadKey); exists {
+		previous = value.(time.Duration)
+	}
+	delay := k8s.FinalizeRetryDelay(previous)
+	h.finalizeRetries.Store(workloadKey, delay)
+
+	h.wg.Go(func() {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		h.finalizePending.Delete(workloadKey)
+
+		if !h.active() || h.workloads == nil {
+			return
+		}
+
+		lock := h.getWorkloadLock(workloadKey)
+		lock.Lock()
+		defer lock.Unlock()
+
+		latest, err := h.workloads.GetWorkload(workload.Kind(), workload.GetNamespace(), workload.GetName())
+		if err != nil || latest.GetDeletionTimestamp() == nil {
+			h.finalizeRetries.Delete(workloadKey)
+			return
+		}
+		_ = h.finalizeWorkload(latest)
+	})
+}
+
+// releaseFinalizer 工作负载不再受管理（例如移除了 managed-by 标签）时移除遗留的 finalizer
+func (h *EventHandler) releaseFinalizer(workload k8s.Workload) {
+	if !k8s.HasFinalizer(workload, k8s.FinalizerRouteCleanup) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := k8s.RemoveWorkloadFinalizer(ctx, h.k8sClient, workload.Kind(), workload.GetNamespace(), workload.GetName(), k8s.FinalizerRouteCleanup); err != nil {
+		h.logger.Warn("Failed to release route cleanup finalizer",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+	}
+}
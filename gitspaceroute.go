@@ -0,0 +1,359 @@
+package caddy2k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// errBackendNotReady GitspaceRoute 引用的后端当前没有可代理的地址
+var errBackendNotReady = errors.New("backend is not ready")
+
+// gitspaceRouteID 返回 GitspaceRoute 对应的 Caddy 路由 ID
+// 使用 ":" 分隔，不会与 gitspace identifier（DNS label）冲突
+func gitspaceRouteID(route *k8s.GitspaceRoute) string {
+	return fmt.Sprintf("gitspaceroute:%s:%s", route.Namespace, route.Name)
+}
+
+// gitspaceRouteKey 返回 GitspaceRoute 在 Tracker 和锁中使用的键
+func gitspaceRouteKey(route *k8s.GitspaceRoute) string {
+	return k8s.BuildWorkloadKey(k8s.KindGitspaceRoute, route.Namespace, route.Name)
+}
+
+// OnGitspaceRouteAdd 处理 GitspaceRoute 创建事件
+func (h *EventHandler) OnGitspaceRouteAdd(route *k8s.GitspaceRoute) error {
+	if !h.active() {
+		return nil
+	}
+
+	lock := h.getWorkloadLock(gitspaceRouteKey(route))
+	lock.Lock()
+	defer lock.Unlock()
+
+	return h.syncGitspaceRoute(route)
+}
+
+// OnGitspaceRouteUpdate 处理 GitspaceRoute 更新事件
+func (h *EventHandler) OnGitspaceRouteUpdate(oldRoute, newRoute *k8s.GitspaceRoute) error {
+	if !h.active() {
+		return nil
+	}
+
+	// spec 未变化的更新（通常是本插件写回的 status）不需要重新同步；resync 事件除外
+	if oldRoute.Generation == newRoute.Generation && oldRoute.ResourceVersion != newRoute.ResourceVersion {
+		return nil
+	}
+
+	lock := h.getWorkloadLock(gitspaceRouteKey(newRoute))
+	lock.Lock()
+	defer lock.Unlock()
+
+	return h.syncGitspaceRoute(newRoute)
+}
+
+// OnGitspaceRouteDelete 处理 GitspaceRoute 删除事件
+func (h *EventHandler) OnGitspaceRouteDelete(route *k8s.GitspaceRoute) error {
+	if !h.active() {
+		return nil
+	}
+
+	key := gitspaceRouteKey(route)
+	lock := h.getWorkloadLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	defer h.workloadLocks.Delete(key)
+
+	return h.removeGitspaceRoute(route)
+}
+
+// syncGitspaceRoute 根据 GitspaceRoute spec 写入路由并更新 status（调用方需持有锁）
+func (h *EventHandler) syncGitspaceRoute(route *k8s.GitspaceRoute) error {
+	key := gitspaceRouteKey(route)
+	routeID := gitspaceRouteID(route)
+
+	if err := route.Spec.Validate(); err != nil {
+		h.logger.Warn("Invalid GitspaceRoute spec",
+			zap.String("gitspace_route", key),
+			zap.Error(err),
+		)
+		h.updateGitspaceRouteStatus(route, "", false, k8s.ReasonInvalidSpec, err.Error())
+		return h.removeGitspaceRoute(route)
+	}
+
+	hosts := h.gitspaceRouteHosts(route)
+
+	// 不允许接管 gitspace 和端口转发的域名（包括默认的 <name>.<base_domain>）
+	for _, host := range hosts {
+		if err := k8s.CheckGitspaceRouteHost(host, h.baseDomain, h.identifierInUse); err != nil {
+			h.logger.Warn("GitspaceRoute host conflicts with gitspace",
+				zap.String("gitspace_route", key),
+				zap.Error(err),
+			)
+			h.updateGitspaceRouteStatus(route, "", false, k8s.ReasonInvalidSpec, err.Error())
+			return h.removeGitspaceRoute(route)
+		}
+	}
+
+	upstream, workload, err := h.resolveGitspaceRouteUpstream(route)
+	if err != nil {
+		h.logger.Debug("GitspaceRoute backend not ready",
+			zap.String("gitspace_route", key),
+			zap.Error(err),
+		)
+		h.updateGitspaceRouteStatus(route, "", false, k8s.ReasonBackendNotReady, err.Error())
+		return h.removeGitspaceRoute(route)
+	}
+
+	var handlers []map[string]any
+	if route.Spec.Auth != nil && route.Spec.Auth.Basic != nil {
+		handlers = append(handlers, basicAuthHandlerConfig(route.Spec.Auth.Basic))
+	}
+
+	// 指向 gitspace 工作负载时与工作负载路由一样经过限流和访问控制
+	var spec router.RouteSpec
+	if workload != nil && k8s.GetGitspaceIdentifier(workload) != "" {
+		spec = h.workloadRouteSpec(workload, routeID, hosts[0], upstream, handlers...)
+	} else {
+		spec = h.proxyRouteSpec(routeID, hosts[0], upstream, handlers...)
+	}
+	spec.AliasDomains = hosts[1:]
+	spec.PathPrefix = route.Spec.PathPrefix
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	if err := h.adminClient.ApplyRoute(ctx, spec); err != nil {
+		h.logger.Error("Failed to program GitspaceRoute",
+			zap.String("gitspace_route", key),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		h.updateGitspaceRouteStatus(route, "", false, k8s.ReasonProgramFailed, err.Error())
+		return err
+	}
+
+	if info, exists := h.tracker.Get(key); !exists || info.TargetAddr != upstream {
+		h.logger.Info("GitspaceRoute programmed",
+			zap.String("gitspace_route", key),
+			zap.Strings("hosts", hosts),
+			zap.String("path_prefix", route.Spec.PathPrefix),
+			zap.String("target", upstream),
+		)
+	}
+	h.tracker.Set(key, routeID, upstream)
+
+	h.updateGitspaceRouteStatus(route, upstream, true, k8s.ReasonRouteProgrammed, "")
+	return nil
+}
+
+// gitspaceRouteHosts 返回 GitspaceRoute 声明的域名，未声明时为 <name>.<base_domain>
+func (h *EventHandler) gitspaceRouteHosts(route *k8s.GitspaceRoute) []string {
+	if len(route.Spec.Hosts) > 0 {
+		return route.Spec.Hosts
+	}
+	return []string{fmt.Sprintf("%s.%s", route.Name, h.baseDomain)}
+}
+
+// resolveGitspaceRouteUpstream 解析 GitspaceRoute 的上游地址，后端为工作负载时同时返回该工作负载
+func (h *EventHandler) resolveGitspaceRouteUpstream(route *k8s.GitspaceRoute) (string, k8s.Workload, error) {
+	switch {
+	case route.Spec.Upstream != "":
+		return route.Spec.Upstream, nil, nil
+
+	case route.Spec.Service != nil:
+		// 通过集群 DNS 访问 Service
+		return fmt.Sprintf("%s.%s.svc:%d", route.Spec.Service.Name, route.Namespace, route.Spec.Service.Port), nil, nil
+
+	default:
+		ref := route.Spec.WorkloadRef
+		kind := ref.Kind
+		if kind == "" {
+			kind = k8s.KindDeployment
+		}
+
+		ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+		defer cancel()
+
+		workload, err := k8s.GetWorkload(ctx, h.k8sClient, kind, route.Namespace, ref.Name)
+		if err != nil {
+			return "", nil, err
+		}
+		if workload.DesiredReplicas() != 1 || !h.workloadRoutable(workload) {
+			return "", nil, errBackendNotReady
+		}
+
+		pod, err := h.findReadyPod(workload)
+		if err != nil {
+			return "", nil, err
+		}
+		if pod == nil {
+			return "", nil, errBackendNotReady
+		}
+
+		port := int(route.Spec.Port)
+		if port == 0 {
+			if port, err = k8s.ResolveWorkloadPort(workload, h.settings().defaultPort); err != nil {
+				return "", nil, err
+			}
+		}
+		return h.podTarget(pod, port), workload, nil
+	}
+}
+
+// removeGitspaceRoute 删除 GitspaceRoute 已写入的路由
+func (h *EventHandler) removeGitspaceRoute(route *k8s.GitspaceRoute) error {
+	key := gitspaceRouteKey(route)
+	routeInfo, exists := h.tracker.Get(key)
+	if !exists || routeInfo == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	if err := h.adminClient.DeleteRoute(ctx, routeInfo.RouteID); err != nil {
+		h.logger.Error("Failed to delete GitspaceRoute route",
+			zap.String("gitspace_route", key),
+			zap.String("route_id", routeInfo.RouteID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	h.tracker.Delete(key)
+
+	h.logger.Info("GitspaceRoute route deleted",
+		zap.String("gitspace_route", key),
+		zap.String("route_id", routeInfo.RouteID),
+	)
+	return nil
+}
+
+// updateGitspaceRouteStatus 写回 GitspaceRoute status，状态未变化时不写入
+func (h *EventHandler) updateGitspaceRouteStatus(route *k8s.GitspaceRoute, target string, ready bool, reason, message string) {
+	status := k8s.GitspaceRouteStatus{
+		ObservedGeneration: route.Generation,
+		Conditions:         append([]metav1.Condition(nil), route.Status.Conditions...),
+	}
+
+	condition := metav1.Condition{
+		Type:               k8s.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: route.Generation,
+		Reason:             reason,
+		Message:            message,
+	}
+	if ready {
+		condition.Status = metav1.ConditionTrue
+		status.RouteID = gitspaceRouteID(route)
+		status.Target = target
+
+		status.URL = h.gitspaceRouteHosts(route)[0] + route.Spec.PathPrefix
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+
+	if equality.Semantic.DeepEqual(status, route.Status) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := k8s.PatchGitspaceRouteStatus(ctx, h.dynClient, route.Namespace, route.Name, status); err != nil {
+		h.logger.Warn("Failed to update GitspaceRoute status",
+			zap.String("gitspace_route", gitspaceRouteKey(route)),
+			zap.Error(err),
+		)
+	}
+}
+
+// syncReferencingGitspaceRoutes 工作负载变化后，重新同步引用它或与其 identifier 域名冲突的 GitspaceRoute
+// 调用方不能持有工作负载锁
+func (h *EventHandler) syncReferencingGitspaceRoutes(workload k8s.Workload) {
+	if h.gitspaceRoutes == nil || !h.active() {
+		return
+	}
+
+	routes, err := h.gitspaceRoutes.List()
+	if err != nil {
+		h.logger.Warn("Failed to list GitspaceRoutes", zap.Error(err))
+		return
+	}
+
+	identifier := k8s.GetGitspaceIdentifier(workload)
+	for _, route := range routes {
+		if !referencesWorkload(route, workload) && !h.gitspaceRouteClaimsIdentifier(route, identifier) {
+			continue
+		}
+
+		lock := h.getWorkloadLock(gitspaceRouteKey(route))
+		lock.Lock()
+		_ = h.syncGitspaceRoute(route)
+		lock.Unlock()
+	}
+}
+
+// referencesWorkload 判断 GitspaceRoute 的 workloadRef 是否指向该工作负载
+func referencesWorkload(route *k8s.GitspaceRoute, workload k8s.Workload) bool {
+	ref := route.Spec.WorkloadRef
+	if ref == nil || route.Namespace != workload.GetNamespace() || ref.Name != workload.GetName() {
+		return false
+	}
+	kind := ref.Kind
+	return kind == workload.Kind() || (kind == "" && workload.Kind() == k8s.KindDeployment)
+}
+
+// gitspaceRouteClaimsIdentifier 判断 GitspaceRoute 的域名是否落在 <identifier>.<base_domain> 上
+func (h *EventHandler) gitspaceRouteClaimsIdentifier(route *k8s.GitspaceRoute, identifier string) bool {
+	if identifier == "" {
+		return false
+	}
+	for _, host := range h.gitspaceRouteHosts(route) {
+		isIdentifier := func(label string) bool { return label == identifier }
+		if k8s.CheckGitspaceRouteHost(host, h.baseDomain, isIdentifier) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// basicAuthHandlerConfig 构造 Caddy authentication 处理器（http_basic）
+func basicAuthHandlerConfig(auth *k8s.BasicAuth) map[string]any {
+	accounts := make([]map[string]string, 0, len(auth.Users))
+	for _, user := range auth.Users {
+		accounts = append(accounts, map[string]string{
+			"username": user.Username,
+			"password": user.PasswordHash,
+		})
+	}
+
+	realm := auth.Realm
+	if realm == "" {
+		realm = "restricted"
+	}
+
+	return map[string]any{
+		"handler": "authentication",
+		"providers": map[string]any{
+			"http_basic": map[string]any{
+				"accounts": accounts,
+				"hash": map[string]string{
+					"algorithm": "bcrypt",
+				},
+				"realm": realm,
+			},
+		},
+	}
+}
+
+// Interface guard
+var _ k8s.GitspaceRouteHandler = (*EventHandler)(nil)
@@ -4,92 +4,284 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 
+	"github.com/ysicing/caddy2-gitspace/config"
 	"github.com/ysicing/caddy2-gitspace/k8s"
 	"github.com/ysicing/caddy2-gitspace/router"
 	"go.uber.org/zap"
 )
 
+// handlerSettings 事件处理设置，配置重载时由运行中的控制器直接替换
+type handlerSettings struct {
+	defaultPort int
+	wakeTimeout time.Duration
+	drainPeriod time.Duration
+
+	// ingressStatusAddress 写回 Ingress status 的负载均衡地址（为空时不写回）
+	ingressStatusAddress string
+
+	// unreadyGracePeriod 工作负载变为未就绪后保留路由的宽限期
+	unreadyGracePeriod time.Duration
+	// minRouteChangeInterval 同一工作负载两次路由变化之间的最小间隔
+	minRouteChangeInterval time.Duration
+	// keepUnreadyPods 路由指向的 Pod 仍在运行时保留路由并标记为降级
+	keepUnreadyPods bool
+
+	// ipFamily 双栈集群中选择 Pod IP 的地址族偏好（ipv4、ipv6、any）
+	ipFamily string
+
+	// healthChecks 生成路由的默认健康检查设置（可被工作负载注解覆盖）
+	healthChecks k8s.HealthCheckSettings
+
+	// rateLimits 生成路由的默认限流设置（可被工作负载注解覆盖）
+	rateLimits rateLimitSettings
+
+	// clientCAFiles 校验客户端证书的 CA，为空时不能要求客户端证书
+	clientCAFiles []string
+	// portForwarding 是否开启按需端口转发，客户端证书策略需要同时匹配端口域名
+	portForwarding bool
+
+	// useFinalizers 为工作负载添加路由清理 finalizer
+	useFinalizers bool
+	// finalizerTimeout 删除路由持续失败时强制移除 finalizer 的超时
+	finalizerTimeout time.Duration
+
+	// readinessProbePath 设置 route-ready 条件前探测上游的路径（为空时不探测）
+	readinessProbePath string
+	// readinessProbeTimeout 上游探测的最长等待时间
+	readinessProbeTimeout time.Duration
+}
+
+// newHandlerSettings 从配置创建事件处理设置
+func newHandlerSettings(cfg *config.Config) *handlerSettings {
+	return &handlerSettings{
+		defaultPort: cfg.DefaultPort,
+		wakeTimeout: cfg.GetWakeTimeoutDuration(),
+		drainPeriod: cfg.GetDrainPeriodDuration(),
+
+		ingressStatusAddress: cfg.IngressStatusAddress,
+
+		unreadyGracePeriod:     cfg.GetUnreadyGracePeriodDuration(),
+		minRouteChangeInterval: cfg.GetMinRouteChangeIntervalDuration(),
+		keepUnreadyPods:        cfg.KeepUnreadyPods,
+
+		ipFamily: cfg.IPFamily,
+
+		healthChecks: k8s.HealthCheckSettings{
+			Path:            cfg.HealthCheckPath,
+			Interval:        cfg.GetHealthCheckIntervalDuration(),
+			ExpectStatus:    cfg.HealthCheckStatus,
+			FailDuration:    cfg.GetHealthCheckFailDurationDuration(),
+			UnhealthyStatus: cfg.HealthCheckUnhealthyStatus,
+		},
+
+		rateLimits: rateLimitSettings{
+			client:    cfg.GetRateLimitClient(),
+			route:     cfg.GetRateLimitRoute(),
+			bandwidth: cfg.GetBandwidthLimit(),
+		},
+
+		clientCAFiles:  cfg.ClientCAFiles,
+		portForwarding: cfg.PortForwarding,
+
+		useFinalizers:    cfg.UseFinalizers,
+		finalizerTimeout: cfg.GetFinalizerTimeoutDuration(),
+
+		readinessProbePath:    cfg.ReadinessProbePath,
+		readinessProbeTimeout: cfg.GetReadinessProbeTimeoutDuration(),
+	}
+}
+
 // EventHandler 实现 k8s.EventHandler 接口
 // 连接 Watcher 和 AdminAPIClient
 type EventHandler struct {
 	adminClient *router.AdminAPIClient
 	tracker     *router.RouteIDTracker
 	k8sClient   kubernetes.Interface
+	dynClient   dynamic.Interface
 	namespace   string
 	baseDomain  string
-	defaultPort int
 	logger      *zap.Logger
 
-	// 并发控制：为每个 deployment 维护独立的互斥锁
+	// gatewayName 只处理挂载到该 Gateway 的 HTTPRoute
+	gatewayName string
+
+	// upstreamTLS 签发上游证书，生成的工作负载路由使用 HTTPS 访问上游（未开启 upstream_tls 时为 nil）
+	upstreamTLS *upstreamIssuer
+
+	// current 当前的事件处理设置，通过 settings 读取
+	current atomic.Pointer[handlerSettings]
+
+	// ctx 控制器的生命周期，所有 Admin API 和 K8s 调用都基于它
+	ctx context.Context
+	// active 返回 false 时忽略事件（控制器已被新配置取代）
+	active func() bool
+	// gitspaceRoutes GitspaceRoute 缓存，工作负载变化时重新同步引用它的路由（可为 nil）
+	gitspaceRoutes *k8s.GitspaceRouteWatcher
+	// workloads 工作负载缓存，用于判断 identifier 的所有者（可为 nil）
+	workloads *k8s.Watcher
+	// recorder 记录 Kubernetes Event（可为 nil）
+	recorder record.EventRecorder
+	// wg 跟踪唤醒、排空等后台 goroutine
+	wg sync.WaitGroup
+
+	// 并发控制：为每个工作负载维护独立的互斥锁
 	// 防止并发事件触发重复的路由创建
-	deploymentLocks sync.Map // key: deploymentKey (namespace/name), value: *sync.Mutex
+	workloadLocks sync.Map // key: workloadKey (kind/namespace/name), value: *sync.Mutex
+
+	// 唤醒合并：同一工作负载的并发唤醒请求共享一次等待
+	wakeMu    sync.Mutex
+	wakeCalls map[string]*wakeCall
+
+	// 排空中的路由：删除路由前等待进行中的请求完成
+	drains *router.DrainSet
+
+	// finalize 重试：上一次的退避时间和是否有待执行的重试
+	finalizeRetries sync.Map // key: workloadKey, value: time.Duration
+	finalizePending sync.Map // key: workloadKey, value: struct{}
+
+	// 抖动抑制：上一次路由变化的时间和是否有待执行的延迟同步
+	routeChanges       sync.Map // key: workloadKey, value: time.Time
+	routeResyncPending sync.Map // key: workloadKey, value: struct{}
+
+	// 进行中的 route-ready 探测，避免同一 Pod 重复探测
+	readinessProbes sync.Map // key: namespace/name, value: struct{}
+
+	// 已创建客户端证书策略的路由
+	clientCertPolicies sync.Map // key: routeID, value: struct{}
 }
 
 // NewEventHandler 创建新的 EventHandler
 func NewEventHandler(
+	ctx context.Context,
 	adminClient *router.AdminAPIClient,
 	tracker *router.RouteIDTracker,
 	k8sClient kubernetes.Interface,
-	namespace string,
-	baseDomain string,
-	defaultPort int,
+	dynClient dynamic.Interface,
+	cfg *config.Config,
 	logger *zap.Logger,
 ) *EventHandler {
-	return &EventHandler{
+	var issuer *upstreamIssuer
+	if cfg.UpstreamTLS {
+		issuer = newUpstreamIssuer(cfg.GetUpstreamTLSLifetimeDuration())
+	}
+
+	h := &EventHandler{
 		adminClient: adminClient,
 		tracker:     tracker,
 		k8sClient:   k8sClient,
-		namespace:   namespace,
-		baseDomain:  baseDomain,
-		defaultPort: defaultPort,
+		dynClient:   dynClient,
+		namespace:   cfg.Namespace,
+		baseDomain:  cfg.BaseDomain,
 		logger:      logger,
+
+		gatewayName: cfg.GatewayName,
+		upstreamTLS: issuer,
+
+		ctx:       ctx,
+		active:    func() bool { return true },
+		wakeCalls: make(map[string]*wakeCall),
+		drains:    router.NewDrainSet(),
 	}
+	h.current.Store(newHandlerSettings(cfg))
+	return h
 }
 
-// getDeploymentLock 获取或创建 deployment 专用的互斥锁
-func (h *EventHandler) getDeploymentLock(deploymentKey string) *sync.Mutex {
-	lock, _ := h.deploymentLocks.LoadOrStore(deploymentKey, &sync.Mutex{})
+// settings 返回当前的事件处理设置
+func (h *EventHandler) settings() *handlerSettings {
+	return h.current.Load()
+}
+
+// applyConfig 将重载后的配置交给运行中的事件处理器
+// 只替换 handlerSettings 中的设置，其余设置变化时会重建控制器
+func (h *EventHandler) applyConfig(cfg *config.Config) {
+	h.current.Store(newHandlerSettings(cfg))
+}
+
+// Wait 等待所有后台 goroutine 退出（需先取消 ctx）
+func (h *EventHandler) Wait() {
+	h.wg.Wait()
+}
+
+// getWorkloadLock 获取或创建工作负载专用的互斥锁
+func (h *EventHandler) getWorkloadLock(workloadKey string) *sync.Mutex {
+	lock, _ := h.workloadLocks.LoadOrStore(workloadKey, &sync.Mutex{})
 	return lock.(*sync.Mutex)
 }
 
-// OnDeploymentAdd 处理 Deployment 创建事件
-func (h *EventHandler) OnDeploymentAdd(deployment *appsv1.Deployment) error {
-	// 获取 deployment 专用锁，防止并发处理
-	deploymentKey := fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name)
-	lock := h.getDeploymentLock(deploymentKey)
+// OnWorkloadAdd 处理工作负载创建事件
+func (h *EventHandler) OnWorkloadAdd(workload k8s.Workload) error {
+	if !h.active() {
+		return nil
+	}
+	// 释放工作负载锁后同步引用它的 GitspaceRoute
+	defer h.syncReferencingGitspaceRoutes(workload)
+
+	// 获取工作负载专用锁，防止并发处理
+	lock := h.getWorkloadLock(k8s.WorkloadKey(workload))
 	lock.Lock()
 	defer lock.Unlock()
 
-	// 只处理单副本 Deployment
-	replicas := k8s.DesiredReplicaCount(deployment)
+	// 删除中的工作负载（例如重启时仍在等待 finalizer）
+	if workload.GetDeletionTimestamp() != nil {
+		return h.finalizeWorkload(workload)
+	}
+	h.ensureFinalizer(workload)
+
+	return h.syncWorkload(workload)
+}
+
+// syncWorkload 根据工作负载当前状态创建路由（调用方需持有工作负载锁）
+func (h *EventHandler) syncWorkload(workload k8s.Workload) error {
+	workloadKey := k8s.WorkloadKey(workload)
+
+	// 排空后留下的 stopping 路由在下一次同步时移除（重新创建的路由会直接替换它）
+	defer h.clearStoppingRoute(workloadKey)
+
+	// 先发布上游证书 Secret，Pod 挂载它后才能启动
+	if err := h.syncUpstreamSecret(workload); err != nil {
+		h.logger.Warn("Failed to sync upstream TLS secret",
+			zap.String("workload", workloadKey),
+			zap.Error(err),
+		)
+	}
+
+	// 开启自动唤醒且尚未就绪的工作负载使用唤醒路由占位
+	if wantsActivatorRoute(workload) {
+		return h.createActivatorRoute(workload)
+	}
+
+	// 只处理单副本工作负载
+	replicas := workload.DesiredReplicas()
 	if replicas != 1 {
-		h.logger.Debug("Skipping non-single-replica deployment",
-			zap.String("deployment", deployment.Name),
+		h.logger.Debug("Skipping non-single-replica workload",
+			zap.String("workload", workloadKey),
 			zap.Int32("replicas", replicas),
 		)
 		return nil
 	}
 
-	// 检查 Deployment 是否就绪
-	if !isDeploymentReady(deployment) {
-		h.logger.Debug("Deployment not ready yet, skipping",
-			zap.String("deployment", deployment.Name),
+	// 检查工作负载是否就绪
+	if !h.workloadRoutable(workload) {
+		h.logger.Debug("Workload not ready yet, skipping",
+			zap.String("workload", workloadKey),
 		)
 		return nil
 	}
 
 	// 查找就绪的 Pod
-	pod, err := h.findReadyPod(deployment)
+	pod, err := h.findReadyPod(workload)
 	if err != nil {
 		h.logger.Error("Failed to find ready pod",
-			zap.String("deployment", deployment.Name),
+			zap.String("workload", workloadKey),
 			zap.Error(err),
 		)
 		return err
@@ -97,100 +289,167 @@ func (h *EventHandler) OnDeploymentAdd(deployment *appsv1.Deployment) error {
 
 	if pod == nil {
 		h.logger.Debug("No ready pod found",
-			zap.String("deployment", deployment.Name),
+			zap.String("workload", workloadKey),
 		)
 		return nil
 	}
 
 	// 创建路由
-	return h.createRoute(deployment, pod)
+	return h.createRoute(workload, pod)
 }
 
-// OnDeploymentUpdate 处理 Deployment 更新事件
-func (h *EventHandler) OnDeploymentUpdate(oldDeployment, newDeployment *appsv1.Deployment) error {
-	// 获取 deployment 专用锁，防止并发处理
-	deploymentKey := fmt.Sprintf("%s/%s", newDeployment.Namespace, newDeployment.Name)
-	lock := h.getDeploymentLock(deploymentKey)
+// OnWorkloadUpdate 处理工作负载更新事件
+func (h *EventHandler) OnWorkloadUpdate(oldWorkload, newWorkload k8s.Workload) error {
+	if !h.active() {
+		return nil
+	}
+	defer h.syncReferencingGitspaceRoutes(newWorkload)
+
+	workloadKey := k8s.WorkloadKey(newWorkload)
+
+	// identifier 变化后，旧 identifier 的其他候选者可能成为所有者（释放锁后执行）
+	oldIdentifier := k8s.GetGitspaceIdentifier(oldWorkload)
+	newIdentifier := k8s.GetGitspaceIdentifier(newWorkload)
+	if oldIdentifier != newIdentifier {
+		defer h.syncIdentifierClaimants(oldIdentifier, workloadKey)
+	}
+
+	// 获取工作负载专用锁，防止并发处理
+	lock := h.getWorkloadLock(workloadKey)
 	lock.Lock()
 	defer lock.Unlock()
 
-	oldReplicas := k8s.DesiredReplicaCount(oldDeployment)
-	newReplicas := k8s.DesiredReplicaCount(newDeployment)
+	// 场景 -2: 工作负载删除中 → 先删除路由，再移除 finalizer
+	if newWorkload.GetDeletionTimestamp() != nil && k8s.HasFinalizer(newWorkload, k8s.FinalizerRouteCleanup) {
+		return h.finalizeWorkload(newWorkload)
+	}
+	h.ensureFinalizer(newWorkload)
+
+	// 场景 -1: identifier 变化 → 删除旧域名的路由，按新 identifier 重新同步
+	if oldIdentifier != newIdentifier {
+		if err := h.retargetRoute(newWorkload, oldIdentifier, newIdentifier); err != nil {
+			return err
+		}
+		return h.syncWorkload(newWorkload)
+	}
 
-	oldReady := isDeploymentReady(oldDeployment)
-	newReady := isDeploymentReady(newDeployment)
+	oldReplicas := oldWorkload.DesiredReplicas()
+	newReplicas := newWorkload.DesiredReplicas()
 
-	// 场景 1: 副本数从 1 变为其他值 → 删除路由
+	oldReady := k8s.IsWorkloadRoutable(oldWorkload, nil)
+	if k8s.RoutabilityFromPod(oldWorkload) {
+		// 声明了 readiness gate 的工作负载状态不反映 Pod 容器就绪，以路由是否指向 Pod 作为此前的状态
+		oldReady = h.routesToPod(workloadKey)
+	}
+	newReady := h.workloadRoutable(newWorkload)
+
+	// 场景 1: 副本数从 1 变为其他值 → 删除路由（开启自动唤醒时切换为唤醒路由）
 	if oldReplicas == 1 && newReplicas != 1 {
-		h.logger.Info("Deployment replicas changed from 1, deleting route",
-			zap.String("deployment", newDeployment.Name),
+		h.logger.Info("Workload replicas changed from 1, removing route",
+			zap.String("workload", workloadKey),
 			zap.Int32("new_replicas", newReplicas),
 		)
-		return h.deleteRoute(newDeployment)
+		return h.parkOrDeleteRoute(newWorkload)
 	}
 
 	// 场景 2: 副本数从其他值变为 1 → 尝试创建路由
 	if oldReplicas != 1 && newReplicas == 1 {
-		h.logger.Info("Deployment replicas changed to 1",
-			zap.String("deployment", newDeployment.Name),
+		h.logger.Info("Workload replicas changed to 1",
+			zap.String("workload", workloadKey),
 		)
-		return h.OnDeploymentAdd(newDeployment)
+		return h.syncWorkload(newWorkload)
+	}
+
+	// 访问限制等路由配置注解变化时需要重新写入路由（ApplyRoute 会跳过无变化的写入）
+	settingsChanged := k8s.RouteSettingsChanged(oldWorkload.GetAnnotations(), newWorkload.GetAnnotations())
+
+	// 场景 0: 副本数保持为 0 → 跟随自动唤醒注解的开关创建或删除唤醒路由
+	if newReplicas == 0 {
+		if k8s.IsAutowakeEnabled(newWorkload.GetAnnotations()) {
+			if _, exists := h.tracker.Get(workloadKey); !exists || settingsChanged {
+				return h.createActivatorRoute(newWorkload)
+			}
+			return nil
+		}
+		return h.deleteRoute(newWorkload)
 	}
 
 	// 场景 3: 副本数保持为 1，但就绪状态变化
 	if newReplicas == 1 {
 		// 从未就绪变为就绪 → 创建路由
 		if !oldReady && newReady {
-			h.logger.Info("Deployment became ready, creating route",
-				zap.String("deployment", newDeployment.Name),
+			h.logger.Info("Workload became ready, creating route",
+				zap.String("workload", workloadKey),
 			)
-			return h.OnDeploymentAdd(newDeployment)
+			return h.syncWorkload(newWorkload)
 		}
 
-		// 从就绪变为未就绪 → 删除路由
+		// 从就绪变为未就绪 → 删除路由（开启自动唤醒时切换为唤醒路由），按配置抑制抖动
 		if oldReady && !newReady {
-			h.logger.Info("Deployment became not ready, deleting route",
-				zap.String("deployment", newDeployment.Name),
-			)
-			return h.deleteRoute(newDeployment)
+			return h.handleUnready(newWorkload)
+		}
+
+		// 保持未就绪 → 降级保留的路由所指 Pod 不再运行时移除路由
+		if !oldReady && !newReady && h.settings().keepUnreadyPods && !wantsActivatorRoute(newWorkload) {
+			if routeInfo, exists := h.tracker.Get(workloadKey); exists && routeInfo.TargetAddr != activatorUpstream {
+				pod := h.routedRunningPod(newWorkload)
+				if pod == nil {
+					h.logger.Info("Degraded pod is gone, removing route",
+						zap.String("workload", workloadKey),
+					)
+					return h.removeUnreadyRoute(newWorkload)
+				}
+				if settingsChanged {
+					return h.createRoute(newWorkload, pod)
+				}
+			}
+			return nil
 		}
 
 		// 保持就绪状态 → 可能是 Pod 重建（IP 变化）
 		// 使用缓存的 TargetAddr 检查 Pod IP 是否变化，避免频繁调用 GetRoute
 		if oldReady && newReady {
 			// 检查是否有就绪的 Pod
-			pod, err := h.findReadyPod(newDeployment)
+			pod, err := h.findReadyPod(newWorkload)
 			if err != nil {
 				return err
 			}
 
 			if pod != nil {
+				// 端口无法确定时由 createRoute 标记失败并删除路由
+				port, err := k8s.ResolveWorkloadPort(newWorkload, h.settings().defaultPort)
+				if err != nil {
+					return h.createRoute(newWorkload, pod)
+				}
+
 				// 计算期望的 target address
-				expectedAddr := fmt.Sprintf("%s:%d", pod.Status.PodIP, getPortFromDeployment(newDeployment, h.defaultPort))
+				expectedAddr := h.podTarget(pod, port)
 
 				// 从 Tracker 查询缓存的路由信息
-				deploymentKey := fmt.Sprintf("%s/%s", newDeployment.Namespace, newDeployment.Name)
-				routeInfo, exists := h.tracker.Get(deploymentKey)
+				routeInfo, exists := h.tracker.Get(workloadKey)
 
 				if exists && routeInfo != nil {
 					// 比较缓存的 TargetAddr 与期望值
-					if routeInfo.TargetAddr != expectedAddr {
+					if !router.SameTarget(routeInfo.TargetAddr, expectedAddr) {
 						h.logger.Info("Pod IP changed, updating route",
-							zap.String("deployment", newDeployment.Name),
+							zap.String("workload", workloadKey),
 							zap.String("old_target", routeInfo.TargetAddr),
 							zap.String("new_target", expectedAddr),
 						)
-						// 删除旧路由
-						if err := h.deleteRoute(newDeployment); err != nil {
-							h.logger.Error("Failed to delete old route", zap.Error(err))
-						}
-						// 创建新路由
-						return h.createRoute(newDeployment, pod)
+						// 直接替换路由：已建立的升级连接在排空期内继续使用旧上游
+						return h.createRoute(newWorkload, pod)
 					}
-					// Pod IP 没有变化，跳过更新
+					// Pod IP 没有变化但路由配置注解变化，重新写入路由
+					if settingsChanged {
+						h.logger.Info("Route settings changed, updating route",
+							zap.String("workload", workloadKey),
+						)
+						return h.createRoute(newWorkload, pod)
+					}
+					// Pod IP 和路由配置都没有变化，跳过更新
 				} else {
 					// 没有路由，创建新路由
-					return h.createRoute(newDeployment, pod)
+					return h.createRoute(newWorkload, pod)
 				}
 			}
 		}
@@ -199,58 +458,128 @@ func (h *EventHandler) OnDeploymentUpdate(oldDeployment, newDeployment *appsv1.D
 	return nil
 }
 
-// OnDeploymentDelete 处理 Deployment 删除事件
-func (h *EventHandler) OnDeploymentDelete(deployment *appsv1.Deployment) error {
-	// 获取 deployment 专用锁，防止并发处理
-	deploymentKey := fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name)
-	lock := h.getDeploymentLock(deploymentKey)
+// OnWorkloadDelete 处理工作负载删除事件
+func (h *EventHandler) OnWorkloadDelete(workload k8s.Workload) error {
+	if !h.active() {
+		return nil
+	}
+	defer h.syncReferencingGitspaceRoutes(workload)
+
+	// 释放锁后让同一 identifier 的其他候选者接管路由
+	workloadKey := k8s.WorkloadKey(workload)
+	defer h.syncIdentifierClaimants(k8s.GetGitspaceIdentifier(workload), workloadKey)
+
+	// 获取工作负载专用锁，防止并发处理
+	lock := h.getWorkloadLock(workloadKey)
 	lock.Lock()
 	defer lock.Unlock()
 
 	// 删除后清理锁（可选优化）
-	defer h.deploymentLocks.Delete(deploymentKey)
+	defer h.workloadLocks.Delete(workloadKey)
+	defer h.routeChanges.Delete(workloadKey)
+	defer h.finalizeRetries.Delete(workloadKey)
 
-	return h.deleteRoute(deployment)
+	// 对象仍存在但不再受管理（移除了 managed-by 标签），释放遗留的 finalizer
+	if workload.GetDeletionTimestamp() == nil {
+		h.releaseFinalizer(workload)
+	}
+
+	return h.deleteRoute(workload)
 }
 
 // createRoute 创建路由
-func (h *EventHandler) createRoute(deployment *appsv1.Deployment, pod *corev1.Pod) error {
-	// 从 deployment labels 获取稳定的 gitspace identifier
-	// 注意：使用 gitspaceIdentifier 而不是 deployment.Name
-	// 这是因为 deployment name 可能包含实例后缀，不稳定
-	gitspaceIdentifier := k8s.GetGitspaceIdentifier(deployment)
+func (h *EventHandler) createRoute(workload k8s.Workload, pod *corev1.Pod) error {
+	workloadKey := k8s.WorkloadKey(workload)
+
+	// 从工作负载 labels 获取稳定的 gitspace identifier
+	// 注意：使用 gitspaceIdentifier 而不是工作负载名称
+	// 这是因为工作负载名称可能包含实例后缀，不稳定
+	gitspaceIdentifier := k8s.GetGitspaceIdentifier(workload)
 	if gitspaceIdentifier == "" {
-		h.logger.Error("Failed to get gitspace identifier from deployment",
-			zap.String("deployment", deployment.Name),
+		h.logger.Error("Failed to get gitspace identifier from workload",
+			zap.String("workload", workloadKey),
 		)
-		return fmt.Errorf("missing gitspace identifier for deployment %s", deployment.Name)
+		return fmt.Errorf("missing gitspace identifier for %s", workloadKey)
 	}
 
-	// 读取端口注解
-	port, err := k8s.GetPortFromAnnotation(deployment.Annotations, h.defaultPort)
+	// identifier 已被更早的工作负载占用
+	if !h.claimIdentifier(workload, gitspaceIdentifier) {
+		return nil
+	}
+
+	// 解析目标端口（端口注解、容器端口名称或声明的容器端口），无法确定时不创建路由，已有路由随之删除
+	port, err := k8s.ResolveWorkloadPort(workload, h.settings().defaultPort)
 	if err != nil {
-		h.logger.Warn("Invalid port annotation, using default",
-			zap.String("deployment", deployment.Name),
+		h.logger.Warn("Invalid target port, route not created",
+			zap.String("workload", workloadKey),
 			zap.String("gitspace_identifier", gitspaceIdentifier),
-			zap.Int("default_port", h.defaultPort),
 			zap.Error(err),
 		)
-		port = h.defaultPort
+		h.markRouteFailed(workload, eventReasonInvalidPort, err.Error())
+		return h.deleteRoute(workload)
+	}
+
+	// 网络访问限制无效时不创建路由，已有路由随之删除，避免限制失效期间对外开放
+	policy, ok := h.networkPolicyFor(workload)
+	if !ok {
+		return h.deleteRoute(workload)
 	}
 
 	// 生成 Route ID 和域名（使用 gitspaceIdentifier）
-	deploymentKey := fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name)
 	routeID := router.BuildRouteID(gitspaceIdentifier)
 	domain := fmt.Sprintf("%s.%s", gitspaceIdentifier, h.baseDomain)
-	targetAddr := fmt.Sprintf("%s:%d", pod.Status.PodIP, port)
+	targetAddr := h.podTarget(pod, port)
+
+	// 上游变化时检查路由变化间隔，距上次变化过近则延迟切换
+	previous, tracked := h.tracker.Get(workloadKey)
+	changed := !tracked || previous.RouteID != routeID || !router.SameTarget(previous.TargetAddr, targetAddr)
+	if changed && h.deferRouteChange(workload) {
+		return nil
+	}
+
+	// 运行中但未就绪的 Pod（keep_unready_pods）通过响应头标记为降级
+	degraded := !k8s.IsPodRoutable(pod)
+	routeStatus := k8s.RouteStatusReady
+	var handlers []map[string]any
+	if degraded {
+		routeStatus = k8s.RouteStatusDegraded
+		handlers = append(handlers, degradedHandlerConfig())
+	}
 
 	// 调用 Admin API 创建路由（CreateRoute 已经是幂等的，会自动检查和处理重复）
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
 	defer cancel()
 
-	if err := h.adminClient.CreateRoute(ctx, routeID, domain, pod.Status.PodIP, port); err != nil {
+	// 路由即将被重新创建，取消正在进行的排空
+	h.cancelDrain(workloadKey)
+
+	// 先写入客户端证书策略，路由生效时握手已经要求证书
+	if err := h.syncClientCertPolicy(ctx, routeID, domain, policy); err != nil {
+		h.logger.Error("Failed to sync client certificate policy",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	transport, err := h.upstreamTransport(gitspaceIdentifier)
+	if err != nil {
+		h.logger.Error("Failed to prepare upstream transport",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	spec := h.workloadRouteSpec(workload, routeID, domain, targetAddr, handlers...)
+	spec.HealthChecks = h.healthChecksFor(workload)
+	spec.AllowCIDRs = policy.AllowCIDRs
+	spec.Transport = transport
+	if err := h.adminClient.ApplyRoute(ctx, spec); err != nil {
 		h.logger.Error("Failed to create route",
-			zap.String("deployment", deployment.Name),
+			zap.String("workload", workloadKey),
 			zap.String("gitspace_identifier", gitspaceIdentifier),
 			zap.String("route_id", routeID),
 			zap.String("domain", domain),
@@ -260,28 +589,39 @@ func (h *EventHandler) createRoute(deployment *appsv1.Deployment, pod *corev1.Po
 	}
 
 	// 记录到 Tracker（缓存 RouteID 和 TargetAddr）
-	h.tracker.Set(deploymentKey, routeID, targetAddr)
+	h.tracker.Set(workloadKey, routeID, targetAddr)
+	h.evictDuplicateClaims(workload, gitspaceIdentifier, routeID)
+	if changed {
+		h.recordRouteChange(workloadKey)
+	}
 
 	h.logger.Info("Route created",
-		zap.String("deployment", deployment.Name),
+		zap.String("workload", workloadKey),
 		zap.String("gitspace_identifier", gitspaceIdentifier),
 		zap.String("domain", domain),
 		zap.String("target", targetAddr),
+		zap.Bool("degraded", degraded),
 	)
 
-	// 写回注解到 Deployment
-	annotations := map[string]string{
-		k8s.AnnotationURL:     domain,
-		k8s.AnnotationSynced:  time.Now().Format(time.RFC3339),
-		k8s.AnnotationRouteID: routeID,
+	// 声明了 readiness gate 的 Pod 在路由写入后才变为 Ready
+	if !degraded {
+		h.markRouteReady(workload, pod, targetAddr)
 	}
 
-	ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
+	// 写回注解到工作负载，只在域名、路由 ID 或上游地址变化时写入，
+	// 避免每次同步都更新工作负载、触发新的 update 事件
+	if !k8s.RouteAnnotationsChanged(workload.GetAnnotations(), domain, routeID, targetAddr, routeStatus) {
+		return nil
+	}
+
+	annotations := k8s.RouteAnnotations(domain, routeID, targetAddr, routeStatus, time.Now())
+
+	ctx2, cancel2 := context.WithTimeout(h.ctx, 5*time.Second)
 	defer cancel2()
 
-	if err := k8s.PatchDeploymentAnnotation(ctx2, h.k8sClient, deployment.Namespace, deployment.Name, annotations); err != nil {
-		h.logger.Warn("Failed to patch deployment annotations",
-			zap.String("deployment", deployment.Name),
+	if err := k8s.ApplyWorkloadAnnotations(ctx2, h.k8sClient, workload.Kind(), workload.GetNamespace(), workload.GetName(), annotations); err != nil {
+		h.logger.Warn("Failed to patch workload annotations",
+			zap.String("workload", workloadKey),
 			zap.String("gitspace_identifier", gitspaceIdentifier),
 			zap.Error(err),
 		)
@@ -291,28 +631,149 @@ func (h *EventHandler) createRoute(deployment *appsv1.Deployment, pod *corev1.Po
 	return nil
 }
 
+// createActivatorRoute 为开启自动唤醒的工作负载创建唤醒路由
+// 路由先经过 gitspace_activator 处理器唤醒工作负载，再代理到其写入的上游地址
+func (h *EventHandler) createActivatorRoute(workload k8s.Workload) error {
+	workloadKey := k8s.WorkloadKey(workload)
+
+	gitspaceIdentifier := k8s.GetGitspaceIdentifier(workload)
+	if gitspaceIdentifier == "" {
+		h.logger.Error("Failed to get gitspace identifier from workload",
+			zap.String("workload", workloadKey),
+		)
+		return fmt.Errorf("missing gitspace identifier for %s", workloadKey)
+	}
+
+	if !h.claimIdentifier(workload, gitspaceIdentifier) {
+		return nil
+	}
+
+	policy, ok := h.networkPolicyFor(workload)
+	if !ok {
+		return h.deleteRoute(workload)
+	}
+
+	routeID := router.BuildRouteID(gitspaceIdentifier)
+	domain := fmt.Sprintf("%s.%s", gitspaceIdentifier, h.baseDomain)
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	h.cancelDrain(workloadKey)
+
+	if err := h.syncClientCertPolicy(ctx, routeID, domain, policy); err != nil {
+		h.logger.Error("Failed to sync client certificate policy",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	transport, err := h.upstreamTransport(gitspaceIdentifier)
+	if err != nil {
+		h.logger.Error("Failed to prepare upstream transport",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	previous, tracked := h.tracker.Get(workloadKey)
+	changed := !tracked || previous.TargetAddr != activatorUpstream
+
+	spec := h.workloadRouteSpec(workload, routeID, domain, activatorUpstream, map[string]any{
+		"handler":   "gitspace_activator",
+		"kind":      workload.Kind(),
+		"namespace": workload.GetNamespace(),
+		"name":      workload.GetName(),
+	})
+	spec.AllowCIDRs = policy.AllowCIDRs
+	spec.Transport = transport
+	if err := h.adminClient.ApplyRoute(ctx, spec); err != nil {
+		h.logger.Error("Failed to create activator route",
+			zap.String("workload", workloadKey),
+			zap.String("gitspace_identifier", gitspaceIdentifier),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	h.tracker.Set(workloadKey, routeID, activatorUpstream)
+	h.evictDuplicateClaims(workload, gitspaceIdentifier, routeID)
+	if changed {
+		h.recordRouteChange(workloadKey)
+	}
+	h.clearRouteReady(workload)
+
+	h.logger.Info("Activator route created",
+		zap.String("workload", workloadKey),
+		zap.String("gitspace_identifier", gitspaceIdentifier),
+		zap.String("domain", domain),
+	)
+
+	return nil
+}
+
+// parkOrDeleteRoute 工作负载不再可服务时，开启自动唤醒则切换为唤醒路由，否则删除路由
+func (h *EventHandler) parkOrDeleteRoute(workload k8s.Workload) error {
+	if wantsActivatorRoute(workload) {
+		return h.createActivatorRoute(workload)
+	}
+	return h.deleteRoute(workload)
+}
+
 // deleteRoute 删除路由
-func (h *EventHandler) deleteRoute(deployment *appsv1.Deployment) error {
-	deploymentKey := fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name)
-	gitspaceIdentifier := k8s.GetGitspaceIdentifier(deployment)
+func (h *EventHandler) deleteRoute(workload k8s.Workload) error {
+	workloadKey := k8s.WorkloadKey(workload)
+	gitspaceIdentifier := k8s.GetGitspaceIdentifier(workload)
 
 	// 从 Tracker 查找 Route 信息
-	routeInfo, exists := h.tracker.Get(deploymentKey)
+	routeInfo, exists := h.tracker.Get(workloadKey)
 	if !exists || routeInfo == nil {
 		h.logger.Debug("No route to delete",
-			zap.String("deployment", deployment.Name),
+			zap.String("workload", workloadKey),
 			zap.String("gitspace_identifier", gitspaceIdentifier),
 		)
 		return nil
 	}
 
+	// 路由下线后不再需要客户端证书策略；排空期间的新请求仍由访问控制检查证书
+	policyCtx, policyCancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer policyCancel()
+	if err := h.deleteClientCertPolicy(policyCtx, routeInfo.RouteID); err != nil {
+		h.logger.Warn("Failed to delete client certificate policy",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", routeInfo.RouteID),
+			zap.Error(err),
+		)
+	}
+
+	// 开启排空时异步等待进行中的请求完成，再删除路由
+	if drainPeriod := h.settings().drainPeriod; drainPeriod > 0 {
+		h.tracker.Delete(workloadKey)
+		h.recordRouteChange(workloadKey)
+		h.startDrain(workloadKey, routeInfo.RouteID)
+		h.clearRouteReady(workload)
+
+		h.logger.Info("Route draining",
+			zap.String("workload", workloadKey),
+			zap.String("gitspace_identifier", gitspaceIdentifier),
+			zap.String("route_id", routeInfo.RouteID),
+			zap.Duration("drain_period", drainPeriod),
+		)
+		return nil
+	}
+
 	// 调用 Admin API 删除路由
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
 	defer cancel()
 
 	if err := h.adminClient.DeleteRoute(ctx, routeInfo.RouteID); err != nil {
 		h.logger.Error("Failed to delete route",
-			zap.String("deployment", deployment.Name),
+			zap.String("workload", workloadKey),
 			zap.String("gitspace_identifier", gitspaceIdentifier),
 			zap.String("route_id", routeInfo.RouteID),
 			zap.Error(err),
@@ -321,10 +782,12 @@ func (h *EventHandler) deleteRoute(deployment *appsv1.Deployment) error {
 	}
 
 	// 清理 Tracker
-	h.tracker.Delete(deploymentKey)
+	h.tracker.Delete(workloadKey)
+	h.recordRouteChange(workloadKey)
+	h.clearRouteReady(workload)
 
 	h.logger.Info("Route deleted",
-		zap.String("deployment", deployment.Name),
+		zap.String("workload", workloadKey),
 		zap.String("gitspace_identifier", gitspaceIdentifier),
 		zap.String("route_id", routeInfo.RouteID),
 	)
@@ -332,49 +795,108 @@ func (h *EventHandler) deleteRoute(deployment *appsv1.Deployment) error {
 	return nil
 }
 
-// findReadyPod 查找 Deployment 的就绪 Pod
-func (h *EventHandler) findReadyPod(deployment *appsv1.Deployment) (*corev1.Pod, error) {
+// proxyRouteSpec 构造代理到 upstream 的路由，handlers 插入在活动记录之后、reverse_proxy 之前
+func (h *EventHandler) proxyRouteSpec(routeID, domain, upstream string, handlers ...map[string]any) router.RouteSpec {
+	return router.RouteSpec{
+		ID:               routeID,
+		Domain:           domain,
+		Upstream:         upstream,
+		Handlers:         append([]map[string]any{activityHandlerConfig(routeID)}, handlers...),
+		StreamCloseDelay: h.settings().drainPeriod,
+	}
+}
+
+// workloadRouteSpec 构造工作负载路由，限流和访问控制处理器位于最前面，被拒绝的请求不计入活动也不会唤醒工作负载
+func (h *EventHandler) workloadRouteSpec(workload k8s.Workload, routeID, domain, upstream string, handlers ...map[string]any) router.RouteSpec {
+	spec := h.proxyRouteSpec(routeID, domain, upstream, handlers...)
+	spec.Handlers = append([]map[string]any{accessHandlerConfig(workload)}, spec.Handlers...)
+	if limit := h.rateLimitFor(workload, routeID); limit != nil {
+		spec.Handlers = append([]map[string]any{limit}, spec.Handlers...)
+	}
+	return spec
+}
+
+// findReadyPod 查找工作负载的就绪 Pod（跳过删除中的 Pod，优先当前版本）
+func (h *EventHandler) findReadyPod(workload k8s.Workload) (*corev1.Pod, error) {
+	// 独立 Pod 本身就是路由目标
+	if w, ok := workload.(k8s.PodWorkload); ok {
+		if w.IsReady() && w.DeletionTimestamp == nil {
+			return w.Pod, nil
+		}
+		return nil, nil
+	}
+
 	// 使用 label selector 查找 Pod
-	labelSelector := metav1.FormatLabelSelector(deployment.Spec.Selector)
+	labelSelector := metav1.FormatLabelSelector(workload.PodSelector())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
 	defer cancel()
 
-	pods, err := h.k8sClient.CoreV1().Pods(deployment.Namespace).List(ctx, metav1.ListOptions{
+	pods, err := h.k8sClient.CoreV1().Pods(workload.GetNamespace()).List(ctx, metav1.ListOptions{
 		LabelSelector: labelSelector,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	// 查找第一个就绪的 Pod
-	for i := range pods.Items {
-		if k8s.IsPodReady(&pods.Items[i]) {
-			return &pods.Items[i], nil
-		}
+	// 滚动更新期间优先选择当前版本的 Pod，新 Pod 就绪后路由随之切换
+	revisionKey, revisionValue, err := k8s.CurrentRevisionLabel(ctx, h.k8sClient, workload)
+	if err != nil {
+		h.logger.Warn("Failed to determine current revision, selecting any ready pod",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
 	}
 
-	return nil, nil
+	return k8s.SelectRoutablePod(pods.Items, revisionKey, revisionValue), nil
 }
 
-// isDeploymentReady 检查 Deployment 是否就绪
-func isDeploymentReady(deployment *appsv1.Deployment) bool {
-	// 检查 Deployment 的状态条件
-	for _, cond := range deployment.Status.Conditions {
-		if cond.Type == appsv1.DeploymentAvailable {
-			return cond.Status == corev1.ConditionTrue
-		}
+// workloadRoutable 判断工作负载是否可以创建或保留路由（见 k8s.IsWorkloadRoutable）
+// 声明了 readiness gate 的工作负载需要查询 Pod：路由指向的 Pod 容器不再就绪、且没有其他可路由的 Pod 时不可路由。
+// 查询失败时按是否已有路由处理，避免 API 暂时不可用导致路由被删除
+func (h *EventHandler) workloadRoutable(workload k8s.Workload) bool {
+	if !k8s.RoutabilityFromPod(workload) {
+		return k8s.IsWorkloadRoutable(workload, nil)
 	}
-	return false
-}
 
-// getPortFromDeployment 从 Deployment 获取端口
-func getPortFromDeployment(deployment *appsv1.Deployment, defaultPort int) int {
-	port, err := k8s.GetPortFromAnnotation(deployment.Annotations, defaultPort)
+	pod, err := h.findReadyPod(workload)
 	if err != nil {
-		return defaultPort
+		h.logger.Warn("Failed to find routable pod of gated workload",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+		return h.routesToPod(k8s.WorkloadKey(workload))
+	}
+	return k8s.IsWorkloadRoutable(workload, pod)
+}
+
+// routesToPod 判断工作负载当前是否有指向 Pod 的路由（唤醒路由除外）
+func (h *EventHandler) routesToPod(workloadKey string) bool {
+	routeInfo, exists := h.tracker.Get(workloadKey)
+	return exists && routeInfo != nil && routeInfo.TargetAddr != activatorUpstream
+}
+
+// wantsActivatorRoute 判断工作负载是否应使用唤醒路由
+// 开启自动唤醒，且副本数为 0，或副本数为 1 但尚未就绪（正在唤醒中）
+// 独立 Pod 无法扩缩容，不支持自动唤醒
+func wantsActivatorRoute(workload k8s.Workload) bool {
+	if workload.Kind() == k8s.KindPod || !k8s.IsAutowakeEnabled(workload.GetAnnotations()) {
+		return false
 	}
-	return port
+	switch workload.DesiredReplicas() {
+	case 0:
+		return true
+	case 1:
+		// 声明了 readiness gate 的工作负载在路由写入前不会就绪，不使用唤醒路由
+		return !k8s.RoutabilityFromPod(workload) && !workload.IsReady()
+	default:
+		return false
+	}
+}
+
+// podTarget 按地址族偏好返回 Pod 的上游地址（IPv6 使用方括号）
+func (h *EventHandler) podTarget(pod *corev1.Pod, port int) string {
+	return router.JoinTarget(k8s.SelectPodIP(pod, h.settings().ipFamily), port)
 }
 
 // Interface guard
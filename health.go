@@ -0,0 +1,153 @@
+package caddy2k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyevents"
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// eventReasonUpstreamUnhealthy 上游变为不健康时记录的事件原因
+const eventReasonUpstreamUnhealthy = "UpstreamUnhealthy"
+
+// upstreamHealth 记录生成路由上游的健康状态
+// 与 routeActivity 一样使用包级变量，跨越 Caddy 配置重载保留
+var upstreamHealth = router.NewUpstreamHealth()
+
+// healthEventHandler 接收 reverse_proxy 主动健康检查发出的 healthy/unhealthy 事件
+type healthEventHandler struct{}
+
+// Handle 记录主动健康检查的结果
+func (healthEventHandler) Handle(_ context.Context, e caddy.Event) error {
+	host, _ := e.Data["host"].(string)
+	if host == "" {
+		return nil
+	}
+	upstreamHealth.SetActive(host, e.Name() == "healthy")
+	return nil
+}
+
+// subscribeHealthEvents 订阅 reverse_proxy 主动健康检查事件（只能在 events 应用启动前调用）
+func subscribeHealthEvents(ctx caddy.Context) error {
+	app, err := ctx.App("events")
+	if err != nil {
+		return fmt.Errorf("failed to load events app: %w", err)
+	}
+	events := app.(*caddyevents.App)
+	for _, name := range []string{"healthy", "unhealthy"} {
+		if err := events.On(name, healthEventHandler{}); err != nil {
+			return fmt.Errorf("failed to subscribe to %s events: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// healthChecksFor 返回工作负载路由的 reverse_proxy health_checks 配置，未启用时返回 nil
+// 注解无效时记录警告并使用全局配置
+func (h *EventHandler) healthChecksFor(workload k8s.Workload) map[string]any {
+	settings, err := k8s.ApplyHealthCheckAnnotations(h.settings().healthChecks, workload.GetAnnotations())
+	if err != nil {
+		h.logger.Warn("Invalid health check annotation, using defaults",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+		settings = h.settings().healthChecks
+	}
+	return healthChecksConfig(settings)
+}
+
+// healthChecksConfig 将健康检查设置转换为 reverse_proxy 的 health_checks 配置
+func healthChecksConfig(settings k8s.HealthCheckSettings) map[string]any {
+	if !settings.Enabled() {
+		return nil
+	}
+
+	checks := make(map[string]any)
+	if settings.Path != "" {
+		active := map[string]any{
+			"uri":      settings.Path,
+			"interval": settings.Interval.String(),
+			"timeout":  min(settings.Interval, 5*time.Second).String(),
+		}
+		if settings.ExpectStatus > 0 {
+			active["expect_status"] = settings.ExpectStatus
+		}
+		checks["active"] = active
+	}
+	if settings.FailDuration > 0 {
+		passive := map[string]any{
+			"fail_duration": settings.FailDuration.String(),
+			"max_fails":     1,
+		}
+		if len(settings.UnhealthyStatus) > 0 {
+			passive["unhealthy_status"] = settings.UnhealthyStatus
+		}
+		checks["passive"] = passive
+	}
+	return checks
+}
+
+// syncUpstreamHealth 刷新被动健康检查状态，并将上游健康状态写回启用了健康检查的工作负载
+func (c *routerController) syncUpstreamHealth() {
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	fails, err := c.adminClient.ListUpstreamFails(ctx)
+	if err != nil {
+		c.logger.Debug("Failed to list upstream health", zap.Error(err))
+	} else {
+		upstreamHealth.SetPassiveFails(fails)
+	}
+
+	for workloadKey, routeInfo := range c.tracker.List() {
+		if routeInfo.TargetAddr == activatorUpstream {
+			continue
+		}
+		kind, namespace, name, err := k8s.SplitWorkloadKey(workloadKey)
+		if err != nil {
+			continue
+		}
+		workload, err := c.watcher.GetWorkload(kind, namespace, name)
+		if err != nil {
+			continue
+		}
+
+		annotations := workload.GetAnnotations()
+		current, annotated := annotations[k8s.AnnotationUpstreamHealth]
+		if c.eventHandler.healthChecksFor(workload) == nil && !annotated {
+			continue
+		}
+
+		status := upstreamHealth.Status(routeInfo.TargetAddr)
+		if current == status {
+			continue
+		}
+
+		if status == router.UpstreamUnhealthy {
+			c.logger.Warn("Upstream unhealthy",
+				zap.String("workload", workloadKey),
+				zap.String("target", routeInfo.TargetAddr),
+			)
+			if c.eventHandler.recorder != nil {
+				c.eventHandler.recorder.Eventf(workload.Object(), corev1.EventTypeWarning, eventReasonUpstreamUnhealthy,
+					"upstream %s failed health checks", routeInfo.TargetAddr)
+			}
+		}
+
+		if err := k8s.ApplyUpstreamHealthAnnotation(ctx, c.k8sClient, kind, namespace, name, status); err != nil {
+			c.logger.Warn("Failed to patch upstream health annotation",
+				zap.String("workload", workloadKey),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Interface guard
+var _ caddyevents.Handler = healthEventHandler{}
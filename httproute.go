@@ -0,0 +1,177 @@
+package caddy2k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// httpRouteID 返回 HTTPRoute 对应的 Caddy 路由 ID
+func httpRouteID(route *k8s.HTTPRoute) string {
+	return fmt.Sprintf("httproute:%s:%s", route.Namespace, route.Name)
+}
+
+// httpRouteKey 返回 HTTPRoute 在 Tracker 和锁中使用的键
+func httpRouteKey(route *k8s.HTTPRoute) string {
+	return k8s.BuildWorkloadKey(k8s.KindHTTPRoute, route.Namespace, route.Name)
+}
+
+// httpRouteReference 返回记录事件使用的 HTTPRoute 引用（HTTPRoute 未注册到 client-go scheme）
+func httpRouteReference(route *k8s.HTTPRoute) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion:      k8s.HTTPRouteGVR.GroupVersion().String(),
+		Kind:            k8s.KindHTTPRoute,
+		Namespace:       route.Namespace,
+		Name:            route.Name,
+		UID:             route.UID,
+		ResourceVersion: route.ResourceVersion,
+	}
+}
+
+// OnHTTPRouteAdd 处理 HTTPRoute 创建事件
+func (h *EventHandler) OnHTTPRouteAdd(route *k8s.HTTPRoute) error {
+	if !h.active() {
+		return nil
+	}
+
+	lock := h.getWorkloadLock(httpRouteKey(route))
+	lock.Lock()
+	defer lock.Unlock()
+
+	return h.syncHTTPRoute(route)
+}
+
+// OnHTTPRouteUpdate 处理 HTTPRoute 更新事件
+func (h *EventHandler) OnHTTPRouteUpdate(oldRoute, newRoute *k8s.HTTPRoute) error {
+	if !h.active() {
+		return nil
+	}
+
+	// spec 未变化的更新（通常是其他控制器写回的 status）不需要重新同步；resync 事件除外
+	if oldRoute.Generation == newRoute.Generation && oldRoute.ResourceVersion != newRoute.ResourceVersion {
+		return nil
+	}
+
+	lock := h.getWorkloadLock(httpRouteKey(newRoute))
+	lock.Lock()
+	defer lock.Unlock()
+
+	return h.syncHTTPRoute(newRoute)
+}
+
+// OnHTTPRouteDelete 处理 HTTPRoute 删除事件
+func (h *EventHandler) OnHTTPRouteDelete(route *k8s.HTTPRoute) error {
+	if !h.active() {
+		return nil
+	}
+
+	key := httpRouteKey(route)
+	lock := h.getWorkloadLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	defer h.workloadLocks.Delete(key)
+
+	return h.removeTrackedRoute(key)
+}
+
+// syncHTTPRoute 将 HTTPRoute 规则转换为一条 Caddy 路由（调用方需持有锁）
+// 外层路由匹配 hostnames，内部每条规则对应一个 subroute 路由，按声明顺序匹配
+func (h *EventHandler) syncHTTPRoute(route *k8s.HTTPRoute) error {
+	key := httpRouteKey(route)
+	routeID := httpRouteID(route)
+
+	// 不再挂载到本 Gateway 的 HTTPRoute 视为删除
+	if !route.AttachedToGateway(h.gatewayName) {
+		return h.removeTrackedRoute(key)
+	}
+
+	// 没有 hostnames 的 HTTPRoute 会匹配所有域名，可能覆盖 gitspace 路由，因此忽略
+	if len(route.Spec.Hostnames) == 0 {
+		h.logger.Warn("Ignoring HTTPRoute without hostnames",
+			zap.String("httproute", key),
+		)
+		return h.removeTrackedRoute(key)
+	}
+
+	// 占用 gitspace 保留域名的 hostname 被忽略
+	hostnames, ignored := k8s.FilterHostnames(route.Spec.Hostnames, h.reservedHostChecker())
+	for _, err := range ignored {
+		h.logger.Warn("Ignoring HTTPRoute hostname",
+			zap.String("httproute", key),
+			zap.Error(err),
+		)
+		if h.recorder != nil {
+			h.recorder.Event(httpRouteReference(route), corev1.EventTypeWarning, eventReasonRuleIgnored, err.Error())
+		}
+	}
+	if len(hostnames) == 0 {
+		return h.removeTrackedRoute(key)
+	}
+
+	var routes []map[string]any
+	var firstUpstream string
+	for i, rule := range route.Spec.Rules {
+		upstreams, weights := k8s.HTTPRouteBackends(route.Namespace, rule.BackendRefs)
+		if len(upstreams) == 0 {
+			h.logger.Warn("Ignoring HTTPRoute rule without supported backends",
+				zap.String("httproute", key),
+				zap.Int("rule", i),
+			)
+			continue
+		}
+		if firstUpstream == "" {
+			firstUpstream = upstreams[0]
+		}
+
+		subroute := map[string]any{
+			"handle": []map[string]any{h.reverseProxyHandlerConfig(upstreams, weights)},
+		}
+		if matchers := k8s.HTTPRouteMatchers(hostnames, rule.Matches); len(matchers) > 0 {
+			subroute["match"] = matchers
+		}
+		routes = append(routes, subroute)
+	}
+
+	if len(routes) == 0 {
+		return h.removeTrackedRoute(key)
+	}
+
+	spec := router.RouteSpec{
+		ID:           routeID,
+		Domain:       hostnames[0],
+		AliasDomains: hostnames[1:],
+		Handlers:     []map[string]any{subrouteHandlerConfig(routes)},
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	if err := h.adminClient.ApplyRoute(ctx, spec); err != nil {
+		h.logger.Error("Failed to program HTTPRoute",
+			zap.String("httproute", key),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if _, exists := h.tracker.Get(key); !exists {
+		h.logger.Info("HTTPRoute programmed",
+			zap.String("httproute", key),
+			zap.Strings("hostnames", hostnames),
+			zap.Int("rules", len(routes)),
+		)
+	}
+	h.tracker.Set(key, routeID, firstUpstream)
+
+	return nil
+}
+
+// Interface guard
+var _ k8s.HTTPRouteHandler = (*EventHandler)(nil)
@@ -0,0 +1,215 @@
+package caddy2k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// eventReasonDuplicateIdentifier identifier 被其他工作负载占用时记录的事件原因
+const eventReasonDuplicateIdentifier = "DuplicateGitspaceIdentifier"
+
+// eventReasonInvalidPort 无法确定目标端口时记录的事件原因
+const eventReasonInvalidPort = "InvalidGitspacePort"
+
+// identifierOwner 从 Informer 缓存中查找 identifier 的所有者
+// 缓存不可用时返回 nil，调用方按无冲突处理
+func (h *EventHandler) identifierOwner(identifier string) k8s.Workload {
+	if h.workloads == nil || !h.workloads.IsReady() {
+		return nil
+	}
+
+	workloads, err := h.workloads.ListWorkloads()
+	if err != nil {
+		h.logger.Warn("Failed to list workloads for identifier check", zap.Error(err))
+		return nil
+	}
+	return k8s.IdentifierOwners(workloads)[identifier]
+}
+
+// identifierInUse 判断 identifier 是否已被工作负载声明（缓存不可用时按未声明处理）
+func (h *EventHandler) identifierInUse(identifier string) bool {
+	return h.identifierOwner(identifier) != nil
+}
+
+// claimIdentifier 检查工作负载是否拥有其 gitspace identifier（调用方需持有工作负载锁）
+// 多个工作负载声明同一 identifier 时，creationTimestamp 最早的获胜，其余标记为 Failed
+func (h *EventHandler) claimIdentifier(workload k8s.Workload, identifier string) bool {
+	owner := h.identifierOwner(identifier)
+	if k8s.OwnsIdentifier(owner, workload) {
+		return true
+	}
+
+	workloadKey := k8s.WorkloadKey(workload)
+	routeID := router.BuildRouteID(identifier)
+
+	// 失败方此前写入的路由交还给所有者
+	if h.tracker.Holds(workloadKey, routeID) {
+		h.cancelDrain(workloadKey)
+
+		ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+		err := h.adminClient.DeleteRoute(ctx, routeID)
+		cancel()
+		if err != nil {
+			h.logger.Error("Failed to delete route of duplicate workload",
+				zap.String("workload", workloadKey),
+				zap.String("route_id", routeID),
+				zap.Error(err),
+			)
+		} else {
+			h.tracker.Delete(workloadKey)
+			h.resyncWorkloadAsync(owner)
+		}
+	}
+
+	h.markDuplicate(workload, identifier, owner)
+	return false
+}
+
+// evictDuplicateClaims 所有者写入路由后，清理其他工作负载在 Tracker 中对同一路由的记录
+func (h *EventHandler) evictDuplicateClaims(owner k8s.Workload, identifier, routeID string) {
+	for _, workloadKey := range h.tracker.Holders(routeID, k8s.WorkloadKey(owner)) {
+		h.tracker.Delete(workloadKey)
+
+		if h.workloads == nil {
+			continue
+		}
+		kind, namespace, name, err := k8s.SplitWorkloadKey(workloadKey)
+		if err != nil {
+			continue
+		}
+		if workload, err := h.workloads.GetWorkload(kind, namespace, name); err == nil {
+			h.markDuplicate(workload, identifier, owner)
+		}
+	}
+}
+
+// markDuplicate 将工作负载的路由状态标记为 Failed 并记录事件，已标记时跳过
+func (h *EventHandler) markDuplicate(workload k8s.Workload, identifier string, owner k8s.Workload) {
+	ownerKey := k8s.WorkloadKey(owner)
+	message := fmt.Sprintf("gitspace identifier %q is already used by %s, no route is created", identifier, ownerKey)
+	if !h.markRouteFailed(workload, eventReasonDuplicateIdentifier, message) {
+		return
+	}
+
+	h.logger.Warn("Duplicate gitspace identifier, route is owned by an older workload",
+		zap.String("workload", k8s.WorkloadKey(workload)),
+		zap.String("gitspace_identifier", identifier),
+		zap.String("owner", ownerKey),
+	)
+}
+
+// markRouteFailed 将工作负载的路由状态标记为 Failed，记录原因并发出警告事件
+// 状态和原因未变化时跳过并返回 false
+func (h *EventHandler) markRouteFailed(workload k8s.Workload, reason, message string) bool {
+	annotations := workload.GetAnnotations()
+	if annotations[k8s.AnnotationRouteStatus] == k8s.RouteStatusFailed && annotations[k8s.AnnotationRouteMessage] == message {
+		return false
+	}
+
+	if h.recorder != nil {
+		h.recorder.Event(workload.Object(), corev1.EventTypeWarning, reason, message)
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+
+	// 只保留状态和原因注解，此前由 FieldManager 写回的域名、路由 ID 等注解随之移除
+	failed := map[string]string{
+		k8s.AnnotationRouteStatus:  k8s.RouteStatusFailed,
+		k8s.AnnotationRouteMessage: message,
+	}
+	if err := k8s.ApplyWorkloadAnnotations(ctx, h.k8sClient, workload.Kind(), workload.GetNamespace(), workload.GetName(), failed); err != nil {
+		h.logger.Warn("Failed to patch workload annotations",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+	}
+	return true
+}
+
+// retargetRoute identifier 变化时删除旧 identifier 的路由（调用方需持有工作负载锁）
+// 新路由由调用方随后同步创建
+func (h *EventHandler) retargetRoute(workload k8s.Workload, oldIdentifier, newIdentifier string) error {
+	workloadKey := k8s.WorkloadKey(workload)
+
+	h.logger.Info("Gitspace identifier changed, retargeting route",
+		zap.String("workload", workloadKey),
+		zap.String("old_identifier", oldIdentifier),
+		zap.String("new_identifier", newIdentifier),
+	)
+
+	staleID, ok := h.tracker.StaleRoute(workloadKey, router.BuildRouteID(newIdentifier))
+	if !ok {
+		return nil
+	}
+
+	h.cancelDrain(workloadKey)
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	if err := h.adminClient.DeleteRoute(ctx, staleID); err != nil {
+		h.logger.Error("Failed to delete route of previous identifier",
+			zap.String("workload", workloadKey),
+			zap.String("route_id", staleID),
+			zap.Error(err),
+		)
+		return err
+	}
+	h.tracker.Delete(workloadKey)
+	return nil
+}
+
+// syncIdentifierClaimants 工作负载释放 identifier（删除或改名）后，让新的所有者接管路由
+// 调用方不能持有工作负载锁
+func (h *EventHandler) syncIdentifierClaimants(identifier, releasedKey string) {
+	if identifier == "" || !h.active() {
+		return
+	}
+
+	owner := h.identifierOwner(identifier)
+	if owner == nil || k8s.WorkloadKey(owner) == releasedKey {
+		return
+	}
+
+	// 所有者已持有路由
+	ownerKey := k8s.WorkloadKey(owner)
+	if h.tracker.Holds(ownerKey, router.BuildRouteID(identifier)) {
+		return
+	}
+
+	lock := h.getWorkloadLock(ownerKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	_ = h.syncWorkload(owner)
+}
+
+// resyncWorkloadAsync 在后台重新同步工作负载（调用方持有其他工作负载的锁时使用）
+func (h *EventHandler) resyncWorkloadAsync(workload k8s.Workload) {
+	h.wg.Go(func() {
+		if !h.active() {
+			return
+		}
+
+		lock := h.getWorkloadLock(k8s.WorkloadKey(workload))
+		lock.Lock()
+		defer lock.Unlock()
+
+		// 获取锁后从缓存读取最新状态
+		if h.workloads != nil {
+			latest, err := h.workloads.GetWorkload(workload.Kind(), workload.GetNamespace(), workload.GetName())
+			if err != nil {
+				return
+			}
+			workload = latest
+		}
+		_ = h.syncWorkload(workload)
+	})
+}
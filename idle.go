@@ -0,0 +1,145 @@
+package caddy2k8s
+
+import (
+	"context"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// runIdleMonitor 定期写回最近活动时间，并缩容超过空闲超时的工作负载
+func (c *routerController) runIdleMonitor() {
+	ticker := time.NewTicker(c.config().GetIdleCheckPeriodDuration())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkIdleWorkloads()
+			c.syncUpstreamHealth()
+		case <-c.ctx.Done():
+			c.logger.Info("Stopping idle monitor")
+			return
+		}
+	}
+}
+
+// checkIdleWorkloads 遍历所有已创建路由的工作负载，同步活动状态
+func (c *routerController) checkIdleWorkloads() {
+	for workloadKey, routeInfo := range c.tracker.List() {
+		kind, namespace, name, err := k8s.SplitWorkloadKey(workloadKey)
+		if err != nil {
+			continue
+		}
+
+		workload, err := c.watcher.GetWorkload(kind, namespace, name)
+		if err != nil {
+			c.logger.Debug("Workload not found in cache, skipping idle check",
+				zap.String("workload", workloadKey),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		c.syncWorkloadActivity(workload, routeInfo.RouteID)
+	}
+}
+
+// syncWorkloadActivity 写回工作负载的最近活动时间，并在空闲超时后缩容到 0
+// 所有工作负载都写回最近活动时间；空闲超时只决定是否缩容
+func (c *routerController) syncWorkloadActivity(workload k8s.Workload, routeID string) {
+	workloadKey := k8s.WorkloadKey(workload)
+	idleTimeout, err := k8s.GetIdleTimeout(workload.GetAnnotations())
+	if err != nil {
+		c.logger.Warn("Invalid idle timeout annotation",
+			zap.String("workload", workloadKey),
+			zap.Error(err),
+		)
+	}
+
+	now := time.Now()
+	annotated, hasAnnotation := k8s.GetLastActivity(workload.GetAnnotations())
+	observed, hasObserved := routeActivity.LastActivity(routeID)
+
+	// 1. 计算最近活动时间（注解精度为秒）
+	last := annotated
+	switch {
+	case hasObserved && observed.Truncate(time.Second).After(annotated):
+		last = observed
+	case !hasObserved && !hasAnnotation:
+		// 从未观察到请求，以工作负载变为可用的时间作为起点
+		last = availableSince(workload)
+	}
+
+	// 2. 写回最近活动时间：只在变为空闲或注解即将过期时写入，避免每个周期都更新工作负载
+	if hasObserved && router.LastActivityDue(now, annotated, observed, idleTimeout, c.config().GetIdleCheckPeriodDuration()) {
+		annotations := map[string]string{
+			k8s.AnnotationLastActivity: observed.UTC().Format(time.RFC3339),
+		}
+
+		ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+		err := k8s.PatchWorkloadAnnotation(ctx, c.k8sClient, workload.Kind(), workload.GetNamespace(), workload.GetName(), annotations)
+		cancel()
+		if err != nil {
+			c.logger.Warn("Failed to patch last activity annotation",
+				zap.String("workload", workloadKey),
+				zap.Error(err),
+			)
+		}
+	}
+
+	// 3. 空闲缩容（未设置空闲超时或独立 Pod 不缩容）
+	if idleTimeout == 0 || workload.Kind() == k8s.KindPod || workload.DesiredReplicas() != 1 || last.IsZero() {
+		return
+	}
+	if routeActivity.Active(routeID) > 0 {
+		return
+	}
+
+	idle := now.Sub(last)
+	if idle < idleTimeout {
+		return
+	}
+
+	c.logger.Info("Workload idle, scaling to zero",
+		zap.String("workload", workloadKey),
+		zap.String("route_id", routeID),
+		zap.Duration("idle", idle),
+		zap.Duration("idle_timeout", idleTimeout),
+	)
+
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := k8s.ScaleWorkload(ctx, c.k8sClient, workload.Kind(), workload.GetNamespace(), workload.GetName(), 0); err != nil {
+		c.logger.Warn("Failed to scale idle workload",
+			zap.String("workload", workloadKey),
+			zap.Error(err),
+		)
+		return
+	}
+	routeActivity.Forget(routeID)
+}
+
+// availableSince 返回工作负载最近一次变为可用的时间，未知时返回零值
+// Deployment 取 Available 条件的变化时间；StatefulSet 没有该条件，取路由同步时间
+func availableSince(workload k8s.Workload) time.Time {
+	if w, ok := workload.(k8s.DeploymentWorkload); ok {
+		for _, cond := range w.Status.Conditions {
+			if cond.Type == appsv1.DeploymentAvailable && cond.Status == corev1.ConditionTrue {
+				return cond.LastTransitionTime.Time
+			}
+		}
+		return time.Time{}
+	}
+
+	synced, err := time.Parse(time.RFC3339, workload.GetAnnotations()[k8s.AnnotationSynced])
+	if err != nil {
+		return time.Time{}
+	}
+	return synced
+}
@@ -0,0 +1,220 @@
+package caddy2k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// eventReasonRuleIgnored Ingress 或 HTTPRoute 规则被忽略（如占用 gitspace 保留域名）时记录的事件原因
+const eventReasonRuleIgnored = "GitspaceRuleIgnored"
+
+// ingressRouteID 返回 Ingress 对应的 Caddy 路由 ID
+func ingressRouteID(ingress *networkingv1.Ingress) string {
+	return fmt.Sprintf("ingress:%s:%s", ingress.Namespace, ingress.Name)
+}
+
+// ingressKey 返回 Ingress 在 Tracker 和锁中使用的键
+func ingressKey(ingress *networkingv1.Ingress) string {
+	return k8s.BuildWorkloadKey(k8s.KindIngress, ingress.Namespace, ingress.Name)
+}
+
+// reservedHostChecker 返回检查 Ingress、HTTPRoute 域名的函数
+// 路由插入在最前面，占用 <identifier>.<base_domain>、端口转发域名或 *.<base_domain> 的规则
+// 会接管 gitspace 流量并绕过 gitspace_access，因此拒绝
+func (h *EventHandler) reservedHostChecker() func(string) error {
+	return k8s.ReservedHostChecker(h.baseDomain, h.identifierInUse)
+}
+
+// OnIngressAdd 处理 Ingress 创建事件
+func (h *EventHandler) OnIngressAdd(ingress *networkingv1.Ingress) error {
+	if !h.active() {
+		return nil
+	}
+
+	lock := h.getWorkloadLock(ingressKey(ingress))
+	lock.Lock()
+	defer lock.Unlock()
+
+	return h.syncIngress(ingress)
+}
+
+// OnIngressUpdate 处理 Ingress 更新事件
+func (h *EventHandler) OnIngressUpdate(oldIngress, newIngress *networkingv1.Ingress) error {
+	if !h.active() {
+		return nil
+	}
+
+	// spec 未变化的更新（通常是本插件写回的 status）不需要重新同步；resync 事件除外
+	if oldIngress.Generation == newIngress.Generation && oldIngress.ResourceVersion != newIngress.ResourceVersion {
+		return nil
+	}
+
+	lock := h.getWorkloadLock(ingressKey(newIngress))
+	lock.Lock()
+	defer lock.Unlock()
+
+	return h.syncIngress(newIngress)
+}
+
+// OnIngressDelete 处理 Ingress 删除事件
+func (h *EventHandler) OnIngressDelete(ingress *networkingv1.Ingress) error {
+	if !h.active() {
+		return nil
+	}
+
+	key := ingressKey(ingress)
+	lock := h.getWorkloadLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	defer h.workloadLocks.Delete(key)
+
+	return h.removeTrackedRoute(key)
+}
+
+// syncIngress 将 Ingress 规则转换为一条 Caddy 路由并写回负载均衡地址（调用方需持有锁）
+// 外层路由匹配所有 host，内部通过 subroute 按 host + path 分发到各 Service
+func (h *EventHandler) syncIngress(ingress *networkingv1.Ingress) error {
+	key := ingressKey(ingress)
+	routeID := ingressRouteID(ingress)
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	hosts, paths, ignored := k8s.TranslateIngress(ctx, h.k8sClient, ingress, h.reservedHostChecker())
+	for _, err := range ignored {
+		h.logger.Warn("Ignoring Ingress rule",
+			zap.String("ingress", key),
+			zap.Error(err),
+		)
+		if h.recorder != nil {
+			h.recorder.Event(ingress, corev1.EventTypeWarning, eventReasonRuleIgnored, err.Error())
+		}
+	}
+	if len(paths) == 0 {
+		h.logger.Debug("Ingress has no routable rules",
+			zap.String("ingress", key),
+		)
+		return h.removeTrackedRoute(key)
+	}
+
+	routes := make([]map[string]any, 0, len(paths))
+	for _, p := range paths {
+		match := map[string]any{"host": []string{p.Host}}
+		if len(p.Paths) > 0 {
+			match["path"] = p.Paths
+		}
+		routes = append(routes, map[string]any{
+			"match":  []map[string]any{match},
+			"handle": []map[string]any{h.reverseProxyHandlerConfig([]string{p.Upstream}, nil)},
+		})
+	}
+
+	spec := router.RouteSpec{
+		ID:           routeID,
+		Domain:       hosts[0],
+		AliasDomains: hosts[1:],
+		Handlers:     []map[string]any{subrouteHandlerConfig(routes)},
+	}
+	if err := h.adminClient.ApplyRoute(ctx, spec); err != nil {
+		h.logger.Error("Failed to program Ingress",
+			zap.String("ingress", key),
+			zap.String("route_id", routeID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if _, exists := h.tracker.Get(key); !exists {
+		h.logger.Info("Ingress programmed",
+			zap.String("ingress", key),
+			zap.Strings("hosts", hosts),
+			zap.Int("paths", len(paths)),
+		)
+	}
+	h.tracker.Set(key, routeID, paths[0].Upstream)
+
+	// 写回负载均衡地址
+	if address := h.settings().ingressStatusAddress; address != "" {
+		if err := k8s.PatchIngressLoadBalancer(ctx, h.k8sClient, ingress, address); err != nil {
+			h.logger.Warn("Failed to update Ingress status",
+				zap.String("ingress", key),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// removeTrackedRoute 删除 Tracker 中记录的路由（用于 Ingress、HTTPRoute）
+func (h *EventHandler) removeTrackedRoute(key string) error {
+	routeInfo, exists := h.tracker.Get(key)
+	if !exists || routeInfo == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	if err := h.adminClient.DeleteRoute(ctx, routeInfo.RouteID); err != nil {
+		h.logger.Error("Failed to delete route",
+			zap.String("source", key),
+			zap.String("route_id", routeInfo.RouteID),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	h.tracker.Delete(key)
+
+	h.logger.Info("Route deleted",
+		zap.String("source", key),
+		zap.String("route_id", routeInfo.RouteID),
+	)
+	return nil
+}
+
+// reverseProxyHandlerConfig 构造代理到一组上游的 reverse_proxy 处理器
+// 提供 weights 且有多个上游时使用加权轮询
+func (h *EventHandler) reverseProxyHandlerConfig(upstreams []string, weights []int) map[string]any {
+	dials := make([]map[string]string, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		dials = append(dials, map[string]string{"dial": upstream})
+	}
+
+	proxy := map[string]any{
+		"handler":   "reverse_proxy",
+		"upstreams": dials,
+	}
+	if len(upstreams) > 1 && len(weights) == len(upstreams) {
+		proxy["load_balancing"] = map[string]any{
+			"selection_policy": map[string]any{
+				"policy":  "weighted_round_robin",
+				"weights": weights,
+			},
+		}
+	}
+	if h.settings().drainPeriod > 0 {
+		proxy["stream_close_delay"] = h.settings().drainPeriod.String()
+	}
+	return proxy
+}
+
+// subrouteHandlerConfig 构造 subroute 处理器
+func subrouteHandlerConfig(routes []map[string]any) map[string]any {
+	return map[string]any{
+		"handler": "subroute",
+		"routes":  routes,
+	}
+}
+
+// Interface guard
+var _ k8s.IngressHandler = (*EventHandler)(nil)
@@ -6,28 +6,79 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 )
 
+// EventComponent 写入 Kubernetes Event 时使用的组件名称
+const EventComponent = "caddy-gitspace"
+
+// FieldManager Server-Side Apply 写回路由注解时使用的字段管理者
+const FieldManager = "caddy-gitspace"
+
+// HealthFieldManager Server-Side Apply 写回上游健康状态注解时使用的字段管理者
+// 与 FieldManager 分开，写入健康状态时不会删除路由注解
+const HealthFieldManager = "caddy-gitspace-health"
+
 // NewKubernetesClient 创建 Kubernetes clientset
 // 优先使用集群内配置，如果失败则尝试 kubeconfigPath
 func NewKubernetesClient(kubeconfigPath string) (*kubernetes.Clientset, error) {
-	var config *rest.Config
-	var err error
+	config, err := NewRESTConfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	return clientset, nil
+}
+
+// NewDynamicClient 创建 dynamic client，用于访问 GitspaceRoute 等自定义资源
+func NewDynamicClient(kubeconfigPath string) (dynamic.Interface, error) {
+	config, err := NewRESTConfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
 
+	return client, nil
+}
+
+// NewEventRecorder 创建写入 Kubernetes Event 的记录器
+// 调用方负责在停止时调用 broadcaster.Shutdown()
+func NewEventRecorder(client kubernetes.Interface) (record.EventBroadcaster, record.EventRecorder) {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: client.CoreV1().Events(""),
+	})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: EventComponent})
+	return broadcaster, recorder
+}
+
+// NewRESTConfig 加载 Kubernetes 客户端配置
+// 优先使用集群内配置，如果失败则尝试 kubeconfigPath
+func NewRESTConfig(kubeconfigPath string) (*rest.Config, error) {
 	// 尝试集群内配置
-	config, err = rest.InClusterConfig()
+	config, err := rest.InClusterConfig()
 	if err == nil {
-		clientset, err := kubernetes.NewForConfig(config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create clientset: %w", err)
-		}
-		return clientset, nil
+		return config, nil
 	}
 
 	// 集群内配置失败，尝试 kubeconfig
@@ -47,20 +98,15 @@ func NewKubernetesClient(kubeconfigPath string) (*kubernetes.Clientset, error) {
 		return nil, fmt.Errorf("failed to load kubeconfig from %s: %w", kubeconfigPath, err)
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create clientset: %w", err)
-	}
-
-	return clientset, nil
+	return config, nil
 }
 
-// PatchDeploymentAnnotation 更新 Deployment 的注解
+// PatchWorkloadAnnotation 更新工作负载的注解
 // 使用 Strategic Merge Patch 确保只更新指定的注解
-func PatchDeploymentAnnotation(
+func PatchWorkloadAnnotation(
 	ctx context.Context,
 	client kubernetes.Interface,
-	namespace, name string,
+	kind, namespace, name string,
 	annotations map[string]string,
 ) error {
 	// 构造 patch 数据
@@ -76,16 +122,127 @@ func PatchDeploymentAnnotation(
 	}
 
 	// 应用 patch
-	_, err = client.AppsV1().Deployments(namespace).Patch(
-		ctx,
-		name,
-		types.StrategicMergePatchType,
-		patchBytes,
-		metav1.PatchOptions{},
-	)
+	if err := patchWorkload(ctx, client, kind, namespace, name, types.StrategicMergePatchType, patchBytes, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch %s %s/%s: %w", kind, namespace, name, err)
+	}
+
+	return nil
+}
+
+// ApplyWorkloadAnnotations 使用 Server-Side Apply 写入 FieldManager 管理的注解
+// annotations 是该管理者拥有的完整注解集合：上次写入、本次省略的注解会被删除，
+// 内容未变化时 API Server 不会更新对象，也不会产生新的 update 事件
+func ApplyWorkloadAnnotations(
+	ctx context.Context,
+	client kubernetes.Interface,
+	kind, namespace, name string,
+	annotations map[string]string,
+) error {
+	return applyWorkloadAnnotations(ctx, client, FieldManager, kind, namespace, name, annotations)
+}
+
+// RouteAnnotations 构造 FieldManager 写回的路由注解集合，syncedAt 记录本次变化的时间
+func RouteAnnotations(domain, routeID, targetAddr, routeStatus string, syncedAt time.Time) map[string]string {
+	return map[string]string{
+		AnnotationURL:         domain,
+		AnnotationSynced:      syncedAt.Format(time.RFC3339),
+		AnnotationRouteID:     routeID,
+		AnnotationTarget:      targetAddr,
+		AnnotationRouteStatus: routeStatus,
+	}
+}
+
+// RouteAnnotationsChanged 判断工作负载上已写回的路由注解是否与当前路由不一致
+// 只有域名、路由 ID、上游地址或状态变化（或从未写回）时才需要重新写入
+func RouteAnnotationsChanged(annotations map[string]string, domain, routeID, targetAddr, routeStatus string) bool {
+	return annotations[AnnotationURL] != domain ||
+		annotations[AnnotationRouteID] != routeID ||
+		annotations[AnnotationTarget] != targetAddr ||
+		annotations[AnnotationRouteStatus] != routeStatus ||
+		annotations[AnnotationSynced] == ""
+}
+
+// applyWorkloadAnnotations 以指定字段管理者通过 Server-Side Apply 写入注解
+func applyWorkloadAnnotations(
+	ctx context.Context,
+	client kubernetes.Interface,
+	manager, kind, namespace, name string,
+	annotations map[string]string,
+) error {
+	apiVersion := "apps/v1"
+	if kind == KindPod {
+		apiVersion = "v1"
+	}
+
+	applyBytes, err := json.Marshal(map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]any{
+			"name":        name,
+			"namespace":   namespace,
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal apply data: %w", err)
+	}
+
+	// 强制接管此前由 Strategic Merge Patch 写入的同名注解
+	force := true
+	opts := metav1.PatchOptions{FieldManager: manager, Force: &force}
+	if err := patchWorkload(ctx, client, kind, namespace, name, types.ApplyPatchType, applyBytes, opts); err != nil {
+		return fmt.Errorf("failed to apply annotations to %s %s/%s: %w", kind, namespace, name, err)
+	}
+	return nil
+}
+
+// ScaleWorkload 修改工作负载的副本数
+// 使用 Merge Patch 只更新 spec.replicas
+func ScaleWorkload(
+	ctx context.Context,
+	client kubernetes.Interface,
+	kind, namespace, name string,
+	replicas int32,
+) error {
+	if kind == KindPod {
+		return fmt.Errorf("pod %s/%s cannot be scaled", namespace, name)
+	}
+
+	patchBytes, err := json.Marshal(map[string]any{
+		"spec": map[string]any{
+			"replicas": replicas,
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to patch deployment %s/%s: %w", namespace, name, err)
+		return fmt.Errorf("failed to marshal patch data: %w", err)
+	}
+
+	if err := patchWorkload(ctx, client, kind, namespace, name, types.MergePatchType, patchBytes, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to scale %s %s/%s: %w", kind, namespace, name, err)
 	}
 
 	return nil
 }
+
+// patchWorkload 按工作负载类型调用对应资源的 Patch 接口
+func patchWorkload(
+	ctx context.Context,
+	client kubernetes.Interface,
+	kind, namespace, name string,
+	patchType types.PatchType,
+	data []byte,
+	opts metav1.PatchOptions,
+) error {
+	var err error
+	switch kind {
+	case KindDeployment:
+		_, err = client.AppsV1().Deployments(namespace).Patch(ctx, name, patchType, data, opts)
+	case KindStatefulSet:
+		_, err = client.AppsV1().StatefulSets(namespace).Patch(ctx, name, patchType, data, opts)
+	case KindPod:
+		_, err = client.CoreV1().Pods(namespace).Patch(ctx, name, patchType, data, opts)
+	default:
+		err = fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package k8s
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestRouteAnnotationsChanged 测试只有路由实际变化时才需要写回注解
+func TestRouteAnnotationsChanged(t *testing.T) {
+	synced := RouteAnnotations("ws.example.com", "gitspace-ws", "10.0.0.1:8080", RouteStatusReady, time.Now())
+	with := func(key, value string) map[string]string {
+		annotations := maps.Clone(synced)
+		annotations[key] = value
+		return annotations
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        bool
+	}{
+		{"从未写回", nil, true},
+		{"路由未变化", synced, false},
+		{"只有时间戳不同", with(AnnotationSynced, "2024-01-01T00:00:00Z"), false},
+		{"其他注解变化", with(AnnotationUpstreamHealth, "Unhealthy"), false},
+		{"缺少时间戳", with(AnnotationSynced, ""), true},
+		{"域名变化", with(AnnotationURL, "old.example.com"), true},
+		{"路由 ID 变化", with(AnnotationRouteID, "gitspace-old"), true},
+		{"上游地址变化", with(AnnotationTarget, "10.0.0.2:8080"), true},
+		{"状态变化", with(AnnotationRouteStatus, RouteStatusDegraded), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RouteAnnotationsChanged(tt.annotations, "ws.example.com", "gitspace-ws", "10.0.0.1:8080", RouteStatusReady)
+			if got != tt.want {
+				t.Errorf("RouteAnnotationsChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyWorkloadAnnotations 测试 FieldManager 写回的注解：省略的键被删除，其他管理者和用户的注解保留
+func TestApplyWorkloadAnnotations(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vscode",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationPort: "8080"},
+		},
+	})
+
+	get := func() map[string]string {
+		t.Helper()
+		deployment, err := client.AppsV1().Deployments("default").Get(ctx, "vscode", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deployment.GetAnnotations()
+	}
+
+	syncedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	route := RouteAnnotations("ws.example.com", "gitspace-ws", "10.0.0.1:8080", RouteStatusReady, syncedAt)
+	if err := ApplyWorkloadAnnotations(ctx, client, KindDeployment, "default", "vscode", route); err != nil {
+		t.Fatalf("ApplyWorkloadAnnotations() error = %v", err)
+	}
+	if err := ApplyUpstreamHealthAnnotation(ctx, client, KindDeployment, "default", "vscode", "Healthy"); err != nil {
+		t.Fatalf("ApplyUpstreamHealthAnnotation() error = %v", err)
+	}
+
+	// 写回后的注解与当前路由一致，下次同步不再写入
+	annotations := get()
+	if RouteAnnotationsChanged(annotations, "ws.example.com", "gitspace-ws", "10.0.0.1:8080", RouteStatusReady) {
+		t.Errorf("Expected no change after write-back, got %v", annotations)
+	}
+	if !RouteAnnotationsChanged(annotations, "ws.example.com", "gitspace-ws", "10.0.0.2:8080", RouteStatusReady) {
+		t.Error("Expected target change to be detected")
+	}
+
+	// 路由失败时只保留状态和原因，域名、路由 ID 等注解被删除
+	failed := map[string]string{
+		AnnotationRouteStatus:  RouteStatusFailed,
+		AnnotationRouteMessage: "duplicate",
+	}
+	if err := ApplyWorkloadAnnotations(ctx, client, KindDeployment, "default", "vscode", failed); err != nil {
+		t.Fatalf("ApplyWorkloadAnnotations() error = %v", err)
+	}
+
+	annotations = get()
+	for _, key := range []string{AnnotationURL, AnnotationSynced, AnnotationRouteID, AnnotationTarget} {
+		if _, exists := annotations[key]; exists {
+			t.Errorf("Expected %s to be removed, got %v", key, annotations)
+		}
+	}
+	if annotations[AnnotationRouteStatus] != RouteStatusFailed || annotations[AnnotationRouteMessage] != "duplicate" {
+		t.Errorf("Unexpected route status annotations: %v", annotations)
+	}
+	if annotations[AnnotationUpstreamHealth] != "Healthy" {
+		t.Errorf("Expected health annotation to be preserved, got %v", annotations)
+	}
+	if annotations[AnnotationPort] != "8080" {
+		t.Errorf("Expected user annotation to be preserved, got %v", annotations)
+	}
+}
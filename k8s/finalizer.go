@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// 删除路由失败后重试 finalize 的退避时间
+const (
+	finalizeRetryInitialDelay = 2 * time.Second
+	finalizeRetryMaxDelay     = 30 * time.Second
+)
+
+// NeedsFinalizer 判断是否需要为工作负载添加路由清理 finalizer
+// 未开启 use_finalizers、工作负载删除中或已带有 finalizer 时不需要
+func NeedsFinalizer(obj metav1.Object, enabled bool) bool {
+	return enabled && obj.GetDeletionTimestamp() == nil && !HasFinalizer(obj, FinalizerRouteCleanup)
+}
+
+// IsFinalizing 判断工作负载是否正在删除且等待路由清理 finalizer 移除
+func IsFinalizing(obj metav1.Object) bool {
+	return obj.GetDeletionTimestamp() != nil && HasFinalizer(obj, FinalizerRouteCleanup)
+}
+
+// FinalizeRetryDelay 返回下一次重试 finalize 的退避时间
+// previous 为上一次的退避时间（首次重试时为 0），每次翻倍，最长 30 秒
+func FinalizeRetryDelay(previous time.Duration) time.Duration {
+	if previous <= 0 {
+		return finalizeRetryInitialDelay
+	}
+	return min(previous*2, finalizeRetryMaxDelay)
+}
+
+// FinalizerTimedOut 判断删除中的工作负载是否已超过 finalizer 超时，此时应强制移除 finalizer
+func FinalizerTimedOut(obj metav1.Object, now time.Time, timeout time.Duration) bool {
+	deletedAt := obj.GetDeletionTimestamp()
+	return deletedAt != nil && now.Sub(deletedAt.Time) >= timeout
+}
+
+// HasFinalizer 判断对象是否带有指定的 finalizer
+func HasFinalizer(obj metav1.Object, finalizer string) bool {
+	return slices.Contains(obj.GetFinalizers(), finalizer)
+}
+
+// AddWorkloadFinalizer 为工作负载添加 finalizer
+// 使用 Strategic Merge Patch，不影响其他控制器的 finalizer
+func AddWorkloadFinalizer(
+	ctx context.Context,
+	client kubernetes.Interface,
+	kind, namespace, name, finalizer string,
+) error {
+	patchBytes, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"finalizers": []string{finalizer},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch data: %w", err)
+	}
+
+	if err := patchWorkload(ctx, client, kind, namespace, name, types.StrategicMergePatchType, patchBytes, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to add finalizer to %s %s/%s: %w", kind, namespace, name, err)
+	}
+	return nil
+}
+
+// RemoveWorkloadFinalizer 移除工作负载的 finalizer，对象已不存在时不报错
+func RemoveWorkloadFinalizer(
+	ctx context.Context,
+	client kubernetes.Interface,
+	kind, namespace, name, finalizer string,
+) error {
+	patchBytes, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"$deleteFromPrimitiveList/finalizers": []string{finalizer},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch data: %w", err)
+	}
+
+	err = patchWorkload(ctx, client, kind, namespace, name, types.StrategicMergePatchType, patchBytes, metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove finalizer from %s %s/%s: %w", kind, namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// recordingHandler 记录收到的工作负载创建事件
+type recordingHandler struct {
+	added []string
+}
+
+func (h *recordingHandler) OnWorkloadAdd(workload Workload) error {
+	h.added = append(h.added, WorkloadKey(workload))
+	return nil
+}
+
+func (h *recordingHandler) OnWorkloadUpdate(_, _ Workload) error { return nil }
+
+func (h *recordingHandler) OnWorkloadDelete(Workload) error { return nil }
+
+func (h *recordingHandler) OnGatedPodUpdate(*corev1.Pod) error { return nil }
+
+// newFinalizerDeployment 创建测试用的 Deployment
+func newFinalizerDeployment(replicas int32, deleting bool, annotations map[string]string, finalizers ...string) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vscode",
+			Namespace:   "default",
+			Annotations: annotations,
+			Finalizers:  finalizers,
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	if deleting {
+		now := metav1.Now()
+		deployment.DeletionTimestamp = &now
+	}
+	return deployment
+}
+
+// TestHandleWorkloadAdd 测试创建事件的过滤：删除中且等待路由清理的工作负载不论副本数都会交给 EventHandler
+func TestHandleWorkloadAdd(t *testing.T) {
+	autowake := map[string]string{AnnotationAutowake: "true"}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		want       bool
+	}{
+		{"单副本", newFinalizerDeployment(1, false, nil), true},
+		{"多副本", newFinalizerDeployment(3, false, nil), false},
+		{"0 副本未开启自动唤醒", newFinalizerDeployment(0, false, nil), false},
+		{"0 副本开启自动唤醒", newFinalizerDeployment(0, false, autowake), true},
+		{"删除中的多副本带路由清理 finalizer", newFinalizerDeployment(3, true, nil, FinalizerRouteCleanup), true},
+		{"删除中的 0 副本带路由清理 finalizer", newFinalizerDeployment(0, true, nil, FinalizerRouteCleanup), true},
+		{"删除中的多副本只有其他 finalizer", newFinalizerDeployment(3, true, nil, "example.com/other"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &recordingHandler{}
+			watcher := &Watcher{eventHandler: handler}
+
+			watcher.handleWorkloadAdd(tt.deployment)
+
+			if got := len(handler.added) == 1; got != tt.want {
+				t.Errorf("handleWorkloadAdd() dispatched = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNeedsFinalizer 测试是否需要添加路由清理 finalizer
+func TestNeedsFinalizer(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		enabled    bool
+		want       bool
+	}{
+		{"开启且没有 finalizer", newFinalizerDeployment(1, false, nil), true, true},
+		{"未开启 use_finalizers", newFinalizerDeployment(1, false, nil), false, false},
+		{"已带有 finalizer", newFinalizerDeployment(1, false, nil, FinalizerRouteCleanup), true, false},
+		{"只有其他 finalizer", newFinalizerDeployment(1, false, nil, "example.com/other"), true, true},
+		{"删除中", newFinalizerDeployment(1, true, nil, "example.com/other"), true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsFinalizer(tt.deployment, tt.enabled); got != tt.want {
+				t.Errorf("NeedsFinalizer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFinalizeRetryDelay 测试 finalize 重试的指数退避
+func TestFinalizeRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous time.Duration
+		want     time.Duration
+	}{
+		{"首次重试", 0, 2 * time.Second},
+		{"翻倍", 2 * time.Second, 4 * time.Second},
+		{"继续翻倍", 8 * time.Second, 16 * time.Second},
+		{"不超过上限", 16 * time.Second, 30 * time.Second},
+		{"保持上限", 30 * time.Second, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FinalizeRetryDelay(tt.previous); got != tt.want {
+				t.Errorf("FinalizeRetryDelay(%s) = %s, want %s", tt.previous, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFinalizerTimedOut 测试超过 finalizer_timeout 后强制移除 finalizer
+func TestFinalizerTimedOut(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deleting := newFinalizerDeployment(1, true, nil, FinalizerRouteCleanup)
+	deleting.DeletionTimestamp = &metav1.Time{Time: deletedAt}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		now        time.Time
+		want       bool
+	}{
+		{"未超时", deleting, deletedAt.Add(4 * time.Minute), false},
+		{"刚好超时", deleting, deletedAt.Add(5 * time.Minute), true},
+		{"已超时", deleting, deletedAt.Add(time.Hour), true},
+		{"未删除", newFinalizerDeployment(1, false, nil), deletedAt.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FinalizerTimedOut(tt.deployment, tt.now, 5*time.Minute); got != tt.want {
+				t.Errorf("FinalizerTimedOut() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWorkloadFinalizerPatch 测试添加和移除 finalizer 不影响其他控制器的 finalizer
+func TestWorkloadFinalizerPatch(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset(newFinalizerDeployment(1, false, nil, "example.com/other"))
+
+	finalizers := func() []string {
+		t.Helper()
+		deployment, err := client.AppsV1().Deployments("default").Get(ctx, "vscode", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get deployment: %v", err)
+		}
+		// Strategic Merge Patch 不保证合并后的顺序
+		return slices.Sorted(slices.Values(deployment.Finalizers))
+	}
+
+	if err := AddWorkloadFinalizer(ctx, client, KindDeployment, "default", "vscode", FinalizerRouteCleanup); err != nil {
+		t.Fatalf("AddWorkloadFinalizer() error = %v", err)
+	}
+	if got, want := finalizers(), []string{"example.com/other", FinalizerRouteCleanup}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Finalizers after add = %v, want %v", got, want)
+	}
+
+	if err := RemoveWorkloadFinalizer(ctx, client, KindDeployment, "default", "vscode", FinalizerRouteCleanup); err != nil {
+		t.Fatalf("RemoveWorkloadFinalizer() error = %v", err)
+	}
+	if got, want := finalizers(), []string{"example.com/other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Finalizers after remove = %v, want %v", got, want)
+	}
+
+	// 工作负载已删除时移除 finalizer 不报错
+	if err := RemoveWorkloadFinalizer(ctx, client, KindDeployment, "default", "missing", FinalizerRouteCleanup); err != nil {
+		t.Errorf("RemoveWorkloadFinalizer() on missing workload error = %v", err)
+	}
+}
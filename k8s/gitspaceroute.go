@@ -0,0 +1,264 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// KindGitspaceRoute GitspaceRoute 资源类型
+const KindGitspaceRoute = "GitspaceRoute"
+
+// GitspaceRouteGVR GitspaceRoute 自定义资源的 GroupVersionResource
+var GitspaceRouteGVR = schema.GroupVersionResource{
+	Group:    "gitspace.app.io",
+	Version:  "v1alpha1",
+	Resource: "gitspaceroutes",
+}
+
+// GitspaceRoute 声明式路由：将域名（和路径前缀）代理到工作负载、Service 或静态上游
+type GitspaceRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GitspaceRouteSpec   `json:"spec"`
+	Status GitspaceRouteStatus `json:"status,omitempty"`
+}
+
+// GitspaceRouteSpec 期望的路由配置
+// WorkloadRef、Service、Upstream 三者必须且只能设置一个
+type GitspaceRouteSpec struct {
+	// Hosts 匹配的域名，为空时使用 <metadata.name>.<base_domain>
+	Hosts []string `json:"hosts,omitempty"`
+
+	// PathPrefix 只匹配该路径前缀，为空时匹配所有路径
+	PathPrefix string `json:"pathPrefix,omitempty"`
+
+	// WorkloadRef 代理到同命名空间工作负载的就绪 Pod
+	WorkloadRef *WorkloadReference `json:"workloadRef,omitempty"`
+
+	// Service 代理到同命名空间的 Service（通过集群 DNS 解析）
+	Service *ServiceReference `json:"service,omitempty"`
+
+	// Upstream 代理到静态上游地址（host:port）
+	Upstream string `json:"upstream,omitempty"`
+
+	// Port WorkloadRef 的目标端口，为空时使用工作负载端口注解或 default_port
+	Port int32 `json:"port,omitempty"`
+
+	// Auth 访问认证，为空时不认证
+	Auth *RouteAuth `json:"auth,omitempty"`
+}
+
+// WorkloadReference 引用同命名空间的工作负载
+type WorkloadReference struct {
+	// Kind Deployment、StatefulSet 或 Pod，默认 Deployment
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name"`
+}
+
+// ServiceReference 引用同命名空间的 Service 端口
+type ServiceReference struct {
+	Name string `json:"name"`
+	Port int32  `json:"port"`
+}
+
+// RouteAuth 路由访问认证
+type RouteAuth struct {
+	Basic *BasicAuth `json:"basic,omitempty"`
+}
+
+// BasicAuth HTTP Basic 认证
+type BasicAuth struct {
+	// Realm 认证域，默认 restricted
+	Realm string `json:"realm,omitempty"`
+
+	Users []BasicAuthUser `json:"users"`
+}
+
+// BasicAuthUser Basic 认证用户，密码为 bcrypt 哈希（caddy hash-password 生成）
+type BasicAuthUser struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"passwordHash"`
+}
+
+// GitspaceRouteStatus 已观察到的路由状态
+type GitspaceRouteStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	RouteID            string             `json:"routeID,omitempty"`
+	URL                string             `json:"url,omitempty"`
+	Target             string             `json:"target,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// GitspaceRoute 条件类型和原因
+const (
+	// ConditionReady 路由已写入 Caddy
+	ConditionReady = "Ready"
+
+	ReasonRouteProgrammed = "RouteProgrammed"
+	ReasonInvalidSpec     = "InvalidSpec"
+	ReasonBackendNotReady = "BackendNotReady"
+	ReasonProgramFailed   = "ProgramFailed"
+)
+
+// Validate 检查 spec 是否合法
+func (s *GitspaceRouteSpec) Validate() error {
+	backends := 0
+	if s.WorkloadRef != nil {
+		backends++
+		if s.WorkloadRef.Name == "" {
+			return fmt.Errorf("workloadRef.name is required")
+		}
+		switch s.WorkloadRef.Kind {
+		case "", KindDeployment, KindStatefulSet, KindPod:
+		default:
+			return fmt.Errorf("unsupported workloadRef.kind: %s", s.WorkloadRef.Kind)
+		}
+	}
+	if s.Service != nil {
+		backends++
+		if s.Service.Name == "" {
+			return fmt.Errorf("service.name is required")
+		}
+		if s.Service.Port < 1 || s.Service.Port > 65535 {
+			return fmt.Errorf("service.port out of range (1-65535): %d", s.Service.Port)
+		}
+	}
+	if s.Upstream != "" {
+		backends++
+	}
+	if backends != 1 {
+		return fmt.Errorf("exactly one of workloadRef, service or upstream must be set")
+	}
+
+	if s.Port != 0 && (s.Port < 1 || s.Port > 65535) {
+		return fmt.Errorf("port out of range (1-65535): %d", s.Port)
+	}
+	for _, host := range s.Hosts {
+		if host == "" {
+			return fmt.Errorf("hosts must not contain empty entries")
+		}
+	}
+	if s.PathPrefix != "" && s.PathPrefix[0] != '/' {
+		return fmt.Errorf("pathPrefix must start with /: %s", s.PathPrefix)
+	}
+	if s.Auth != nil && s.Auth.Basic != nil {
+		if len(s.Auth.Basic.Users) == 0 {
+			return fmt.Errorf("auth.basic.users must not be empty")
+		}
+		for _, user := range s.Auth.Basic.Users {
+			if user.Username == "" || user.PasswordHash == "" {
+				return fmt.Errorf("auth.basic.users require username and passwordHash")
+			}
+		}
+	}
+
+	return nil
+}
+
+// portForwardLabel 匹配端口转发域名的第一段：<port>-<identifier>
+var portForwardLabel = regexp.MustCompile(`^[0-9]{1,5}-.+$`)
+
+// CheckGitspaceRouteHost 检查 GitspaceRoute 域名是否占用了 gitspace 保留的域名
+// <identifier>.<base_domain> 属于工作负载，<port>-<identifier>.<base_domain> 属于端口转发，
+// 两者都不允许由 GitspaceRoute 声明；isIdentifier 判断第一段是否为现有工作负载的 identifier。
+// base_domain 下带通配符的域名（如 *.<base_domain>）会覆盖所有 gitspace，同样不允许
+func CheckGitspaceRouteHost(host, baseDomain string, isIdentifier func(string) bool) error {
+	label, found := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if !found || label == "" {
+		return nil
+	}
+	if strings.Contains(label, "*") {
+		return fmt.Errorf("host %s is a wildcard under base domain %s", host, baseDomain)
+	}
+	if strings.Contains(label, ".") {
+		return nil
+	}
+	if portForwardLabel.MatchString(label) {
+		return fmt.Errorf("host %s is reserved for port forwarding", host)
+	}
+	if isIdentifier != nil && isIdentifier(label) {
+		return fmt.Errorf("host %s is reserved for gitspace %s", host, label)
+	}
+	return nil
+}
+
+// ReservedHostChecker 返回检查域名是否占用 gitspace 保留域名的函数
+// 供 Ingress、HTTPRoute 等非 gitspace 路由使用，规则与 CheckGitspaceRouteHost 相同
+func ReservedHostChecker(baseDomain string, isIdentifier func(string) bool) func(string) error {
+	return func(host string) error {
+		return CheckGitspaceRouteHost(host, baseDomain, isIdentifier)
+	}
+}
+
+// GitspaceRouteFromUnstructured 将 dynamic informer 返回的对象转换为 GitspaceRoute
+func GitspaceRouteFromUnstructured(obj *unstructured.Unstructured) (*GitspaceRoute, error) {
+	route := &GitspaceRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, route); err != nil {
+		return nil, fmt.Errorf("failed to convert GitspaceRoute %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return route, nil
+}
+
+// ListGitspaceRoutes 通过 API 列出命名空间下的所有 GitspaceRoute
+// CRD 未安装时返回空列表
+func ListGitspaceRoutes(ctx context.Context, client dynamic.Interface, namespace string) ([]*GitspaceRoute, error) {
+	list, err := client.Resource(GitspaceRouteGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gitspaceroutes: %w", err)
+	}
+
+	routes := make([]*GitspaceRoute, 0, len(list.Items))
+	for i := range list.Items {
+		route, err := GitspaceRouteFromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// PatchGitspaceRouteStatus 更新 GitspaceRoute 的 status 子资源
+// 使用 Merge Patch 整体替换 status
+func PatchGitspaceRouteStatus(
+	ctx context.Context,
+	client dynamic.Interface,
+	namespace, name string,
+	status GitspaceRouteStatus,
+) error {
+	patchBytes, err := json.Marshal(map[string]any{
+		"status": status,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch data: %w", err)
+	}
+
+	_, err = client.Resource(GitspaceRouteGVR).Namespace(namespace).Patch(
+		ctx,
+		name,
+		types.MergePatchType,
+		patchBytes,
+		metav1.PatchOptions{},
+		"status",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to patch gitspaceroute %s/%s status: %w", namespace, name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package k8s
+
+import "testing"
+
+// TestCheckGitspaceRouteHost 测试 GitspaceRoute 域名与 gitspace 保留域名的冲突检测
+func TestCheckGitspaceRouteHost(t *testing.T) {
+	identifiers := map[string]bool{"vscode": true}
+	isIdentifier := func(label string) bool { return identifiers[label] }
+
+	tests := []struct {
+		name    string
+		host    string
+		wantErr bool
+	}{
+		{"普通子域名", "docs.example.com", false},
+		{"gitspace 域名", "vscode.example.com", true},
+		{"大小写不敏感", "VSCode.Example.com", true},
+		{"端口转发域名", "8080-vscode.example.com", true},
+		{"端口转发格式但 identifier 不存在", "3000-other.example.com", true},
+		{"数字开头的普通名称", "2fa.example.com", false},
+		{"多级子域名", "api.vscode.example.com", false},
+		{"其他域名", "vscode.example.org", false},
+		{"基础域名本身", "example.com", false},
+		{"基础域名下的通配符", "*.example.com", true},
+		{"通配符大小写不敏感", "*.Example.COM", true},
+		{"多级通配符", "*.team.example.com", true},
+		{"部分通配符", "vs*.example.com", true},
+		{"其他域名的通配符", "*.example.org", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckGitspaceRouteHost(tt.host, "example.com", isIdentifier)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckGitspaceRouteHost(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+		})
+	}
+}
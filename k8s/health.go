@@ -0,0 +1,126 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// 健康检查注解，覆盖全局的 health_check_* 配置
+const (
+	// AnnotationHealthPath 主动健康检查的请求路径，设为 "off" 时关闭主动检查
+	AnnotationHealthPath = "gitspace.caddy.health.path"
+
+	// AnnotationHealthInterval 主动健康检查的间隔（Go duration 格式）
+	AnnotationHealthInterval = "gitspace.caddy.health.interval"
+
+	// AnnotationHealthStatus 主动健康检查期望的状态码（如 "200"，或 "2" 表示 2xx）
+	AnnotationHealthStatus = "gitspace.caddy.health.status"
+
+	// AnnotationHealthFailDuration 被动健康检查记住一次失败的时长，设为 "0" 时关闭被动检查
+	AnnotationHealthFailDuration = "gitspace.caddy.health.fail-duration"
+
+	// AnnotationHealthUnhealthyStatus 被动健康检查视为失败的响应状态码（逗号分隔）
+	AnnotationHealthUnhealthyStatus = "gitspace.caddy.health.unhealthy-status"
+
+	// AnnotationUpstreamHealth 写回的上游健康状态注解键（Healthy、Unhealthy）
+	AnnotationUpstreamHealth = "gitspace.caddy.route.upstream-health"
+)
+
+// HealthCheckSettings 生成路由的 reverse_proxy 健康检查设置
+type HealthCheckSettings struct {
+	// Path 主动健康检查的请求路径，为空时不启用主动检查
+	Path string
+
+	// Interval 主动健康检查的间隔
+	Interval time.Duration
+
+	// ExpectStatus 主动健康检查期望的状态码，0 表示 2xx
+	ExpectStatus int
+
+	// FailDuration 被动健康检查记住一次失败的时长，0 表示不启用被动检查
+	FailDuration time.Duration
+
+	// UnhealthyStatus 被动健康检查视为失败的响应状态码
+	UnhealthyStatus []int
+}
+
+// Enabled 是否启用了主动或被动健康检查
+func (s HealthCheckSettings) Enabled() bool {
+	return s.Path != "" || s.FailDuration > 0
+}
+
+// ApplyHealthCheckAnnotations 用工作负载注解覆盖健康检查设置
+func ApplyHealthCheckAnnotations(settings HealthCheckSettings, annotations map[string]string) (HealthCheckSettings, error) {
+	if value, exists := annotations[AnnotationHealthPath]; exists {
+		switch {
+		case value == "off":
+			settings.Path = ""
+		case strings.HasPrefix(value, "/"):
+			settings.Path = value
+		default:
+			return settings, fmt.Errorf("invalid health path annotation %q: must start with /", value)
+		}
+	}
+
+	if value, exists := annotations[AnnotationHealthInterval]; exists {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return settings, fmt.Errorf("invalid health interval annotation %q", value)
+		}
+		settings.Interval = interval
+	}
+
+	if value, exists := annotations[AnnotationHealthStatus]; exists {
+		status, err := strconv.Atoi(value)
+		if err != nil || status < 1 || status > 599 {
+			return settings, fmt.Errorf("invalid health status annotation %q", value)
+		}
+		settings.ExpectStatus = status
+	}
+
+	if value, exists := annotations[AnnotationHealthFailDuration]; exists {
+		failDuration, err := time.ParseDuration(value)
+		if err != nil || failDuration < 0 {
+			return settings, fmt.Errorf("invalid health fail duration annotation %q", value)
+		}
+		settings.FailDuration = failDuration
+	}
+
+	if value, exists := annotations[AnnotationHealthUnhealthyStatus]; exists {
+		statuses, err := ParseStatusCodes(value)
+		if err != nil {
+			return settings, fmt.Errorf("invalid health unhealthy status annotation: %w", err)
+		}
+		settings.UnhealthyStatus = statuses
+	}
+
+	return settings, nil
+}
+
+// ParseStatusCodes 解析逗号或空格分隔的 HTTP 状态码列表
+func ParseStatusCodes(value string) ([]int, error) {
+	var statuses []int
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		status, err := strconv.Atoi(field)
+		if err != nil || status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid status code %q", field)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// ApplyUpstreamHealthAnnotation 以 HealthFieldManager 通过 Server-Side Apply 写回上游健康状态
+// status 为空时移除此前写入的注解
+func ApplyUpstreamHealthAnnotation(ctx context.Context, client kubernetes.Interface, kind, namespace, name, status string) error {
+	annotations := map[string]string{}
+	if status != "" {
+		annotations[AnnotationUpstreamHealth] = status
+	}
+	return applyWorkloadAnnotations(ctx, client, HealthFieldManager, kind, namespace, name, annotations)
+}
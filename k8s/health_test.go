@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestApplyHealthCheckAnnotations 测试注解覆盖全局健康检查配置
+func TestApplyHealthCheckAnnotations(t *testing.T) {
+	defaults := HealthCheckSettings{Path: "/healthz", Interval: 10 * time.Second}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        HealthCheckSettings
+		wantErr     bool
+	}{
+		{"无注解使用全局配置", nil, defaults, false},
+		{"关闭主动检查", map[string]string{AnnotationHealthPath: "off"}, HealthCheckSettings{Interval: 10 * time.Second}, false},
+		{
+			"覆盖间隔并开启被动检查",
+			map[string]string{
+				AnnotationHealthInterval:        "30s",
+				AnnotationHealthFailDuration:    "1m",
+				AnnotationHealthUnhealthyStatus: "502,503",
+			},
+			HealthCheckSettings{Path: "/healthz", Interval: 30 * time.Second, FailDuration: time.Minute, UnhealthyStatus: []int{502, 503}},
+			false,
+		},
+		{"路径不以 / 开头", map[string]string{AnnotationHealthPath: "healthz"}, HealthCheckSettings{}, true},
+		{"无效状态码", map[string]string{AnnotationHealthUnhealthyStatus: "50x"}, HealthCheckSettings{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyHealthCheckAnnotations(defaults, tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyHealthCheckAnnotations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyHealthCheckAnnotations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyUpstreamHealthAnnotation 测试写回健康状态不影响 FieldManager 写入的路由注解
+func TestApplyUpstreamHealthAnnotation(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "vscode", Namespace: "default"},
+	})
+
+	route := map[string]string{AnnotationRouteID: "gitspace-vscode"}
+	if err := ApplyWorkloadAnnotations(ctx, client, KindDeployment, "default", "vscode", route); err != nil {
+		t.Fatalf("ApplyWorkloadAnnotations() error = %v", err)
+	}
+
+	steps := []struct {
+		status string
+		want   string
+	}{
+		{"Unhealthy", "Unhealthy"},
+		{"Healthy", "Healthy"},
+		{"", ""},
+	}
+	for _, step := range steps {
+		if err := ApplyUpstreamHealthAnnotation(ctx, client, KindDeployment, "default", "vscode", step.status); err != nil {
+			t.Fatalf("ApplyUpstreamHealthAnnotation(%q) error = %v", step.status, err)
+		}
+
+		deployment, err := client.AppsV1().Deployments("default").Get(ctx, "vscode", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations := deployment.GetAnnotations()
+		if got := annotations[AnnotationUpstreamHealth]; got != step.want {
+			t.Errorf("status %q: upstream health = %q, want %q", step.status, got, step.want)
+		}
+		if annotations[AnnotationRouteID] != "gitspace-vscode" {
+			t.Errorf("status %q: route annotation removed: %v", step.status, annotations)
+		}
+	}
+}
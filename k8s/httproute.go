@@ -0,0 +1,280 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KindHTTPRoute Gateway API HTTPRoute 资源类型
+const KindHTTPRoute = "HTTPRoute"
+
+// HTTPRouteGVR Gateway API HTTPRoute 的 GroupVersionResource
+var HTTPRouteGVR = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+// HTTPRoute Gateway API HTTPRoute 中本插件使用的字段子集
+// 通过 unstructured 转换，不依赖 Gateway API 的 Go 模块
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HTTPRouteSpec `json:"spec"`
+}
+
+// HTTPRouteSpec HTTPRoute spec
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+// ParentReference HTTPRoute 挂载的 Gateway
+type ParentReference struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// HTTPRouteRule 一组匹配条件（OR）和后端
+type HTTPRouteRule struct {
+	Matches     []HTTPRouteMatch `json:"matches,omitempty"`
+	BackendRefs []HTTPBackendRef `json:"backendRefs,omitempty"`
+}
+
+// HTTPRouteMatch 单个匹配条件（各字段之间为 AND）
+type HTTPRouteMatch struct {
+	Path    *HTTPPathMatch    `json:"path,omitempty"`
+	Headers []HTTPHeaderMatch `json:"headers,omitempty"`
+	Method  string            `json:"method,omitempty"`
+}
+
+// HTTPPathMatch 路径匹配：PathPrefix（默认）、Exact、RegularExpression
+type HTTPPathMatch struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// HTTPHeaderMatch 请求头匹配：Exact（默认）、RegularExpression
+type HTTPHeaderMatch struct {
+	Type  string `json:"type,omitempty"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HTTPBackendRef 后端引用，只支持同命名空间的 Service
+type HTTPBackendRef struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Port      int32  `json:"port,omitempty"`
+	Weight    *int32 `json:"weight,omitempty"`
+}
+
+// AttachedToGateway 判断 HTTPRoute 是否挂载到指定名称的 Gateway
+func (r *HTTPRoute) AttachedToGateway(name string) bool {
+	for _, ref := range r.Spec.ParentRefs {
+		if (ref.Kind == "" || ref.Kind == "Gateway") && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPRouteBackends 将 backendRefs 解析为 Service 集群 DNS 地址和权重
+// 只支持同命名空间、指定端口的 Service；权重为 0 的后端不接收流量
+func HTTPRouteBackends(namespace string, refs []HTTPBackendRef) ([]string, []int) {
+	var upstreams []string
+	var weights []int
+	for _, ref := range refs {
+		if (ref.Group != "" && ref.Group != "core") || (ref.Kind != "" && ref.Kind != "Service") {
+			continue
+		}
+		if ref.Namespace != "" && ref.Namespace != namespace {
+			continue
+		}
+		if ref.Port == 0 {
+			continue
+		}
+
+		weight := 1
+		if ref.Weight != nil {
+			weight = int(*ref.Weight)
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		upstreams = append(upstreams, fmt.Sprintf("%s.%s.svc:%d", ref.Name, namespace, ref.Port))
+		weights = append(weights, weight)
+	}
+	return upstreams, weights
+}
+
+// HTTPRouteMatchers 将 HTTPRoute matches 转换为 Caddy 匹配器集合（集合之间为 OR）
+func HTTPRouteMatchers(hostnames []string, matches []HTTPRouteMatch) []map[string]any {
+	if len(matches) == 0 {
+		return []map[string]any{{"host": hostnames}}
+	}
+
+	matchers := make([]map[string]any, 0, len(matches))
+	for _, match := range matches {
+		matcher := map[string]any{"host": hostnames}
+
+		if match.Path != nil && match.Path.Value != "" {
+			switch match.Path.Type {
+			case "Exact":
+				matcher["path"] = []string{match.Path.Value}
+			case "RegularExpression":
+				matcher["path_regexp"] = map[string]string{"pattern": match.Path.Value}
+			default:
+				if paths := IngressPathMatchers(match.Path.Value, false); paths[0] != "/*" {
+					matcher["path"] = paths
+				}
+			}
+		}
+
+		if match.Method != "" {
+			matcher["method"] = []string{match.Method}
+		}
+
+		headers := make(map[string][]string)
+		headerRegexps := make(map[string]map[string]string)
+		for _, header := range match.Headers {
+			if header.Type == "RegularExpression" {
+				headerRegexps[header.Name] = map[string]string{"pattern": header.Value}
+				continue
+			}
+			headers[header.Name] = append(headers[header.Name], header.Value)
+		}
+		if len(headers) > 0 {
+			matcher["header"] = headers
+		}
+		if len(headerRegexps) > 0 {
+			matcher["header_regexp"] = headerRegexps
+		}
+
+		matchers = append(matchers, matcher)
+	}
+	return matchers
+}
+
+// FilterHostnames 过滤 checkHost 拒绝的 hostnames（gitspace 保留域名），被拒绝的原因通过 ignored 返回
+func FilterHostnames(hostnames []string, checkHost func(string) error) (allowed []string, ignored []error) {
+	for _, hostname := range hostnames {
+		if err := checkHost(hostname); err != nil {
+			ignored = append(ignored, err)
+			continue
+		}
+		allowed = append(allowed, hostname)
+	}
+	return allowed, ignored
+}
+
+// HTTPRouteFromUnstructured 将 dynamic informer 返回的对象转换为 HTTPRoute
+func HTTPRouteFromUnstructured(obj *unstructured.Unstructured) (*HTTPRoute, error) {
+	route := &HTTPRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, route); err != nil {
+		return nil, fmt.Errorf("failed to convert HTTPRoute %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return route, nil
+}
+
+// ListHTTPRoutes 通过 API 列出命名空间下的所有 HTTPRoute
+// Gateway API CRD 未安装时返回空列表
+func ListHTTPRoutes(ctx context.Context, client dynamic.Interface, namespace string) ([]*HTTPRoute, error) {
+	list, err := client.Resource(HTTPRouteGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list httproutes: %w", err)
+	}
+
+	routes := make([]*HTTPRoute, 0, len(list.Items))
+	for i := range list.Items {
+		route, err := HTTPRouteFromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// HTTPRouteHandler 处理 HTTPRoute 事件的回调接口
+type HTTPRouteHandler interface {
+	// OnHTTPRouteAdd 处理 HTTPRoute 创建事件
+	OnHTTPRouteAdd(route *HTTPRoute) error
+
+	// OnHTTPRouteUpdate 处理 HTTPRoute 更新事件
+	OnHTTPRouteUpdate(oldRoute, newRoute *HTTPRoute) error
+
+	// OnHTTPRouteDelete 处理 HTTPRoute 删除事件
+	OnHTTPRouteDelete(route *HTTPRoute) error
+}
+
+// HTTPRouteWatcher 通过 dynamic informer 监听 Gateway API HTTPRoute
+type HTTPRouteWatcher struct {
+	*dynamicWatcher
+}
+
+// NewHTTPRouteWatcher 创建新的 HTTPRouteWatcher
+func NewHTTPRouteWatcher(
+	client dynamic.Interface,
+	discoveryClient discovery.DiscoveryInterface,
+	namespace string,
+	resyncPeriod time.Duration,
+	handler HTTPRouteHandler,
+) *HTTPRouteWatcher {
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if route, ok := toHTTPRoute(obj); ok {
+				_ = handler.OnHTTPRouteAdd(route)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldRoute, ok1 := toHTTPRoute(oldObj)
+			newRoute, ok2 := toHTTPRoute(newObj)
+			if ok1 && ok2 {
+				_ = handler.OnHTTPRouteUpdate(oldRoute, newRoute)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if route, ok := toHTTPRoute(obj); ok {
+				_ = handler.OnHTTPRouteDelete(route)
+			}
+		},
+	}
+
+	return &HTTPRouteWatcher{
+		dynamicWatcher: newDynamicWatcher(client, discoveryClient, HTTPRouteGVR, namespace, resyncPeriod, handlers),
+	}
+}
+
+// toHTTPRoute 将 Informer 对象转换为 HTTPRoute，支持 DeletedFinalStateUnknown
+func toHTTPRoute(obj any) (*HTTPRoute, bool) {
+	u, ok := toUnstructured(obj)
+	if !ok {
+		return nil, false
+	}
+	route, err := HTTPRouteFromUnstructured(u)
+	if err != nil {
+		return nil, false
+	}
+	return route, true
+}
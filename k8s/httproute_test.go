@@ -0,0 +1,164 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestAttachedToGateway 测试 HTTPRoute 是否挂载到指定 Gateway
+func TestAttachedToGateway(t *testing.T) {
+	tests := []struct {
+		name       string
+		parentRefs []ParentReference
+		want       bool
+	}{
+		{"默认 Kind 为 Gateway", []ParentReference{{Name: "caddy"}}, true},
+		{"显式 Kind Gateway", []ParentReference{{Kind: "Gateway", Name: "caddy"}}, true},
+		{"挂载到多个 Gateway", []ParentReference{{Name: "nginx"}, {Name: "caddy"}}, true},
+		{"其他 Gateway", []ParentReference{{Name: "nginx"}}, false},
+		{"同名的其他 Kind", []ParentReference{{Kind: "Service", Name: "caddy"}}, false},
+		{"没有 parentRefs", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := &HTTPRoute{Spec: HTTPRouteSpec{ParentRefs: tt.parentRefs}}
+			if got := route.AttachedToGateway("caddy"); got != tt.want {
+				t.Errorf("AttachedToGateway() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHTTPRouteBackends 测试 backendRefs 的过滤和权重
+func TestHTTPRouteBackends(t *testing.T) {
+	weight := func(w int32) *int32 { return &w }
+
+	tests := []struct {
+		name          string
+		refs          []HTTPBackendRef
+		wantUpstreams []string
+		wantWeights   []int
+	}{
+		{
+			"默认权重为 1",
+			[]HTTPBackendRef{{Name: "web", Port: 8080}},
+			[]string{"web.default.svc:8080"}, []int{1},
+		},
+		{
+			"按权重分流",
+			[]HTTPBackendRef{{Name: "v1", Port: 80, Weight: weight(90)}, {Name: "v2", Port: 80, Weight: weight(10)}},
+			[]string{"v1.default.svc:80", "v2.default.svc:80"}, []int{90, 10},
+		},
+		{
+			"跳过权重为 0、缺少端口、跨命名空间和非 Service 后端",
+			[]HTTPBackendRef{
+				{Name: "off", Port: 80, Weight: weight(0)},
+				{Name: "noport"},
+				{Name: "other", Namespace: "kube-system", Port: 80},
+				{Group: "example.com", Kind: "Bucket", Name: "bucket", Port: 80},
+				{Group: "core", Kind: "Service", Namespace: "default", Name: "web", Port: 80},
+			},
+			[]string{"web.default.svc:80"}, []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstreams, weights := HTTPRouteBackends("default", tt.refs)
+			if !reflect.DeepEqual(upstreams, tt.wantUpstreams) || !reflect.DeepEqual(weights, tt.wantWeights) {
+				t.Errorf("HTTPRouteBackends() = (%v, %v), want (%v, %v)", upstreams, weights, tt.wantUpstreams, tt.wantWeights)
+			}
+		})
+	}
+}
+
+// TestHTTPRouteMatchers 测试 HTTPRoute matches 到 Caddy 匹配器的转换
+func TestHTTPRouteMatchers(t *testing.T) {
+	hosts := []string{"app.example.com"}
+
+	tests := []struct {
+		name    string
+		matches []HTTPRouteMatch
+		want    []map[string]any
+	}{
+		{"没有 matches 时只匹配 host", nil, []map[string]any{{"host": hosts}}},
+		{
+			"PathPrefix 按路径段匹配",
+			[]HTTPRouteMatch{{Path: &HTTPPathMatch{Type: "PathPrefix", Value: "/api"}}},
+			[]map[string]any{{"host": hosts, "path": []string{"/api", "/api/*"}}},
+		},
+		{
+			"PathPrefix / 不限制路径",
+			[]HTTPRouteMatch{{Path: &HTTPPathMatch{Value: "/"}}},
+			[]map[string]any{{"host": hosts}},
+		},
+		{
+			"Exact 和 RegularExpression",
+			[]HTTPRouteMatch{
+				{Path: &HTTPPathMatch{Type: "Exact", Value: "/healthz"}},
+				{Path: &HTTPPathMatch{Type: "RegularExpression", Value: "^/v[0-9]+/"}},
+			},
+			[]map[string]any{
+				{"host": hosts, "path": []string{"/healthz"}},
+				{"host": hosts, "path_regexp": map[string]string{"pattern": "^/v[0-9]+/"}},
+			},
+		},
+		{
+			"方法和请求头",
+			[]HTTPRouteMatch{{
+				Method: "POST",
+				Headers: []HTTPHeaderMatch{
+					{Name: "X-Env", Value: "canary"},
+					{Type: "RegularExpression", Name: "User-Agent", Value: "^curl/"},
+				},
+			}},
+			[]map[string]any{{
+				"host":          hosts,
+				"method":        []string{"POST"},
+				"header":        map[string][]string{"X-Env": {"canary"}},
+				"header_regexp": map[string]map[string]string{"User-Agent": {"pattern": "^curl/"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPRouteMatchers(hosts, tt.matches); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HTTPRouteMatchers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFilterHostnames 测试 HTTPRoute hostnames 中 gitspace 保留域名的过滤
+func TestFilterHostnames(t *testing.T) {
+	checkHost := ReservedHostChecker("ws.example.com", func(label string) bool { return label == "vscode" })
+
+	tests := []struct {
+		name        string
+		hostnames   []string
+		want        []string
+		wantIgnored int
+	}{
+		{"普通域名", []string{"app.example.com", "docs.ws.example.com"}, []string{"app.example.com", "docs.ws.example.com"}, 0},
+		{"gitspace 域名", []string{"vscode.ws.example.com"}, nil, 1},
+		{"端口转发域名", []string{"8080-vscode.ws.example.com"}, nil, 1},
+		{"端口转发格式但 identifier 不存在", []string{"3000-other.ws.example.com"}, nil, 1},
+		{"base_domain 下的通配符", []string{"*.ws.example.com"}, nil, 1},
+		{"只保留未占用的域名", []string{"vscode.ws.example.com", "app.example.com"}, []string{"app.example.com"}, 1},
+		{"其他域名的通配符", []string{"*.example.org"}, []string{"*.example.org"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ignored := FilterHostnames(tt.hostnames, checkHost)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterHostnames() = %v, want %v", got, tt.want)
+			}
+			if len(ignored) != tt.wantIgnored {
+				t.Errorf("ignored = %v, want %d errors", ignored, tt.wantIgnored)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// identifier 来源
+const (
+	// IdentifierSourceLabel 从 label 读取（默认）
+	IdentifierSourceLabel = "label"
+
+	// IdentifierSourceAnnotation 从注解读取
+	IdentifierSourceAnnotation = "annotation"
+
+	// IdentifierSourceName 使用工作负载名称，可去掉匹配的后缀
+	IdentifierSourceName = "name"
+)
+
+// DefaultIdentifierLabel 默认读取 identifier 的 label
+const DefaultIdentifierLabel = "gitspace"
+
+// maxIdentifierLength DNS-1123 label 的最大长度
+const maxIdentifierLength = 63
+
+// identifierHashLength 超长 identifier 截断后追加的哈希长度
+const identifierHashLength = 8
+
+// IdentifierSource 描述从工作负载读取 gitspace identifier 的方式
+type IdentifierSource struct {
+	// Source 来源：IdentifierSourceLabel、IdentifierSourceAnnotation、IdentifierSourceName
+	Source string
+
+	// Key label 或注解的键（Source 为 name 时不使用）
+	Key string
+
+	// NameSuffix 从名称中去掉的后缀（Source 为 name 时使用，可为 nil）
+	NameSuffix *regexp.Regexp
+}
+
+// NewIdentifierSource 根据配置创建 IdentifierSource
+// source 为空时使用 label；label 的 key 为空时使用 "gitspace"
+func NewIdentifierSource(source, key, nameSuffix string) (IdentifierSource, error) {
+	s := IdentifierSource{Source: source, Key: key}
+
+	switch source {
+	case "", IdentifierSourceLabel:
+		s.Source = IdentifierSourceLabel
+		if s.Key == "" {
+			s.Key = DefaultIdentifierLabel
+		}
+	case IdentifierSourceAnnotation:
+		if s.Key == "" {
+			return IdentifierSource{}, fmt.Errorf("identifier key is required for annotation source")
+		}
+	case IdentifierSourceName:
+		if nameSuffix != "" {
+			re, err := regexp.Compile(nameSuffix)
+			if err != nil {
+				return IdentifierSource{}, fmt.Errorf("invalid identifier name suffix: %w", err)
+			}
+			s.NameSuffix = re
+		}
+	default:
+		return IdentifierSource{}, fmt.Errorf("unsupported identifier source: %s", source)
+	}
+
+	return s, nil
+}
+
+// Identifier 读取对象的 identifier 并转换为合法的 DNS-1123 label
+// 来源不存在或转换后为空时返回空字符串
+func (s IdentifierSource) Identifier(obj metav1.Object) string {
+	var raw string
+	switch s.Source {
+	case IdentifierSourceAnnotation:
+		raw = obj.GetAnnotations()[s.Key]
+	case IdentifierSourceName:
+		raw = obj.GetName()
+		if s.NameSuffix != nil {
+			if loc := s.NameSuffix.FindStringIndex(raw); loc != nil && loc[1] == len(raw) && loc[0] > 0 {
+				raw = raw[:loc[0]]
+			}
+		}
+	default:
+		raw = obj.GetLabels()[s.Key]
+	}
+	return SanitizeIdentifier(raw)
+}
+
+// invalidIdentifierChars DNS-1123 label 不允许的字符
+var invalidIdentifierChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// SanitizeIdentifier 将任意字符串转换为合法的 DNS-1123 label
+// 转为小写，非法字符替换为 "-"，去掉首尾的 "-"；
+// 超过 63 个字符时截断并追加原始值的哈希，保证结果稳定且不同输入不易冲突
+func SanitizeIdentifier(raw string) string {
+	identifier := invalidIdentifierChars.ReplaceAllString(strings.ToLower(raw), "-")
+	identifier = strings.Trim(identifier, "-")
+	if len(identifier) <= maxIdentifierLength {
+		return identifier
+	}
+
+	sum := sha256.Sum256([]byte(raw))
+	hash := hex.EncodeToString(sum[:])[:identifierHashLength]
+	prefix := strings.TrimRight(identifier[:maxIdentifierLength-identifierHashLength-1], "-")
+	return prefix + "-" + hash
+}
+
+// identifierSource 当前生效的 identifier 来源，由控制器根据配置设置
+var identifierSource atomic.Pointer[IdentifierSource]
+
+// SetIdentifierSource 设置全局 identifier 来源
+func SetIdentifierSource(source IdentifierSource) {
+	identifierSource.Store(&source)
+}
+
+// GetGitspaceIdentifier 从工作负载中提取 gitspace identifier
+// 这是稳定的配置级别标识符，不同于可能包含实例后缀的工作负载名称；
+// 来源由 SetIdentifierSource 配置，默认读取 "gitspace" label。
+// 返回值已转换为 DNS-1123 label，路由 ID、域名和恢复逻辑都使用该值；来源不存在时返回空字符串
+func GetGitspaceIdentifier(obj metav1.Object) string {
+	if obj == nil {
+		return ""
+	}
+
+	source := identifierSource.Load()
+	if source == nil {
+		return IdentifierSource{Source: IdentifierSourceLabel, Key: DefaultIdentifierLabel}.Identifier(obj)
+	}
+	return source.Identifier(obj)
+}
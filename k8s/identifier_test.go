@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestSanitizeIdentifier 测试 identifier 转换为 DNS-1123 label
+func TestSanitizeIdentifier(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"vscode", "vscode"},
+		{"My_Workspace", "my-workspace"},
+		{"user@example.com", "user-example-com"},
+		{"--edge--", "edge"},
+		{"a..b", "a-b"},
+		{"___", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeIdentifier(tt.raw); got != tt.want {
+			t.Errorf("SanitizeIdentifier(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+// TestSanitizeIdentifierTruncate 测试超长 identifier 截断后追加稳定哈希
+func TestSanitizeIdentifierTruncate(t *testing.T) {
+	long := strings.Repeat("a", 70)
+	got := SanitizeIdentifier(long)
+
+	if len(got) != maxIdentifierLength {
+		t.Fatalf("Expected length %d, got %d (%q)", maxIdentifierLength, len(got), got)
+	}
+	if got != SanitizeIdentifier(long) {
+		t.Error("Expected truncated identifier to be stable")
+	}
+
+	// 前缀相同、超长部分不同的输入不应冲突
+	other := SanitizeIdentifier(strings.Repeat("a", 69) + "b")
+	if got == other {
+		t.Errorf("Expected different hashes for different inputs, both got %q", got)
+	}
+
+	// 截断位置的 "-" 不应留在哈希前
+	dashed := SanitizeIdentifier(strings.Repeat("a", 53) + "-" + strings.Repeat("b", 20))
+	if strings.Contains(dashed, "--") {
+		t.Errorf("Expected no double dash, got %q", dashed)
+	}
+}
+
+// TestIdentifierSource 测试不同来源读取 identifier
+func TestIdentifierSource(t *testing.T) {
+	obj := &metav1.ObjectMeta{
+		Name:        "VSCode-7d9f8",
+		Labels:      map[string]string{"gitspace": "Label_Value", "team": "infra"},
+		Annotations: map[string]string{"gitspace.app.io/id": "from-annotation"},
+	}
+
+	tests := []struct {
+		name       string
+		source     string
+		key        string
+		nameSuffix string
+		want       string
+	}{
+		{"默认 label", "", "", "", "label-value"},
+		{"自定义 label", IdentifierSourceLabel, "team", "", "infra"},
+		{"注解", IdentifierSourceAnnotation, "gitspace.app.io/id", "", "from-annotation"},
+		{"名称", IdentifierSourceName, "", "", "vscode-7d9f8"},
+		{"名称去后缀", IdentifierSourceName, "", `-[0-9a-z]{5}$`, "vscode"},
+		{"后缀不匹配", IdentifierSourceName, "", `-v[0-9]+$`, "vscode-7d9f8"},
+		{"label 不存在", IdentifierSourceLabel, "missing", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewIdentifierSource(tt.source, tt.key, tt.nameSuffix)
+			if err != nil {
+				t.Fatalf("NewIdentifierSource failed: %v", err)
+			}
+			if got := source.Identifier(obj); got != tt.want {
+				t.Errorf("Identifier() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewIdentifierSourceInvalid 测试非法配置
+func TestNewIdentifierSourceInvalid(t *testing.T) {
+	if _, err := NewIdentifierSource(IdentifierSourceAnnotation, "", ""); err == nil {
+		t.Error("Expected error for annotation source without key")
+	}
+	if _, err := NewIdentifierSource(IdentifierSourceName, "", "("); err == nil {
+		t.Error("Expected error for invalid suffix regexp")
+	}
+	if _, err := NewIdentifierSource("env", "", ""); err == nil {
+		t.Error("Expected error for unknown source")
+	}
+}
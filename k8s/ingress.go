@@ -0,0 +1,368 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KindIngress Ingress 资源类型
+const KindIngress = "Ingress"
+
+// annotationIngressClass 旧版本通过注解指定 IngressClass
+const annotationIngressClass = "kubernetes.io/ingress.class"
+
+// IngressHandler 处理 Ingress 事件的回调接口
+type IngressHandler interface {
+	// OnIngressAdd 处理 Ingress 创建事件
+	OnIngressAdd(ingress *networkingv1.Ingress) error
+
+	// OnIngressUpdate 处理 Ingress 更新事件
+	OnIngressUpdate(oldIngress, newIngress *networkingv1.Ingress) error
+
+	// OnIngressDelete 处理 Ingress 删除事件（包括 IngressClass 变为其他值）
+	OnIngressDelete(ingress *networkingv1.Ingress) error
+}
+
+// IngressWatcher 监听指定 IngressClass 的 Ingress
+type IngressWatcher struct {
+	ingressClass    string
+	informerFactory informers.SharedInformerFactory
+	handler         IngressHandler
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+	ready           bool
+	readyMu         sync.RWMutex
+}
+
+// NewIngressWatcher 创建新的 IngressWatcher
+func NewIngressWatcher(
+	clientset kubernetes.Interface,
+	namespace string,
+	ingressClass string,
+	resyncPeriod time.Duration,
+	handler IngressHandler,
+) *IngressWatcher {
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(
+		clientset,
+		resyncPeriod,
+		informers.WithNamespace(namespace),
+	)
+
+	return &IngressWatcher{
+		ingressClass:    ingressClass,
+		informerFactory: informerFactory,
+		handler:         handler,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start 启动监听器
+// 阻塞直到 context 取消或发生致命错误
+func (w *IngressWatcher) Start(ctx context.Context) error {
+	informer := w.informerFactory.Networking().V1().Ingresses().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    w.handleAdd,
+		UpdateFunc: w.handleUpdate,
+		DeleteFunc: w.handleDelete,
+	})
+
+	w.informerFactory.Start(w.stopCh)
+
+	// 等待缓存同步
+	syncCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to sync ingress informer cache")
+	}
+
+	w.readyMu.Lock()
+	w.ready = true
+	w.readyMu.Unlock()
+
+	// 阻塞直到停止信号
+	select {
+	case <-ctx.Done():
+		w.Stop()
+		return nil
+	case <-w.stopCh:
+		return nil
+	}
+}
+
+// Stop 停止监听器，并等待 Informer goroutine 退出
+func (w *IngressWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		w.informerFactory.Shutdown()
+	})
+	w.readyMu.Lock()
+	w.ready = false
+	w.readyMu.Unlock()
+}
+
+// IsReady 返回监听器是否已准备好（informer 已同步）
+func (w *IngressWatcher) IsReady() bool {
+	w.readyMu.RLock()
+	defer w.readyMu.RUnlock()
+	return w.ready
+}
+
+// IsIngressClass 判断 Ingress 是否属于指定的 IngressClass
+// 优先使用 spec.ingressClassName，兼容旧版本的 kubernetes.io/ingress.class 注解
+func IsIngressClass(ingress *networkingv1.Ingress, ingressClass string) bool {
+	if ingress.Spec.IngressClassName != nil {
+		return *ingress.Spec.IngressClassName == ingressClass
+	}
+	return ingress.Annotations[annotationIngressClass] == ingressClass
+}
+
+// handleAdd 处理 Ingress 创建事件
+func (w *IngressWatcher) handleAdd(obj any) {
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok || !IsIngressClass(ingress, w.ingressClass) {
+		return
+	}
+
+	if err := w.handler.OnIngressAdd(ingress); err != nil {
+		// 错误已由 handler 记录
+		return
+	}
+}
+
+// handleUpdate 处理 Ingress 更新事件
+func (w *IngressWatcher) handleUpdate(oldObj, newObj any) {
+	oldIngress, ok1 := oldObj.(*networkingv1.Ingress)
+	newIngress, ok2 := newObj.(*networkingv1.Ingress)
+	if !ok1 || !ok2 {
+		return
+	}
+
+	oldMatch := IsIngressClass(oldIngress, w.ingressClass)
+	newMatch := IsIngressClass(newIngress, w.ingressClass)
+
+	switch {
+	case newMatch:
+		_ = w.handler.OnIngressUpdate(oldIngress, newIngress)
+	case oldMatch:
+		// IngressClass 变为其他值，视为删除
+		_ = w.handler.OnIngressDelete(newIngress)
+	}
+}
+
+// handleDelete 处理 Ingress 删除事件
+func (w *IngressWatcher) handleDelete(obj any) {
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok {
+		// 处理 DeletedFinalStateUnknown 情况
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		ingress, ok = tombstone.Obj.(*networkingv1.Ingress)
+		if !ok {
+			return
+		}
+	}
+
+	if !IsIngressClass(ingress, w.ingressClass) {
+		return
+	}
+
+	if err := w.handler.OnIngressDelete(ingress); err != nil {
+		return
+	}
+}
+
+// IngressPath Ingress 中一条 host + path 规则
+type IngressPath struct {
+	Host     string
+	Paths    []string // Caddy path 匹配器，为空时匹配该 host 的所有路径
+	Exact    bool
+	Upstream string // Service 集群 DNS 地址
+}
+
+// TranslateIngress 解析 Ingress 规则，返回去重后的 host 列表和按优先级排序的 path 规则
+// 没有 host 的规则会匹配所有域名，可能覆盖 gitspace 路由，因此忽略；
+// checkHost 拒绝的 host（gitspace 保留域名）同样忽略。
+// 被忽略的规则和无法解析的后端通过 ignored 返回，由调用方记录
+func TranslateIngress(
+	ctx context.Context,
+	client kubernetes.Interface,
+	ingress *networkingv1.Ingress,
+	checkHost func(string) error,
+) (hosts []string, paths []IngressPath, ignored []error) {
+	seen := make(map[string]bool)
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host == "" {
+			ignored = append(ignored, fmt.Errorf("rule without host"))
+			continue
+		}
+		if checkHost != nil {
+			if err := checkHost(rule.Host); err != nil {
+				ignored = append(ignored, err)
+				continue
+			}
+		}
+
+		var rulePaths []IngressPath
+		if rule.HTTP != nil {
+			for _, path := range rule.HTTP.Paths {
+				upstream, err := IngressBackendUpstream(ctx, client, ingress.Namespace, path.Backend)
+				if err != nil {
+					ignored = append(ignored, fmt.Errorf("path %q of host %s: %w", path.Path, rule.Host, err))
+					continue
+				}
+
+				exact := path.PathType != nil && *path.PathType == networkingv1.PathTypeExact
+				rulePaths = append(rulePaths, IngressPath{
+					Host:     rule.Host,
+					Paths:    IngressPathMatchers(path.Path, exact),
+					Exact:    exact,
+					Upstream: upstream,
+				})
+			}
+		}
+
+		// 默认后端作为该 host 的兜底
+		if ingress.Spec.DefaultBackend != nil {
+			upstream, err := IngressBackendUpstream(ctx, client, ingress.Namespace, *ingress.Spec.DefaultBackend)
+			if err != nil {
+				ignored = append(ignored, fmt.Errorf("default backend of host %s: %w", rule.Host, err))
+			} else {
+				rulePaths = append(rulePaths, IngressPath{Host: rule.Host, Upstream: upstream})
+			}
+		}
+
+		if len(rulePaths) == 0 {
+			continue
+		}
+		if !seen[rule.Host] {
+			seen[rule.Host] = true
+			hosts = append(hosts, rule.Host)
+		}
+		paths = append(paths, rulePaths...)
+	}
+
+	// Exact 优先，其次按路径长度从长到短；兜底（无 path）排在最后
+	sort.SliceStable(paths, func(i, j int) bool {
+		if paths[i].Exact != paths[j].Exact {
+			return paths[i].Exact
+		}
+		return longestPath(paths[i].Paths) > longestPath(paths[j].Paths)
+	})
+
+	return hosts, paths, ignored
+}
+
+// IngressBackendUpstream 将 Ingress 后端解析为 Service 的集群 DNS 地址
+// 端口为名称时查询 Service 获取端口号
+func IngressBackendUpstream(ctx context.Context, client kubernetes.Interface, namespace string, backend networkingv1.IngressBackend) (string, error) {
+	if backend.Service == nil {
+		return "", fmt.Errorf("only service backends are supported")
+	}
+
+	port := backend.Service.Port.Number
+	if port == 0 {
+		service, err := client.CoreV1().Services(namespace).Get(ctx, backend.Service.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get service %s/%s: %w", namespace, backend.Service.Name, err)
+		}
+		for _, servicePort := range service.Spec.Ports {
+			if servicePort.Name == backend.Service.Port.Name {
+				port = servicePort.Port
+				break
+			}
+		}
+		if port == 0 {
+			return "", fmt.Errorf("service %s/%s has no port named %q", namespace, backend.Service.Name, backend.Service.Port.Name)
+		}
+	}
+
+	return fmt.Sprintf("%s.%s.svc:%d", backend.Service.Name, namespace, port), nil
+}
+
+// IngressPathMatchers 将 Ingress path 转换为 Caddy path 匹配器
+// Prefix 按路径段匹配："/foo" 匹配 "/foo" 和 "/foo/..."，不匹配 "/foobar"
+func IngressPathMatchers(path string, exact bool) []string {
+	if path == "" {
+		path = "/"
+	}
+	if exact {
+		return []string{path}
+	}
+
+	prefix := strings.TrimSuffix(path, "/")
+	if prefix == "" {
+		return []string{"/*"}
+	}
+	return []string{prefix, prefix + "/*"}
+}
+
+// longestPath 返回匹配器中最长路径的长度，用于排序
+func longestPath(paths []string) int {
+	longest := 0
+	for _, p := range paths {
+		longest = max(longest, len(p))
+	}
+	return longest
+}
+
+// PatchIngressLoadBalancer 将负载均衡地址写入 Ingress status
+// address 为 IP 时写入 ip 字段，否则写入 hostname 字段；状态未变化时不写入
+func PatchIngressLoadBalancer(
+	ctx context.Context,
+	client kubernetes.Interface,
+	ingress *networkingv1.Ingress,
+	address string,
+) error {
+	entry := networkingv1.IngressLoadBalancerIngress{Hostname: address}
+	if net.ParseIP(address) != nil {
+		entry = networkingv1.IngressLoadBalancerIngress{IP: address}
+	}
+	loadBalancer := networkingv1.IngressLoadBalancerStatus{
+		Ingress: []networkingv1.IngressLoadBalancerIngress{entry},
+	}
+
+	if equality.Semantic.DeepEqual(loadBalancer, ingress.Status.LoadBalancer) {
+		return nil
+	}
+
+	patchBytes, err := json.Marshal(map[string]any{
+		"status": map[string]any{
+			"loadBalancer": loadBalancer,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch data: %w", err)
+	}
+
+	_, err = client.NetworkingV1().Ingresses(ingress.Namespace).Patch(
+		ctx,
+		ingress.Name,
+		types.MergePatchType,
+		patchBytes,
+		metav1.PatchOptions{},
+		"status",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to patch ingress %s/%s status: %w", ingress.Namespace, ingress.Name, err)
+	}
+
+	return nil
+}
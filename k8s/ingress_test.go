@@ -0,0 +1,253 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// serviceBackend 构造按端口号引用 Service 的后端
+func serviceBackend(name string, port int32) networkingv1.IngressBackend {
+	return networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+		Name: name,
+		Port: networkingv1.ServiceBackendPort{Number: port},
+	}}
+}
+
+// namedServiceBackend 构造按端口名称引用 Service 的后端
+func namedServiceBackend(name, port string) networkingv1.IngressBackend {
+	return networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+		Name: name,
+		Port: networkingv1.ServiceBackendPort{Name: port},
+	}}
+}
+
+// ingressRule 构造 host 下的一组 path 规则
+func ingressRule(host string, paths ...networkingv1.HTTPIngressPath) networkingv1.IngressRule {
+	rule := networkingv1.IngressRule{Host: host}
+	if len(paths) > 0 {
+		rule.HTTP = &networkingv1.HTTPIngressRuleValue{Paths: paths}
+	}
+	return rule
+}
+
+// ingressPathRule 构造一条 path 规则
+func ingressPathRule(path string, pathType networkingv1.PathType, backend networkingv1.IngressBackend) networkingv1.HTTPIngressPath {
+	return networkingv1.HTTPIngressPath{Path: path, PathType: &pathType, Backend: backend}
+}
+
+// TestTranslateIngress 测试 Ingress 规则转换：pathType、默认后端、命名端口和规则排序
+func TestTranslateIngress(t *testing.T) {
+	client := fake.NewClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "metrics", Port: 9090},
+			{Name: "http", Port: 8080},
+		}},
+	})
+
+	// ws.example.com 为 base_domain，vscode 为现有 gitspace 的 identifier
+	checkHost := ReservedHostChecker("ws.example.com", func(label string) bool { return label == "vscode" })
+
+	tests := []struct {
+		name        string
+		spec        networkingv1.IngressSpec
+		wantHosts   []string
+		wantPaths   []IngressPath
+		wantIgnored int
+	}{
+		{
+			name: "Prefix 按路径段匹配，Exact 优先，长路径优先",
+			spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				ingressRule("app.example.com",
+					ingressPathRule("/", networkingv1.PathTypePrefix, serviceBackend("root", 80)),
+					ingressPathRule("/api/", networkingv1.PathTypePrefix, serviceBackend("api", 80)),
+					ingressPathRule("/healthz", networkingv1.PathTypeExact, serviceBackend("health", 80)),
+				),
+			}},
+			wantHosts: []string{"app.example.com"},
+			wantPaths: []IngressPath{
+				{Host: "app.example.com", Paths: []string{"/healthz"}, Exact: true, Upstream: "health.default.svc:80"},
+				{Host: "app.example.com", Paths: []string{"/api", "/api/*"}, Upstream: "api.default.svc:80"},
+				{Host: "app.example.com", Paths: []string{"/*"}, Upstream: "root.default.svc:80"},
+			},
+		},
+		{
+			name: "ImplementationSpecific 按 Prefix 处理",
+			spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				ingressRule("app.example.com",
+					ingressPathRule("/docs", networkingv1.PathTypeImplementationSpecific, serviceBackend("docs", 80)),
+				),
+			}},
+			wantHosts: []string{"app.example.com"},
+			wantPaths: []IngressPath{
+				{Host: "app.example.com", Paths: []string{"/docs", "/docs/*"}, Upstream: "docs.default.svc:80"},
+			},
+		},
+		{
+			name: "默认后端作为每个 host 的兜底",
+			spec: networkingv1.IngressSpec{
+				DefaultBackend: &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+					Name: "fallback",
+					Port: networkingv1.ServiceBackendPort{Number: 80},
+				}},
+				Rules: []networkingv1.IngressRule{
+					ingressRule("a.example.com", ingressPathRule("/api", networkingv1.PathTypePrefix, serviceBackend("api", 80))),
+					ingressRule("b.example.com"),
+				},
+			},
+			wantHosts: []string{"a.example.com", "b.example.com"},
+			wantPaths: []IngressPath{
+				{Host: "a.example.com", Paths: []string{"/api", "/api/*"}, Upstream: "api.default.svc:80"},
+				{Host: "a.example.com", Upstream: "fallback.default.svc:80"},
+				{Host: "b.example.com", Upstream: "fallback.default.svc:80"},
+			},
+		},
+		{
+			name: "命名端口通过 Service 解析",
+			spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				ingressRule("app.example.com", ingressPathRule("/", networkingv1.PathTypePrefix, namedServiceBackend("web", "http"))),
+			}},
+			wantHosts: []string{"app.example.com"},
+			wantPaths: []IngressPath{
+				{Host: "app.example.com", Paths: []string{"/*"}, Upstream: "web.default.svc:8080"},
+			},
+		},
+		{
+			name: "无法解析的后端和没有 host 的规则被忽略",
+			spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				ingressRule("", ingressPathRule("/", networkingv1.PathTypePrefix, serviceBackend("any", 80))),
+				ingressRule("app.example.com",
+					ingressPathRule("/missing", networkingv1.PathTypePrefix, namedServiceBackend("web", "grpc")),
+					ingressPathRule("/gone", networkingv1.PathTypePrefix, namedServiceBackend("absent", "http")),
+					ingressPathRule("/ok", networkingv1.PathTypePrefix, serviceBackend("ok", 80)),
+				),
+			}},
+			wantHosts: []string{"app.example.com"},
+			wantPaths: []IngressPath{
+				{Host: "app.example.com", Paths: []string{"/ok", "/ok/*"}, Upstream: "ok.default.svc:80"},
+			},
+			wantIgnored: 3,
+		},
+		{
+			name: "占用 gitspace 保留域名的规则被忽略",
+			spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				ingressRule("vscode.ws.example.com", ingressPathRule("/", networkingv1.PathTypePrefix, serviceBackend("a", 80))),
+				ingressRule("VSCode.WS.example.com", ingressPathRule("/", networkingv1.PathTypePrefix, serviceBackend("a", 80))),
+				ingressRule("8080-vscode.ws.example.com", ingressPathRule("/", networkingv1.PathTypePrefix, serviceBackend("b", 80))),
+				ingressRule("3000-other.ws.example.com", ingressPathRule("/", networkingv1.PathTypePrefix, serviceBackend("b", 80))),
+				ingressRule("*.ws.example.com", ingressPathRule("/", networkingv1.PathTypePrefix, serviceBackend("c", 80))),
+				ingressRule("docs.ws.example.com", ingressPathRule("/", networkingv1.PathTypePrefix, serviceBackend("docs", 80))),
+			}},
+			wantHosts: []string{"docs.ws.example.com"},
+			wantPaths: []IngressPath{
+				{Host: "docs.ws.example.com", Paths: []string{"/*"}, Upstream: "docs.default.svc:80"},
+			},
+			wantIgnored: 5,
+		},
+		{
+			name: "默认后端不会为保留域名生效",
+			spec: networkingv1.IngressSpec{
+				DefaultBackend: &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+					Name: "fallback",
+					Port: networkingv1.ServiceBackendPort{Number: 80},
+				}},
+				Rules: []networkingv1.IngressRule{ingressRule("vscode.ws.example.com")},
+			},
+			wantIgnored: 1,
+		},
+		{
+			name: "重复 host 只记录一次",
+			spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				ingressRule("app.example.com", ingressPathRule("/a", networkingv1.PathTypeExact, serviceBackend("a", 80))),
+				ingressRule("app.example.com", ingressPathRule("/b", networkingv1.PathTypeExact, serviceBackend("b", 80))),
+			}},
+			wantHosts: []string{"app.example.com"},
+			wantPaths: []IngressPath{
+				{Host: "app.example.com", Paths: []string{"/a"}, Exact: true, Upstream: "a.default.svc:80"},
+				{Host: "app.example.com", Paths: []string{"/b"}, Exact: true, Upstream: "b.default.svc:80"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+
+			hosts, paths, ignored := TranslateIngress(context.Background(), client, ingress, checkHost)
+			if !reflect.DeepEqual(hosts, tt.wantHosts) {
+				t.Errorf("hosts = %v, want %v", hosts, tt.wantHosts)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("paths = %+v, want %+v", paths, tt.wantPaths)
+			}
+			if len(ignored) != tt.wantIgnored {
+				t.Errorf("ignored = %v, want %d errors", ignored, tt.wantIgnored)
+			}
+		})
+	}
+}
+
+// TestIngressPathMatchers 测试 Ingress path 到 Caddy 匹配器的转换
+func TestIngressPathMatchers(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		exact bool
+		want  []string
+	}{
+		{"空路径", "", false, []string{"/*"}},
+		{"根路径", "/", false, []string{"/*"}},
+		{"前缀", "/foo", false, []string{"/foo", "/foo/*"}},
+		{"前缀带尾部斜杠", "/foo/", false, []string{"/foo", "/foo/*"}},
+		{"精确匹配", "/foo", true, []string{"/foo"}},
+		{"精确匹配空路径", "", true, []string{"/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IngressPathMatchers(tt.path, tt.exact); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IngressPathMatchers(%q, %v) = %v, want %v", tt.path, tt.exact, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsIngressClass 测试按 IngressClass 过滤 Ingress
+func TestIsIngressClass(t *testing.T) {
+	className := func(name string) *string { return &name }
+
+	tests := []struct {
+		name        string
+		className   *string
+		annotations map[string]string
+		want        bool
+	}{
+		{"ingressClassName 匹配", className("caddy"), nil, true},
+		{"ingressClassName 不匹配", className("nginx"), nil, false},
+		{"ingressClassName 优先于注解", className("nginx"), map[string]string{annotationIngressClass: "caddy"}, false},
+		{"兼容旧注解", nil, map[string]string{annotationIngressClass: "caddy"}, true},
+		{"旧注解不匹配", nil, map[string]string{annotationIngressClass: "nginx"}, false},
+		{"未指定 IngressClass", nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       networkingv1.IngressSpec{IngressClassName: tt.className},
+			}
+			if got := IsIngressClass(ingress, "caddy"); got != tt.want {
+				t.Errorf("IsIngressClass() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
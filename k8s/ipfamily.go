@@ -0,0 +1,36 @@
+package k8s
+
+import (
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Pod IP 地址族偏好
+const (
+	// IPFamilyAny 使用 Pod 的主 IP（status.podIP，默认）
+	IPFamilyAny = "any"
+
+	// IPFamilyIPv4 优先使用 IPv4 地址
+	IPFamilyIPv4 = "ipv4"
+
+	// IPFamilyIPv6 优先使用 IPv6 地址
+	IPFamilyIPv6 = "ipv6"
+)
+
+// SelectPodIP 按地址族偏好从 Pod 的 IP 中选择路由目标
+// 双栈集群中 status.podIPs 同时包含 IPv4 和 IPv6 地址；没有偏好地址族的地址时退回主 IP
+func SelectPodIP(pod *corev1.Pod, family string) string {
+	if family == IPFamilyIPv4 || family == IPFamilyIPv6 {
+		for _, podIP := range pod.Status.PodIPs {
+			ip := net.ParseIP(podIP.IP)
+			if ip == nil {
+				continue
+			}
+			if (ip.To4() != nil) == (family == IPFamilyIPv4) {
+				return ip.String()
+			}
+		}
+	}
+	return pod.Status.PodIP
+}
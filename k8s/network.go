@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// 网络访问限制注解
+const (
+	// AnnotationAllowCIDRs 允许访问 gitspace 的客户端地址段，多个用逗号分隔（如 "10.8.0.0/16,192.168.1.10"）
+	AnnotationAllowCIDRs = "gitspace.caddy.allow-cidrs"
+
+	// AnnotationRequireClientCert 设为 "true" 时要求客户端出示由 client_ca_files 签发的证书
+	AnnotationRequireClientCert = "gitspace.caddy.require-client-cert"
+)
+
+// NetworkPolicy 工作负载声明的网络访问限制
+type NetworkPolicy struct {
+	// AllowCIDRs 允许的客户端地址段（规范化后的 CIDR），为空时不限制
+	AllowCIDRs []string
+	// RequireClientCert 是否要求客户端证书
+	RequireClientCert bool
+}
+
+// ParseNetworkPolicy 解析工作负载的网络访问限制注解
+// 单个 IP 按 /32（IPv6 为 /128）处理，任一地址段无效时返回错误
+func ParseNetworkPolicy(annotations map[string]string) (NetworkPolicy, error) {
+	var policy NetworkPolicy
+
+	if value := strings.TrimSpace(annotations[AnnotationAllowCIDRs]); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			prefix, err := parseCIDR(item)
+			if err != nil {
+				return NetworkPolicy{}, fmt.Errorf("invalid %s annotation: %w", AnnotationAllowCIDRs, err)
+			}
+			policy.AllowCIDRs = append(policy.AllowCIDRs, prefix.String())
+		}
+		if len(policy.AllowCIDRs) == 0 {
+			return NetworkPolicy{}, fmt.Errorf("invalid %s annotation: no address ranges", AnnotationAllowCIDRs)
+		}
+	}
+
+	if value := strings.TrimSpace(annotations[AnnotationRequireClientCert]); value != "" {
+		required, err := strconv.ParseBool(value)
+		if err != nil {
+			return NetworkPolicy{}, fmt.Errorf("invalid %s annotation %q: %w", AnnotationRequireClientCert, value, err)
+		}
+		policy.RequireClientCert = required
+	}
+
+	return policy, nil
+}
+
+// AllowsAddr 客户端地址是否在允许的地址段内，未限制地址段时总是返回 true
+func (p NetworkPolicy) AllowsAddr(addr netip.Addr) bool {
+	if len(p.AllowCIDRs) == 0 {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, cidr := range p.AllowCIDRs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCIDR 解析 CIDR 或单个 IP，返回掩码后的地址段
+func parseCIDR(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", value)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q", value)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
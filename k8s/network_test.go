@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// TestParseNetworkPolicy 测试网络访问限制注解解析
+func TestParseNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantCIDRs   string
+		wantCert    bool
+		wantErr     bool
+	}{
+		{"无注解不限制", nil, "", false, false},
+		{"CIDR 和单个 IP", map[string]string{AnnotationAllowCIDRs: "10.8.1.7/16, 192.168.1.10"}, "10.8.0.0/16,192.168.1.10/32", false, false},
+		{"IPv6", map[string]string{AnnotationAllowCIDRs: "fd00::/8,::1"}, "fd00::/8,::1/128", false, false},
+		{"要求客户端证书", map[string]string{AnnotationRequireClientCert: "true"}, "", true, false},
+		{"无效 CIDR", map[string]string{AnnotationAllowCIDRs: "10.8.0.0/33"}, "", false, true},
+		{"无效 IP", map[string]string{AnnotationAllowCIDRs: "10.8.0.0,vpn"}, "", false, true},
+		{"只有分隔符", map[string]string{AnnotationAllowCIDRs: ","}, "", false, true},
+		{"无效布尔值", map[string]string{AnnotationRequireClientCert: "required"}, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNetworkPolicy(tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNetworkPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cidrs := strings.Join(got.AllowCIDRs, ","); cidrs != tt.wantCIDRs {
+				t.Errorf("AllowCIDRs = %q, want %q", cidrs, tt.wantCIDRs)
+			}
+			if got.RequireClientCert != tt.wantCert {
+				t.Errorf("RequireClientCert = %v, want %v", got.RequireClientCert, tt.wantCert)
+			}
+		})
+	}
+}
+
+// TestNetworkPolicyAllowsAddr 测试客户端地址匹配
+func TestNetworkPolicyAllowsAddr(t *testing.T) {
+	policy := NetworkPolicy{AllowCIDRs: []string{"10.8.0.0/16", "fd00::/8"}}
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"10.8.3.4", true},
+		{"::ffff:10.8.3.4", true},
+		{"10.9.0.1", false},
+		{"fd00::1", true},
+		{"2001:db8::1", false},
+	}
+	for _, tt := range tests {
+		if got := policy.AllowsAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("AllowsAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+
+	if !(NetworkPolicy{}).AllowsAddr(netip.MustParseAddr("203.0.113.1")) {
+		t.Error("Expected empty policy to allow any address")
+	}
+}
+
+// TestRouteSettingsChanged 测试影响路由配置的注解变化检测
+func TestRouteSettingsChanged(t *testing.T) {
+	tests := []struct {
+		name string
+		old  map[string]string
+		new  map[string]string
+		want bool
+	}{
+		{"都为空", nil, nil, false},
+		{"无关注解变化", map[string]string{AnnotationSynced: "a"}, map[string]string{AnnotationSynced: "b"}, false},
+		{"新增 CIDR 限制", nil, map[string]string{AnnotationAllowCIDRs: "10.0.0.0/8"}, true},
+		{"CIDR 限制变化", map[string]string{AnnotationAllowCIDRs: "10.0.0.0/8"}, map[string]string{AnnotationAllowCIDRs: "bad"}, true},
+		{"开启客户端证书", nil, map[string]string{AnnotationRequireClientCert: "true"}, true},
+		{"客户端限流变化", map[string]string{AnnotationRateLimitClient: "10/s"}, map[string]string{AnnotationRateLimitClient: "20/s"}, true},
+		{"移除带宽限制", map[string]string{AnnotationBandwidthLimit: "1MB"}, nil, true},
+		{"健康检查路径变化", map[string]string{AnnotationHealthPath: "/healthz"}, map[string]string{AnnotationHealthPath: "off"}, true},
+		{"健康状态写回不触发", nil, map[string]string{AnnotationUpstreamHealth: "Unhealthy"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RouteSettingsChanged(tt.old, tt.new); got != tt.want {
+				t.Errorf("RouteSettingsChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
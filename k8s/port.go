@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// conventionalPortNames 没有端口注解且声明了多个端口时，按顺序查找的约定端口名称
+var conventionalPortNames = []string{"http", "ide"}
+
+// PodSpecOf 返回工作负载的 Pod 模板（独立 Pod 返回其自身的 spec），未知类型返回 nil
+func PodSpecOf(workload Workload) *corev1.PodSpec {
+	switch w := workload.(type) {
+	case DeploymentWorkload:
+		return &w.Spec.Template.Spec
+	case StatefulSetWorkload:
+		return &w.Spec.Template.Spec
+	case PodWorkload:
+		return &w.Spec
+	default:
+		return nil
+	}
+}
+
+// declaredPorts 返回 Pod spec 中所有容器声明的 TCP 端口
+func declaredPorts(spec *corev1.PodSpec) []corev1.ContainerPort {
+	if spec == nil {
+		return nil
+	}
+	var ports []corev1.ContainerPort
+	for _, container := range spec.Containers {
+		for _, port := range container.Ports {
+			if port.Protocol == "" || port.Protocol == corev1.ProtocolTCP {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports
+}
+
+// ResolveWorkloadPort 解析工作负载的目标端口
+//   - 端口注解为数字时直接使用，容器声明了端口时必须是其中之一
+//   - 端口注解为名称（如 "ide"）时，按容器端口名称解析
+//   - 没有注解时，使用唯一声明的端口，或按约定名称 "http"、"ide" 查找；
+//     容器没有声明端口时使用 defaultPort
+func ResolveWorkloadPort(workload Workload, defaultPort int) (int, error) {
+	ports := declaredPorts(PodSpecOf(workload))
+
+	value, exists := workload.GetAnnotations()[AnnotationPort]
+	if exists {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			// 按容器端口名称解析
+			for _, p := range ports {
+				if p.Name == value {
+					return int(p.ContainerPort), nil
+				}
+			}
+			return 0, fmt.Errorf("port annotation %q does not match any container port name", value)
+		}
+		if port < 1 || port > 65535 {
+			return 0, fmt.Errorf("port out of range (1-65535): %d", port)
+		}
+		if len(ports) > 0 && !slices.ContainsFunc(ports, func(p corev1.ContainerPort) bool { return int(p.ContainerPort) == port }) {
+			return 0, fmt.Errorf("port %d is not declared by any container", port)
+		}
+		return port, nil
+	}
+
+	switch len(ports) {
+	case 0:
+		return defaultPort, nil
+	case 1:
+		return int(ports[0].ContainerPort), nil
+	}
+
+	for _, name := range conventionalPortNames {
+		for _, p := range ports {
+			if p.Name == name {
+				return int(p.ContainerPort), nil
+			}
+		}
+	}
+	if slices.ContainsFunc(ports, func(p corev1.ContainerPort) bool { return int(p.ContainerPort) == defaultPort }) {
+		return defaultPort, nil
+	}
+	return 0, fmt.Errorf("multiple container ports declared, set the %s annotation to choose one", AnnotationPort)
+}
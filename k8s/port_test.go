@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testDeployment 构造声明了指定容器端口的 Deployment
+func testDeployment(annotations map[string]string, ports ...corev1.ContainerPort) DeploymentWorkload {
+	return DeploymentWorkload{Deployment: &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "vscode", Annotations: annotations},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "ide", Ports: ports}},
+				},
+			},
+		},
+	}}
+}
+
+// TestResolveWorkloadPort 测试端口注解、端口名称和自动发现
+func TestResolveWorkloadPort(t *testing.T) {
+	ide := corev1.ContainerPort{Name: "ide", ContainerPort: 3000}
+	metrics := corev1.ContainerPort{Name: "metrics", ContainerPort: 9090}
+	http := corev1.ContainerPort{Name: "http", ContainerPort: 8080}
+
+	tests := []struct {
+		name     string
+		workload Workload
+		want     int
+		wantErr  bool
+	}{
+		{"未声明端口使用默认端口", testDeployment(nil), 8089, false},
+		{"唯一声明的端口", testDeployment(nil, ide), 3000, false},
+		{"约定名称 http 优先", testDeployment(nil, metrics, ide, http), 8080, false},
+		{"约定名称 ide", testDeployment(nil, metrics, ide), 3000, false},
+		{"多个端口无法确定", testDeployment(nil, metrics, corev1.ContainerPort{ContainerPort: 9091}), 0, true},
+		{"注解为端口名称", testDeployment(map[string]string{AnnotationPort: "metrics"}, ide, metrics), 9090, false},
+		{"注解名称不存在", testDeployment(map[string]string{AnnotationPort: "web"}, ide), 0, true},
+		{"注解端口已声明", testDeployment(map[string]string{AnnotationPort: "3000"}, ide), 3000, false},
+		{"注解端口未声明", testDeployment(map[string]string{AnnotationPort: "4000"}, ide), 0, true},
+		{"未声明端口时信任注解", testDeployment(map[string]string{AnnotationPort: "4000"}), 4000, false},
+		{"注解端口越界", testDeployment(map[string]string{AnnotationPort: "70000"}), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveWorkloadPort(tt.workload, 8089)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveWorkloadPort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveWorkloadPort() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
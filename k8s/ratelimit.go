@@ -0,0 +1,13 @@
+package k8s
+
+// 限流注解，覆盖全局的 rate_limit_*、bandwidth_limit 配置，设为 "0" 时关闭对应限制
+const (
+	// AnnotationRateLimitClient 每个客户端 IP 的限流参数（"<rps>[,<burst>]"，如 "10,40"）
+	AnnotationRateLimitClient = "gitspace.caddy.ratelimit.client"
+
+	// AnnotationRateLimitRoute gitspace 所有请求的限流参数（"<rps>[,<burst>]"）
+	AnnotationRateLimitRoute = "gitspace.caddy.ratelimit.route"
+
+	// AnnotationBandwidthLimit gitspace 响应的总带宽上限（每秒字节数，如 "5MB"）
+	AnnotationBandwidthLimit = "gitspace.caddy.ratelimit.bandwidth"
+)
@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConditionRouteReady Pod readiness gate 条件类型，路由写入 Caddy 且上游可访问后为 True
+const ConditionRouteReady corev1.PodConditionType = "gitspace.app.io/route-ready"
+
+// route-ready 条件的原因
+const (
+	ReasonRouteReady   = "RouteProgrammed"
+	ReasonRouteRemoved = "RouteRemoved"
+	ReasonProbeFailed  = "ProbeFailed"
+)
+
+// PodHasRouteReadinessGate 判断 Pod 是否声明了 route-ready readiness gate
+func PodHasRouteReadinessGate(spec *corev1.PodSpec) bool {
+	for _, gate := range spec.ReadinessGates {
+		if gate.ConditionType == ConditionRouteReady {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRouteReadinessGate 判断工作负载的 Pod 是否声明了 route-ready readiness gate
+func HasRouteReadinessGate(workload Workload) bool {
+	spec := PodSpecOf(workload)
+	return spec != nil && PodHasRouteReadinessGate(spec)
+}
+
+// IsPodRoutable 判断 Pod 是否可以作为路由目标
+// 声明了 route-ready readiness gate 的 Pod 在路由写入前不会 Ready，因此以容器就绪（ContainersReady）为准
+func IsPodRoutable(pod *corev1.Pod) bool {
+	if !PodHasRouteReadinessGate(&pod.Spec) {
+		return IsPodReady(pod)
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.ContainersReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// RoutabilityFromPod 判断工作负载是否需要根据 Pod 判断可路由
+// 声明了 route-ready readiness gate 的 Deployment、StatefulSet 在路由写入前不会 Available，
+// 写入后 Pod 容器不再就绪时工作负载状态也不能及时反映，因此以 Pod 的容器就绪状态为准
+func RoutabilityFromPod(workload Workload) bool {
+	if _, standalone := workload.(PodWorkload); standalone {
+		return false
+	}
+	return HasRouteReadinessGate(workload)
+}
+
+// IsWorkloadRoutable 判断工作负载是否可以创建或保留路由
+// 一般工作负载以自身就绪状态为准；RoutabilityFromPod 的工作负载以 pod
+// （可作为路由目标的 Pod，通常为 SelectRoutablePod 的结果）的容器就绪状态为准，pod 为 nil 时不可路由
+func IsWorkloadRoutable(workload Workload, pod *corev1.Pod) bool {
+	if !RoutabilityFromPod(workload) {
+		return workload.IsReady()
+	}
+	return workload.DesiredReplicas() > 0 && pod != nil && pod.DeletionTimestamp == nil && IsPodRoutable(pod)
+}
+
+// RouteReadyStatus 返回 Pod 当前 route-ready 条件的状态，条件不存在时返回空字符串
+func RouteReadyStatus(pod *corev1.Pod) corev1.ConditionStatus {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == ConditionRouteReady {
+			return cond.Status
+		}
+	}
+	return ""
+}
+
+// PatchPodRouteReady 设置 Pod 的 route-ready 条件，状态未变化时不写入
+func PatchPodRouteReady(
+	ctx context.Context,
+	client kubernetes.Interface,
+	pod *corev1.Pod,
+	ready bool,
+	reason, message string,
+) error {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	if RouteReadyStatus(pod) == status {
+		return nil
+	}
+
+	// Strategic Merge Patch 按 type 合并 conditions，不影响其他条件
+	patchBytes, err := json.Marshal(map[string]any{
+		"status": map[string]any{
+			"conditions": []corev1.PodCondition{
+				{
+					Type:               ConditionRouteReady,
+					Status:             status,
+					LastTransitionTime: metav1.NewTime(time.Now()),
+					Reason:             reason,
+					Message:            message,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch data: %w", err)
+	}
+
+	_, err = client.CoreV1().Pods(pod.Namespace).Patch(
+		ctx,
+		pod.Name,
+		types.StrategicMergePatchType,
+		patchBytes,
+		metav1.PatchOptions{},
+		"status",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to patch pod %s/%s route-ready condition: %w", pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
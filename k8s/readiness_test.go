@@ -0,0 +1,189 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// gatedPod 构造声明了 route-ready readiness gate 的 Pod
+// 路由写入前 Pod 不会 Ready，只有容器就绪
+func gatedPod(name, hash string, containersReady bool, created time.Time) corev1.Pod {
+	pod := testPod(name, hash, false, created)
+	pod.Namespace = "default"
+	pod.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: ConditionRouteReady}}
+
+	status := corev1.ConditionFalse
+	if containersReady {
+		status = corev1.ConditionTrue
+	}
+	pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{Type: corev1.ContainersReady, Status: status})
+	return pod
+}
+
+// TestIsPodRoutable 测试带和不带 readiness gate 的 Pod 是否可以作为路由目标
+func TestIsPodRoutable(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		pod  corev1.Pod
+		want bool
+	}{
+		{"无 gate 且 Ready", testPod("a", "v1", true, now), true},
+		{"无 gate 且未 Ready", testPod("a", "v1", false, now), false},
+		{"有 gate 且容器就绪", gatedPod("a", "v1", true, now), true},
+		{"有 gate 但容器未就绪", gatedPod("a", "v1", false, now), false},
+		{"有 gate 但没有容器就绪条件", func() corev1.Pod {
+			pod := gatedPod("a", "v1", true, now)
+			pod.Status.Conditions = pod.Status.Conditions[:1]
+			return pod
+		}(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPodRoutable(&tt.pod); got != tt.want {
+				t.Errorf("IsPodRoutable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSelectRoutablePodWithGates 测试声明了 readiness gate 的 Pod 在路由写入前也能被选中
+func TestSelectRoutablePodWithGates(t *testing.T) {
+	now := time.Now()
+	key := appsv1.DefaultDeploymentUniqueLabelKey
+
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{"容器就绪的新 Pod", []corev1.Pod{testPod("old", "v1", true, now.Add(-time.Hour)), gatedPod("new", "v2", true, now)}, "new"},
+		{"新 Pod 容器未就绪时保留旧 Pod", []corev1.Pod{testPod("old", "v1", true, now.Add(-time.Hour)), gatedPod("new", "v2", false, now)}, "old"},
+		{"没有容器就绪的 Pod", []corev1.Pod{gatedPod("new", "v2", false, now)}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectRoutablePod(tt.pods, key, "v2")
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("SelectRoutablePod() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+// gatedDeployment 构造 Pod 模板声明了 route-ready readiness gate 的 Deployment
+func gatedDeployment(replicas int32, available bool) DeploymentWorkload {
+	workload := testDeployment(nil)
+	workload.Spec.Replicas = &replicas
+	workload.Spec.Template.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: ConditionRouteReady}}
+
+	status := corev1.ConditionFalse
+	if available {
+		status = corev1.ConditionTrue
+	}
+	workload.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: status}}
+	return workload
+}
+
+// TestIsWorkloadRoutable 测试带 gate 的工作负载以路由目标 Pod 的容器就绪状态判断可路由
+func TestIsWorkloadRoutable(t *testing.T) {
+	now := time.Now()
+	ready := gatedPod("a", "v1", true, now)
+	crashing := gatedPod("a", "v1", false, now)
+	deleting := gatedPod("a", "v1", true, now)
+	deleting.DeletionTimestamp = &metav1.Time{Time: now}
+	standalone := gatedPod("a", "v1", false, now)
+
+	available := testDeployment(nil)
+	available.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}}
+
+	tests := []struct {
+		name     string
+		workload Workload
+		pod      *corev1.Pod
+		want     bool
+	}{
+		{"无 gate 且 Available", available, nil, true},
+		{"无 gate 且未 Available", testDeployment(nil), &ready, false},
+		{"有 gate 且 Pod 容器就绪", gatedDeployment(1, false), &ready, true},
+		{"有 gate 且 Available 但 Pod 容器不再就绪", gatedDeployment(1, true), &crashing, false},
+		{"有 gate 但没有可路由的 Pod", gatedDeployment(1, true), nil, false},
+		{"有 gate 且缩容到 0", gatedDeployment(0, true), &ready, false},
+		{"有 gate 且 Pod 删除中", gatedDeployment(1, true), &deleting, false},
+		{"裸 Pod 容器未就绪", PodWorkload{Pod: &standalone}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsWorkloadRoutable(tt.workload, tt.pod); got != tt.want {
+				t.Errorf("IsWorkloadRoutable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPatchPodRouteReady 测试 route-ready 条件的写入和无变化时跳过写入
+func TestPatchPodRouteReady(t *testing.T) {
+	ctx := context.Background()
+	pod := gatedPod("vscode", "v1", true, time.Now())
+	client := fake.NewClientset(&pod)
+
+	steps := []struct {
+		ready      bool
+		reason     string
+		wantStatus corev1.ConditionStatus
+		wantPatch  bool
+	}{
+		{false, ReasonProbeFailed, corev1.ConditionFalse, true},
+		{false, ReasonProbeFailed, corev1.ConditionFalse, false},
+		{true, ReasonRouteReady, corev1.ConditionTrue, true},
+	}
+
+	for i, step := range steps {
+		current, err := client.CoreV1().Pods("default").Get(ctx, "vscode", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.ClearActions()
+
+		if err := PatchPodRouteReady(ctx, client, current, step.ready, step.reason, ""); err != nil {
+			t.Fatalf("step %d: PatchPodRouteReady() error = %v", i, err)
+		}
+
+		patched := false
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "patch" && action.GetSubresource() == "status" {
+				patched = true
+			}
+		}
+		if patched != step.wantPatch {
+			t.Errorf("step %d: patched = %v, want %v", i, patched, step.wantPatch)
+		}
+
+		updated, err := client.CoreV1().Pods("default").Get(ctx, "vscode", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := RouteReadyStatus(updated); got != step.wantStatus {
+			t.Errorf("step %d: route-ready = %q, want %q", i, got, step.wantStatus)
+		}
+		for _, cond := range updated.Status.Conditions {
+			if cond.Type == ConditionRouteReady && cond.Reason != step.reason {
+				t.Errorf("step %d: reason = %q, want %q", i, cond.Reason, step.reason)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AnnotationDeploymentRevision Deployment 控制器写在 ReplicaSet 上的版本号注解键
+const AnnotationDeploymentRevision = "deployment.kubernetes.io/revision"
+
+// CurrentRevisionLabel 返回工作负载当前版本 Pod 所带的版本标签（键和值）
+// Deployment 取 revision 最大的 ReplicaSet 的 pod-template-hash；
+// StatefulSet 取 status.updateRevision 对应的 controller-revision-hash。
+// 无法确定当前版本时返回空字符串
+func CurrentRevisionLabel(ctx context.Context, client kubernetes.Interface, workload Workload) (string, string, error) {
+	switch w := workload.(type) {
+	case DeploymentWorkload:
+		rsList, err := client.AppsV1().ReplicaSets(w.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: metav1.FormatLabelSelector(w.Spec.Selector),
+		})
+		if err != nil {
+			return "", "", fmt.Errorf("failed to list replicasets of deployment %s/%s: %w", w.Namespace, w.Name, err)
+		}
+
+		var current *appsv1.ReplicaSet
+		var currentRevision int64 = -1
+		for i := range rsList.Items {
+			rs := &rsList.Items[i]
+			if !metav1.IsControlledBy(rs, w.Deployment) {
+				continue
+			}
+			revision, err := strconv.ParseInt(rs.Annotations[AnnotationDeploymentRevision], 10, 64)
+			if err != nil {
+				continue
+			}
+			if revision > currentRevision {
+				current, currentRevision = rs, revision
+			}
+		}
+		if current == nil || current.Labels[appsv1.DefaultDeploymentUniqueLabelKey] == "" {
+			return "", "", nil
+		}
+		return appsv1.DefaultDeploymentUniqueLabelKey, current.Labels[appsv1.DefaultDeploymentUniqueLabelKey], nil
+
+	case StatefulSetWorkload:
+		if w.Status.UpdateRevision == "" {
+			return "", "", nil
+		}
+		return appsv1.ControllerRevisionHashLabelKey, w.Status.UpdateRevision, nil
+
+	default:
+		return "", "", nil
+	}
+}
+
+// SelectRoutablePod 从工作负载的 Pod 中选择路由目标
+// 跳过删除中和不可路由的 Pod；优先选择带有当前版本标签的 Pod，
+// 同等条件下选择创建时间最新的 Pod。滚动更新时新 Pod 就绪前继续使用旧 Pod
+func SelectRoutablePod(pods []corev1.Pod, revisionKey, revisionValue string) *corev1.Pod {
+	return selectPod(pods, revisionKey, revisionValue, IsPodRoutable)
+}
+
+// SelectLatestPod 选择最能代表工作负载当前状态的 Pod（不要求可路由）
+// 跳过删除中的 Pod；优先选择带有当前版本标签的 Pod，同等条件下选择创建时间最新的 Pod
+func SelectLatestPod(pods []corev1.Pod, revisionKey, revisionValue string) *corev1.Pod {
+	return selectPod(pods, revisionKey, revisionValue, func(*corev1.Pod) bool { return true })
+}
+
+// FindRunningPod 返回第一个处于 Running、未被删除且满足 match 的 Pod，找不到时返回 nil
+// 用于 keep_unready_pods 判断路由指向的 Pod 是否仍在运行（不要求 Ready）
+func FindRunningPod(pods []corev1.Pod, match func(*corev1.Pod) bool) *corev1.Pod {
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if match(pod) {
+			return pod
+		}
+	}
+	return nil
+}
+
+// selectPod 从满足 eligible 的 Pod 中优先选择当前版本、其次创建时间最新的 Pod
+func selectPod(pods []corev1.Pod, revisionKey, revisionValue string, eligible func(*corev1.Pod) bool) *corev1.Pod {
+	var selected *corev1.Pod
+	selectedCurrent := false
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil || !eligible(pod) {
+			continue
+		}
+
+		current := revisionKey != "" && pod.Labels[revisionKey] == revisionValue
+		switch {
+		case selected == nil,
+			current && !selectedCurrent,
+			current == selectedCurrent && selected.CreationTimestamp.Before(&pod.CreationTimestamp):
+			selected, selectedCurrent = pod, current
+		}
+	}
+	return selected
+}
@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testPod 构造带有版本标签的 Pod
+func testPod(name, hash string, ready bool, created time.Time) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: hash},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+// TestSelectRoutablePod 测试滚动更新期间的 Pod 选择
+func TestSelectRoutablePod(t *testing.T) {
+	now := time.Now()
+	key := appsv1.DefaultDeploymentUniqueLabelKey
+
+	oldPod := testPod("old", "v1", true, now.Add(-time.Hour))
+	newPod := testPod("new", "v2", true, now)
+	newNotReady := testPod("new", "v2", false, now)
+	terminating := testPod("old", "v1", true, now.Add(-time.Hour))
+	terminating.DeletionTimestamp = &metav1.Time{Time: now}
+
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{"新 Pod 就绪后切换", []corev1.Pod{oldPod, newPod}, "new"},
+		{"新 Pod 未就绪时保留旧 Pod", []corev1.Pod{oldPod, newNotReady}, "old"},
+		{"跳过删除中的 Pod", []corev1.Pod{terminating, newNotReady}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectRoutablePod(tt.pods, key, "v2")
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("SelectRoutablePod() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+
+	// 当前版本未知时选择创建时间最新的就绪 Pod
+	if got := SelectRoutablePod([]corev1.Pod{newPod, oldPod}, "", ""); got == nil || got.Name != "new" {
+		t.Errorf("Expected newest pod without revision, got %v", got)
+	}
+}
+
+// TestFindRunningPod 测试 keep_unready_pods 查找路由指向的运行中 Pod：不要求就绪，跳过未运行和删除中的 Pod
+func TestFindRunningPod(t *testing.T) {
+	now := time.Now()
+	pod := func(name string, phase corev1.PodPhase, deleting bool) corev1.Pod {
+		p := testPod(name, "v1", false, now)
+		p.Status.Phase = phase
+		p.Status.PodIP = "10.0.0." + name
+		if deleting {
+			p.DeletionTimestamp = &metav1.Time{Time: now}
+		}
+		return p
+	}
+	routedTo := func(ip string) func(*corev1.Pod) bool {
+		return func(p *corev1.Pod) bool { return p.Status.PodIP == ip }
+	}
+
+	tests := []struct {
+		name  string
+		pods  []corev1.Pod
+		match func(*corev1.Pod) bool
+		want  string
+	}{
+		{"路由指向的 Pod 运行中但未就绪", []corev1.Pod{pod("1", corev1.PodRunning, false)}, routedTo("10.0.0.1"), "1"},
+		{"路由指向其他 Pod", []corev1.Pod{pod("1", corev1.PodRunning, false)}, routedTo("10.0.0.2"), ""},
+		{"Pod 已失败", []corev1.Pod{pod("1", corev1.PodFailed, false)}, routedTo("10.0.0.1"), ""},
+		{"Pod 仍在等待调度", []corev1.Pod{pod("1", corev1.PodPending, false)}, routedTo("10.0.0.1"), ""},
+		{"Pod 删除中", []corev1.Pod{pod("1", corev1.PodRunning, true)}, routedTo("10.0.0.1"), ""},
+		{"多个 Pod 中选择匹配的", []corev1.Pod{pod("1", corev1.PodRunning, false), pod("2", corev1.PodRunning, false)}, routedTo("10.0.0.2"), "2"},
+		{"没有 Pod", nil, routedTo("10.0.0.1"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindRunningPod(tt.pods, tt.match)
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("FindRunningPod() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ErrResourceNotInstalled 集群中没有安装对应的 CRD（GitspaceRoute、HTTPRoute）
+var ErrResourceNotInstalled = errors.New("resource is not installed in the cluster")
+
+// GitspaceRouteHandler 处理 GitspaceRoute 事件的回调接口
+type GitspaceRouteHandler interface {
+	// OnGitspaceRouteAdd 处理 GitspaceRoute 创建事件
+	OnGitspaceRouteAdd(route *GitspaceRoute) error
+
+	// OnGitspaceRouteUpdate 处理 GitspaceRoute 更新事件
+	OnGitspaceRouteUpdate(oldRoute, newRoute *GitspaceRoute) error
+
+	// OnGitspaceRouteDelete 处理 GitspaceRoute 删除事件
+	OnGitspaceRouteDelete(route *GitspaceRoute) error
+}
+
+// GitspaceRouteWatcher 通过 dynamic informer 监听 GitspaceRoute，不依赖生成的 clientset
+type GitspaceRouteWatcher struct {
+	*dynamicWatcher
+}
+
+// NewGitspaceRouteWatcher 创建新的 GitspaceRouteWatcher
+func NewGitspaceRouteWatcher(
+	client dynamic.Interface,
+	discoveryClient discovery.DiscoveryInterface,
+	namespace string,
+	resyncPeriod time.Duration,
+	handler GitspaceRouteHandler,
+) *GitspaceRouteWatcher {
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if route, ok := toGitspaceRoute(obj); ok {
+				_ = handler.OnGitspaceRouteAdd(route)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldRoute, ok1 := toGitspaceRoute(oldObj)
+			newRoute, ok2 := toGitspaceRoute(newObj)
+			if ok1 && ok2 {
+				_ = handler.OnGitspaceRouteUpdate(oldRoute, newRoute)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if route, ok := toGitspaceRoute(obj); ok {
+				_ = handler.OnGitspaceRouteDelete(route)
+			}
+		},
+	}
+
+	return &GitspaceRouteWatcher{
+		dynamicWatcher: newDynamicWatcher(client, discoveryClient, GitspaceRouteGVR, namespace, resyncPeriod, handlers),
+	}
+}
+
+// List 从 Informer 缓存中列出所有 GitspaceRoute，监听器未就绪时返回空列表
+func (w *GitspaceRouteWatcher) List() ([]*GitspaceRoute, error) {
+	objs, err := w.listObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]*GitspaceRoute, 0, len(objs))
+	for _, obj := range objs {
+		route, err := GitspaceRouteFromUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// toGitspaceRoute 将 Informer 对象转换为 GitspaceRoute，支持 DeletedFinalStateUnknown
+func toGitspaceRoute(obj any) (*GitspaceRoute, bool) {
+	u, ok := toUnstructured(obj)
+	if !ok {
+		return nil, false
+	}
+	route, err := GitspaceRouteFromUnstructured(u)
+	if err != nil {
+		return nil, false
+	}
+	return route, true
+}
+
+// dynamicWatcher 通过 dynamic informer 监听单一资源
+// 启动前通过 discovery 检查资源是否存在，未安装 CRD 时不启动 informer
+type dynamicWatcher struct {
+	gvr             schema.GroupVersionResource
+	discovery       discovery.DiscoveryInterface
+	namespace       string
+	informerFactory dynamicinformer.DynamicSharedInformerFactory
+	handlers        cache.ResourceEventHandler
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+	ready           bool
+	readyMu         sync.RWMutex
+}
+
+// newDynamicWatcher 创建新的 dynamicWatcher
+func newDynamicWatcher(
+	client dynamic.Interface,
+	discoveryClient discovery.DiscoveryInterface,
+	gvr schema.GroupVersionResource,
+	namespace string,
+	resyncPeriod time.Duration,
+	handlers cache.ResourceEventHandler,
+) *dynamicWatcher {
+	return &dynamicWatcher{
+		gvr:             gvr,
+		discovery:       discoveryClient,
+		namespace:       namespace,
+		informerFactory: dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, resyncPeriod, namespace, nil),
+		handlers:        handlers,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start 启动监听器
+// 资源未安装时返回 ErrResourceNotInstalled，否则阻塞直到 context 取消
+func (w *dynamicWatcher) Start(ctx context.Context) error {
+	installed, err := w.resourceInstalled()
+	if err != nil {
+		return err
+	}
+	if !installed {
+		return fmt.Errorf("%w: %s", ErrResourceNotInstalled, w.gvr)
+	}
+
+	informer := w.informerFactory.ForResource(w.gvr).Informer()
+	informer.AddEventHandler(w.handlers)
+
+	w.informerFactory.Start(w.stopCh)
+
+	// 等待缓存同步
+	syncCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to sync %s informer cache", w.gvr.Resource)
+	}
+
+	w.readyMu.Lock()
+	w.ready = true
+	w.readyMu.Unlock()
+
+	// 阻塞直到停止信号
+	select {
+	case <-ctx.Done():
+		w.Stop()
+		return nil
+	case <-w.stopCh:
+		return nil
+	}
+}
+
+// Stop 停止监听器，并等待 Informer goroutine 退出
+func (w *dynamicWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		w.informerFactory.Shutdown()
+	})
+	w.readyMu.Lock()
+	w.ready = false
+	w.readyMu.Unlock()
+}
+
+// IsReady 返回监听器是否已准备好（informer 已同步）
+func (w *dynamicWatcher) IsReady() bool {
+	w.readyMu.RLock()
+	defer w.readyMu.RUnlock()
+	return w.ready
+}
+
+// listObjects 从 Informer 缓存中列出所有对象，监听器未就绪时返回空列表
+func (w *dynamicWatcher) listObjects() ([]*unstructured.Unstructured, error) {
+	if !w.IsReady() {
+		return nil, nil
+	}
+
+	objs, err := w.informerFactory.ForResource(w.gvr).Lister().ByNamespace(w.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			result = append(result, u)
+		}
+	}
+	return result, nil
+}
+
+// resourceInstalled 通过 discovery 检查资源是否已安装
+func (w *dynamicWatcher) resourceInstalled() (bool, error) {
+	resources, err := w.discovery.ServerResourcesForGroupVersion(w.gvr.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to discover %s: %w", w.gvr.GroupVersion(), err)
+	}
+
+	for _, resource := range resources.APIResources {
+		if resource.Name == w.gvr.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// toUnstructured 将 dynamic informer 对象转换为 Unstructured，支持 DeletedFinalStateUnknown
+func toUnstructured(obj any) (*unstructured.Unstructured, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	return u, ok
+}
@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// gitspace 所处阶段
+const (
+	GitspaceStarting = "starting"
+	GitspaceStopped  = "stopped"
+	GitspaceFailed   = "failed"
+)
+
+// statefulSetReplicaFailure StatefulSet 创建或删除 Pod 失败时的条件类型（与 Deployment 一致）
+const statefulSetReplicaFailure appsv1.StatefulSetConditionType = "ReplicaFailure"
+
+// failedWaitingReasons 表示容器无法自行恢复启动的等待原因
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// GitspaceState 根据工作负载状态和其当前 Pod 判断 gitspace 所处阶段
+// pod 为工作负载当前版本最新的 Pod（独立 Pod 为其本身，没有时为 nil）。
+// 独立 Pod 通过 Failed 阶段、Deployment 通过 ReplicaFailure 和 Progressing 条件、
+// StatefulSet 通过 ReplicaFailure 条件识别启动失败；
+// 容器处于 CrashLoopBackOff、ImagePullBackOff 等等待状态时同样视为失败
+func GitspaceState(workload Workload, pod *corev1.Pod) (state, reason, message string) {
+	if w, ok := workload.(PodWorkload); ok {
+		if w.Status.Phase == corev1.PodFailed {
+			return GitspaceFailed, w.Status.Reason, w.Status.Message
+		}
+		pod = w.Pod
+	}
+
+	if workload.DesiredReplicas() == 0 {
+		return GitspaceStopped, "", ""
+	}
+
+	switch w := workload.(type) {
+	case DeploymentWorkload:
+		for _, cond := range w.Status.Conditions {
+			switch {
+			case cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue:
+				return GitspaceFailed, cond.Reason, cond.Message
+			case cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse:
+				return GitspaceFailed, cond.Reason, cond.Message
+			}
+		}
+	case StatefulSetWorkload:
+		for _, cond := range w.Status.Conditions {
+			if cond.Type == statefulSetReplicaFailure && cond.Status == corev1.ConditionTrue {
+				return GitspaceFailed, cond.Reason, cond.Message
+			}
+		}
+	}
+
+	if pod != nil {
+		if reason, message, failed := podWaitingFailure(pod); failed {
+			return GitspaceFailed, reason, message
+		}
+	}
+
+	// 副本数为 1 但未就绪，或已就绪但路由尚未写入
+	return GitspaceStarting, "", ""
+}
+
+// podWaitingFailure 返回 Pod 中第一个处于失败等待状态的容器（含 init 容器）的原因
+func podWaitingFailure(pod *corev1.Pod) (reason, message string, failed bool) {
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			if waiting := status.State.Waiting; waiting != nil && failedWaitingReasons[waiting.Reason] {
+				return waiting.Reason, waiting.Message, true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// waitingPod 构造容器处于等待状态的 Pod
+func waitingPod(reason, message string) *corev1.Pod {
+	return &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "ide",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}},
+			}},
+		},
+	}
+}
+
+// TestGitspaceState 测试根据工作负载和当前 Pod 判断 gitspace 阶段
+func TestGitspaceState(t *testing.T) {
+	one, zero := int32(1), int32(0)
+
+	deployment := func(replicas *int32, conditions ...appsv1.DeploymentCondition) Workload {
+		return DeploymentWorkload{&appsv1.Deployment{
+			Spec:   appsv1.DeploymentSpec{Replicas: replicas},
+			Status: appsv1.DeploymentStatus{Conditions: conditions},
+		}}
+	}
+	statefulSet := func(conditions ...appsv1.StatefulSetCondition) Workload {
+		return StatefulSetWorkload{&appsv1.StatefulSet{
+			Spec:   appsv1.StatefulSetSpec{Replicas: &one},
+			Status: appsv1.StatefulSetStatus{Conditions: conditions},
+		}}
+	}
+
+	initFailure := &corev1.Pod{Status: corev1.PodStatus{
+		InitContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "init",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "not found"}},
+		}},
+	}}
+	crashingStandalone := waitingPod("CrashLoopBackOff", "back-off restarting")
+	failedStandalone := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted", Message: "low memory"}}
+
+	tests := []struct {
+		name        string
+		workload    Workload
+		pod         *corev1.Pod
+		wantState   string
+		wantReason  string
+		wantMessage string
+	}{
+		{"缩容到 0", deployment(&zero), nil, GitspaceStopped, "", ""},
+		{"缩容到 0 时忽略遗留 Pod 的失败", deployment(&zero), waitingPod("CrashLoopBackOff", ""), GitspaceStopped, "", ""},
+		{"启动中", deployment(&one), waitingPod("ContainerCreating", ""), GitspaceStarting, "", ""},
+		{"没有 Pod 时启动中", deployment(&one), nil, GitspaceStarting, "", ""},
+		{
+			"Deployment ReplicaFailure",
+			deployment(&one, appsv1.DeploymentCondition{Type: appsv1.DeploymentReplicaFailure, Status: corev1.ConditionTrue, Reason: "FailedCreate", Message: "quota exceeded"}),
+			nil, GitspaceFailed, "FailedCreate", "quota exceeded",
+		},
+		{
+			"Deployment 超过进度期限",
+			deployment(&one, appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"}),
+			nil, GitspaceFailed, "ProgressDeadlineExceeded", "",
+		},
+		{"Deployment Pod CrashLoopBackOff", deployment(&one), waitingPod("CrashLoopBackOff", "back-off 5m0s"), GitspaceFailed, "CrashLoopBackOff", "back-off 5m0s"},
+		{"StatefulSet Pod ImagePullBackOff", statefulSet(), waitingPod("ImagePullBackOff", "pull access denied"), GitspaceFailed, "ImagePullBackOff", "pull access denied"},
+		{"init 容器拉取镜像失败", statefulSet(), initFailure, GitspaceFailed, "ErrImagePull", "not found"},
+		{
+			"StatefulSet ReplicaFailure",
+			statefulSet(appsv1.StatefulSetCondition{Type: "ReplicaFailure", Status: corev1.ConditionTrue, Reason: "FailedCreate", Message: "forbidden"}),
+			nil, GitspaceFailed, "FailedCreate", "forbidden",
+		},
+		{"StatefulSet 启动中", statefulSet(), waitingPod("PodInitializing", ""), GitspaceStarting, "", ""},
+		{"独立 Pod 失败", PodWorkload{failedStandalone}, nil, GitspaceFailed, "Evicted", "low memory"},
+		{"独立 Pod CrashLoopBackOff", PodWorkload{crashingStandalone}, nil, GitspaceFailed, "CrashLoopBackOff", "back-off restarting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, reason, message := GitspaceState(tt.workload, tt.pod)
+			if state != tt.wantState || reason != tt.wantReason || message != tt.wantMessage {
+				t.Errorf("GitspaceState() = (%q, %q, %q), want (%q, %q, %q)",
+					state, reason, message, tt.wantState, tt.wantReason, tt.wantMessage)
+			}
+		})
+	}
+}
+
+// TestSelectLatestPod 测试占位页面选择代表工作负载状态的 Pod
+func TestSelectLatestPod(t *testing.T) {
+	now := time.Now()
+	key := appsv1.DefaultDeploymentUniqueLabelKey
+
+	oldReady := testPod("old", "v1", true, now.Add(-time.Hour))
+	newCrashing := testPod("new", "v2", false, now.Add(-time.Minute))
+	terminating := testPod("terminating", "v2", false, now)
+	terminating.DeletionTimestamp = &metav1.Time{Time: now}
+
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{"当前版本未就绪的 Pod 优先于旧版本", []corev1.Pod{oldReady, newCrashing}, "new"},
+		{"跳过删除中的 Pod", []corev1.Pod{terminating, newCrashing}, "new"},
+		{"没有 Pod", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectLatestPod(tt.pods, key, "v2")
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("SelectLatestPod() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
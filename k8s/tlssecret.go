@@ -0,0 +1,78 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// 上游证书 Secret 的键，与 kubernetes.io/tls 类型一致，另附校验 Caddy 客户端证书的 CA
+const (
+	// UpstreamTLSCAKey pki CA 根证书（PEM），gitspace 用它校验 Caddy 的客户端证书
+	UpstreamTLSCAKey = "ca.crt"
+
+	// UpstreamTLSCertKey gitspace 证书和中间证书（PEM），SAN 为 gitspace identifier
+	UpstreamTLSCertKey = corev1.TLSCertKey
+
+	// UpstreamTLSKeyKey gitspace 证书私钥（PEM）
+	UpstreamTLSKeyKey = corev1.TLSPrivateKeyKey
+)
+
+// UpstreamTLSSecretName 返回 gitspace 上游证书 Secret 的名称（<identifier>-gitspace-tls）
+func UpstreamTLSSecretName(identifier string) string {
+	return identifier + "-gitspace-tls"
+}
+
+// GetUpstreamTLSSecret 读取工作负载命名空间中的上游证书 Secret，不存在时返回 nil
+func GetUpstreamTLSSecret(ctx context.Context, client kubernetes.Interface, namespace, name string) (*corev1.Secret, error) {
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+	return secret, nil
+}
+
+// ApplyUpstreamTLSSecret 通过 Server-Side Apply 写入上游证书 Secret
+// Secret 的 owner 为工作负载，工作负载删除后由垃圾回收一并删除
+func ApplyUpstreamTLSSecret(ctx context.Context, client kubernetes.Interface, workload Workload, name string, data map[string][]byte) error {
+	apiVersion := "apps/v1"
+	if workload.Kind() == KindPod {
+		apiVersion = "v1"
+	}
+
+	applyBytes, err := json.Marshal(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": workload.GetNamespace(),
+			"ownerReferences": []metav1.OwnerReference{{
+				APIVersion: apiVersion,
+				Kind:       workload.Kind(),
+				Name:       workload.GetName(),
+				UID:        workload.GetUID(),
+			}},
+		},
+		"type": corev1.SecretTypeTLS,
+		"data": data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret: %w", err)
+	}
+
+	force := true
+	opts := metav1.PatchOptions{FieldManager: FieldManager, Force: &force}
+	if _, err := client.CoreV1().Secrets(workload.GetNamespace()).Patch(ctx, name, types.ApplyPatchType, applyBytes, opts); err != nil {
+		return fmt.Errorf("failed to apply secret %s/%s: %w", workload.GetNamespace(), name, err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package k8s
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -16,11 +17,68 @@ const (
 	// AnnotationURL 路由创建成功后写回的域名注解键
 	AnnotationURL = "gitspace.caddy.route.url"
 
-	// AnnotationSynced 路由同步时间戳注解键
+	// AnnotationSynced 路由最近一次变化（域名、路由 ID 或上游地址）的时间戳注解键
 	AnnotationSynced = "gitspace.caddy.route.synced-at"
 
 	// AnnotationRouteID 路由 ID 注解键
 	AnnotationRouteID = "gitspace.caddy.route.id"
+
+	// AnnotationTarget 路由当前指向的上游地址注解键
+	AnnotationTarget = "gitspace.caddy.route.target"
+
+	// AnnotationRouteMessage 路由状态为 Failed 时的原因注解键
+	AnnotationRouteMessage = "gitspace.caddy.route.message"
+
+	// AnnotationRouteStatus 路由状态注解键（RouteStatusReady、RouteStatusDegraded、RouteStatusFailed）
+	AnnotationRouteStatus = "gitspace.caddy.route.status"
+
+	// AnnotationAutowake 缩容到 0 时保留路由、首个请求到达时自动唤醒的注解键
+	AnnotationAutowake = "gitspace.caddy.autowake"
+
+	// AnnotationLastActivity 最近一次请求或活跃连接时间的注解键（RFC3339）
+	AnnotationLastActivity = "gitspace.caddy.last-activity"
+
+	// AnnotationIdleTimeout 空闲多久后自动缩容到 0 的注解键（Go duration 格式）
+	AnnotationIdleTimeout = "gitspace.caddy.idle-timeout"
+)
+
+// routeSettingAnnotations 影响路由配置（而不是上游地址）的注解，变化后需要重新写入路由
+var routeSettingAnnotations = []string{
+	AnnotationAllowCIDRs,
+	AnnotationRequireClientCert,
+	AnnotationRateLimitClient,
+	AnnotationRateLimitRoute,
+	AnnotationBandwidthLimit,
+	AnnotationHealthPath,
+	AnnotationHealthInterval,
+	AnnotationHealthStatus,
+	AnnotationHealthFailDuration,
+	AnnotationHealthUnhealthyStatus,
+}
+
+// RouteSettingsChanged 判断两组注解中影响路由配置的注解是否有变化
+func RouteSettingsChanged(oldAnnotations, newAnnotations map[string]string) bool {
+	for _, key := range routeSettingAnnotations {
+		if oldAnnotations[key] != newAnnotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// FinalizerRouteCleanup 保证删除工作负载前先删除路由的 finalizer
+const FinalizerRouteCleanup = "gitspace.app.io/route-cleanup"
+
+// 路由状态
+const (
+	// RouteStatusReady 路由已写入 Caddy
+	RouteStatusReady = "Ready"
+
+	// RouteStatusDegraded 路由指向运行中但暂时未就绪的 Pod（keep_unready_pods）
+	RouteStatusDegraded = "Degraded"
+
+	// RouteStatusFailed 路由无法写入（例如 identifier 已被其他工作负载占用）
+	RouteStatusFailed = "Failed"
 )
 
 // isPodReady 检查 Pod 是否就绪
@@ -33,24 +91,48 @@ func IsPodReady(pod *corev1.Pod) bool {
 	return false
 }
 
-// getPortFromAnnotation 从 Deployment 注解中读取端口
-// 如果注解不存在或无效，返回 defaultPort
-func GetPortFromAnnotation(annotations map[string]string, defaultPort int) (int, error) {
-	portStr, exists := annotations[AnnotationPort]
+// IsAutowakeEnabled 检查 Deployment 是否开启了按请求唤醒
+// 注解值按 strconv.ParseBool 解析，无法解析时视为未开启
+func IsAutowakeEnabled(annotations map[string]string) bool {
+	value, exists := annotations[AnnotationAutowake]
 	if !exists {
-		return defaultPort, nil
+		return false
 	}
+	enabled, err := strconv.ParseBool(value)
+	return err == nil && enabled
+}
 
-	port, err := strconv.Atoi(portStr)
-	if err != nil {
-		return 0, fmt.Errorf("invalid port annotation '%s': %w", portStr, err)
+// GetIdleTimeout 从注解中读取空闲超时
+// 注解不存在时返回 0, nil；格式无效或非正数时返回错误
+func GetIdleTimeout(annotations map[string]string) (time.Duration, error) {
+	value, exists := annotations[AnnotationIdleTimeout]
+	if !exists {
+		return 0, nil
 	}
 
-	if port < 1 || port > 65535 {
-		return 0, fmt.Errorf("port out of range (1-65535): %d", port)
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid idle-timeout annotation '%s': %w", value, err)
 	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("idle-timeout must be positive: %s", value)
+	}
+
+	return timeout, nil
+}
 
-	return port, nil
+// GetLastActivity 从注解中读取最近活动时间
+// 注解不存在或无法解析时 ok 为 false
+func GetLastActivity(annotations map[string]string) (last time.Time, ok bool) {
+	value, exists := annotations[AnnotationLastActivity]
+	if !exists {
+		return time.Time{}, false
+	}
+	last, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return last, true
 }
 
 // DesiredReplicaCount 返回 Deployment 期望的副本数量。
@@ -61,20 +143,3 @@ func DesiredReplicaCount(deployment *appsv1.Deployment) int32 {
 	}
 	return *deployment.Spec.Replicas
 }
-
-// GetGitspaceIdentifier 从 Deployment labels 中提取 gitspace identifier
-// 这是稳定的配置级别标识符，不同于可能包含实例后缀的 deployment name
-// 如果 label 不存在，返回空字符串
-func GetGitspaceIdentifier(deployment *appsv1.Deployment) string {
-	if deployment == nil {
-		return ""
-	}
-
-	// 从 label 中获取 gitspace identifier
-	if identifier, exists := deployment.Labels["gitspace"]; exists && identifier != "" {
-		return identifier
-	}
-
-	// label 不存在，返回空字符串
-	return ""
-}
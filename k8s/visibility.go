@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 路由可见性
+const (
+	// VisibilityPrivate 仅 gitspace 所有者可以访问
+	VisibilityPrivate = "private"
+
+	// VisibilityOrg 任何已认证用户可以访问
+	VisibilityOrg = "org"
+
+	// VisibilityPublic 无需认证即可访问
+	VisibilityPublic = "public"
+)
+
+// 访问控制注解
+const (
+	// AnnotationVisibility gitspace 主域名的可见性注解键
+	AnnotationVisibility = "gitspace.caddy.visibility"
+
+	// AnnotationPortVisibility 按需端口转发的端口可见性注解键（如 "3000=public,8080=org"）
+	AnnotationPortVisibility = "gitspace.caddy.ports.visibility"
+
+	// AnnotationShareNonce 分享令牌的 nonce 注解键，修改该值会吊销已签发的所有分享令牌
+	AnnotationShareNonce = "gitspace.caddy.share-nonce"
+)
+
+// 访问控制标签
+const (
+	// LabelOwner gitspace 所有者的用户名
+	LabelOwner = "gitspace.app.io/owner"
+
+	// LabelGroups 可以访问 private gitspace 的用户组，多个用户组用 "." 分隔（标签值不允许逗号）
+	LabelGroups = "gitspace.app.io/groups"
+)
+
+// IsGitspaceMember 用户是否为 gitspace 的所有者，或属于 gitspace 声明的用户组
+func IsGitspaceMember(labels map[string]string, user string, groups []string) bool {
+	if owner := labels[LabelOwner]; owner != "" && owner == user {
+		return true
+	}
+	for _, allowed := range strings.Split(labels[LabelGroups], ".") {
+		if allowed != "" && slices.Contains(groups, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidVisibility 是否为合法的可见性
+func IsValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPrivate, VisibilityOrg, VisibilityPublic:
+		return true
+	default:
+		return false
+	}
+}
+
+// WorkloadVisibility 返回 gitspace 主域名的可见性，未设置注解时返回 defaultVisibility
+func WorkloadVisibility(annotations map[string]string, defaultVisibility string) (string, error) {
+	value, exists := annotations[AnnotationVisibility]
+	if !exists {
+		return defaultVisibility, nil
+	}
+	if !IsValidVisibility(value) {
+		return "", fmt.Errorf("invalid visibility annotation %q", value)
+	}
+	return value, nil
+}
+
+// ParsePortVisibility 解析逗号分隔的 "<port>=<visibility>" 列表
+func ParsePortVisibility(value string) (map[int]string, error) {
+	result := make(map[int]string)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		portStr, visibility, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid port visibility %q: expected <port>=<visibility>", field)
+		}
+		port, err := strconv.Atoi(strings.TrimSpace(portStr))
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port in %q", field)
+		}
+		visibility = strings.TrimSpace(visibility)
+		if !IsValidVisibility(visibility) {
+			return nil, fmt.Errorf("invalid visibility %q for port %d", visibility, port)
+		}
+		result[port] = visibility
+	}
+	return result, nil
+}
+
+// PortVisibility 返回工作负载上指定端口的可见性，注解未声明该端口时返回 defaultVisibility
+func PortVisibility(annotations map[string]string, port int, defaultVisibility string) (string, error) {
+	value, exists := annotations[AnnotationPortVisibility]
+	if !exists {
+		return defaultVisibility, nil
+	}
+	visibilities, err := ParsePortVisibility(value)
+	if err != nil {
+		return "", err
+	}
+	if visibility, ok := visibilities[port]; ok {
+		return visibility, nil
+	}
+	return defaultVisibility, nil
+}
@@ -0,0 +1,61 @@
+package k8s
+
+import "testing"
+
+// TestPortVisibility 测试端口可见性注解解析
+func TestPortVisibility(t *testing.T) {
+	annotations := map[string]string{AnnotationPortVisibility: "3000=public, 8080=org"}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		port        int
+		want        string
+		wantErr     bool
+	}{
+		{"无注解使用默认可见性", nil, 3000, VisibilityPrivate, false},
+		{"声明为 public", annotations, 3000, VisibilityPublic, false},
+		{"声明为 org", annotations, 8080, VisibilityOrg, false},
+		{"未声明的端口使用默认可见性", annotations, 9000, VisibilityPrivate, false},
+		{"无效可见性", map[string]string{AnnotationPortVisibility: "3000=everyone"}, 3000, "", true},
+		{"缺少端口", map[string]string{AnnotationPortVisibility: "public"}, 3000, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PortVisibility(tt.annotations, tt.port, VisibilityPrivate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PortVisibility() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PortVisibility() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsGitspaceMember 测试所有者和用户组授权
+func TestIsGitspaceMember(t *testing.T) {
+	labels := map[string]string{LabelOwner: "alice", LabelGroups: "platform.qa"}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		user   string
+		groups []string
+		want   bool
+	}{
+		{"所有者", labels, "alice", nil, true},
+		{"属于声明的用户组", labels, "bob", []string{"dev", "qa"}, true},
+		{"不属于任何用户组", labels, "bob", []string{"dev"}, false},
+		{"未声明所有者和用户组", nil, "", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsGitspaceMember(tt.labels, tt.user, tt.groups); got != tt.want {
+				t.Errorf("IsGitspaceMember() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,22 +7,29 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
 
 // EventHandler 处理 Kubernetes 事件的回调接口
+// Deployment、StatefulSet、独立 Pod 事件统一转换为 Workload 后回调
 type EventHandler interface {
-	// OnDeploymentAdd 处理 Deployment 创建事件
-	OnDeploymentAdd(deployment *appsv1.Deployment) error
+	// OnWorkloadAdd 处理工作负载创建事件
+	OnWorkloadAdd(workload Workload) error
 
-	// OnDeploymentUpdate 处理 Deployment 更新事件
-	OnDeploymentUpdate(oldDeployment, newDeployment *appsv1.Deployment) error
+	// OnWorkloadUpdate 处理工作负载更新事件
+	OnWorkloadUpdate(oldWorkload, newWorkload Workload) error
 
-	// OnDeploymentDelete 处理 Deployment 删除事件
-	OnDeploymentDelete(deployment *appsv1.Deployment) error
+	// OnWorkloadDelete 处理工作负载删除事件
+	OnWorkloadDelete(workload Workload) error
+
+	// OnGatedPodUpdate 处理声明了 route-ready readiness gate 的受管理 Pod 的变化
+	// 这类 Pod 在路由写入前不会 Ready，所属工作负载的状态不会随容器就绪而变化
+	OnGatedPodUpdate(pod *corev1.Pod) error
 }
 
 // Watcher 监听 Kubernetes 资源变化
@@ -82,12 +89,20 @@ func (w *Watcher) Start(ctx context.Context) error {
 	// 创建 Deployment Informer
 	deploymentInformer := w.informerFactory.Apps().V1().Deployments().Informer()
 
-	// 创建 Pod Informer
+	// 创建 StatefulSet Informer
+	statefulSetInformer := w.informerFactory.Apps().V1().StatefulSets().Informer()
+
+	// 创建 Pod Informer（独立 Pod、Job Pod 直接作为路由来源）
 	podInformer := w.informerFactory.Core().V1().Pods().Informer()
 
-	// 注册事件处理器（将在 T019 中实现）
-	w.registerDeploymentHandlers(deploymentInformer)
-	w.registerPodHandlers(podInformer)
+	// 注册事件处理器
+	w.registerWorkloadHandlers(deploymentInformer)
+	w.registerWorkloadHandlers(statefulSetInformer)
+	w.registerWorkloadHandlers(podInformer)
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    w.handleGatedPodAdd,
+		UpdateFunc: w.handleGatedPodUpdate,
+	})
 
 	// 启动 Informers
 	w.informerFactory.Start(w.stopCh)
@@ -99,6 +114,7 @@ func (w *Watcher) Start(ctx context.Context) error {
 	if !cache.WaitForCacheSync(
 		syncCtx.Done(),
 		deploymentInformer.HasSynced,
+		statefulSetInformer.HasSynced,
 		podInformer.HasSynced,
 	) {
 		return fmt.Errorf("failed to sync informer caches")
@@ -119,10 +135,11 @@ func (w *Watcher) Start(ctx context.Context) error {
 	}
 }
 
-// Stop 停止监听器
+// Stop 停止监听器，并等待所有 Informer goroutine（包括进行中的事件回调）退出
 func (w *Watcher) Stop() {
 	w.stopOnce.Do(func() {
 		close(w.stopCh)
+		w.informerFactory.Shutdown()
 	})
 	w.readyMu.Lock()
 	w.ready = false
@@ -136,73 +153,179 @@ func (w *Watcher) IsReady() bool {
 	return w.ready
 }
 
-// registerDeploymentHandlers 注册 Deployment 事件处理器
-func (w *Watcher) registerDeploymentHandlers(informer cache.SharedIndexInformer) {
+// GetWorkload 从 Informer 缓存中读取指定类型的工作负载
+// 缓存未就绪或对象不存在时返回错误
+func (w *Watcher) GetWorkload(kind, namespace, name string) (Workload, error) {
+	switch kind {
+	case KindDeployment:
+		deployment, err := w.informerFactory.Apps().V1().Deployments().Lister().Deployments(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return DeploymentWorkload{deployment}, nil
+
+	case KindStatefulSet:
+		statefulSet, err := w.informerFactory.Apps().V1().StatefulSets().Lister().StatefulSets(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return StatefulSetWorkload{statefulSet}, nil
+
+	case KindPod:
+		pod, err := w.informerFactory.Core().V1().Pods().Lister().Pods(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return PodWorkload{pod}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+}
+
+// ListWorkloads 从 Informer 缓存中列出所有受管理的工作负载
+func (w *Watcher) ListWorkloads() ([]Workload, error) {
+	deployments, err := w.informerFactory.Apps().V1().Deployments().Lister().Deployments(w.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	statefulSets, err := w.informerFactory.Apps().V1().StatefulSets().Lister().StatefulSets(w.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := w.informerFactory.Core().V1().Pods().Lister().Pods(w.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := make([]Workload, 0, len(deployments)+len(statefulSets))
+	for _, deployment := range deployments {
+		workloads = append(workloads, DeploymentWorkload{deployment})
+	}
+	for _, statefulSet := range statefulSets {
+		workloads = append(workloads, StatefulSetWorkload{statefulSet})
+	}
+	for _, pod := range pods {
+		if IsStandalonePod(pod) {
+			workloads = append(workloads, PodWorkload{pod})
+		}
+	}
+
+	return workloads, nil
+}
+
+// registerWorkloadHandlers 注册工作负载（Deployment、StatefulSet、独立 Pod）事件处理器
+func (w *Watcher) registerWorkloadHandlers(informer cache.SharedIndexInformer) {
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    w.handleDeploymentAdd,
-		UpdateFunc: w.handleDeploymentUpdate,
-		DeleteFunc: w.handleDeploymentDelete,
+		AddFunc:    w.handleWorkloadAdd,
+		UpdateFunc: w.handleWorkloadUpdate,
+		DeleteFunc: w.handleWorkloadDelete,
 	})
 }
 
-// registerPodHandlers 注册 Pod 事件处理器
-// 注意：不再监听 Pod 事件，只通过 Deployment 状态查询 Pod
-func (w *Watcher) registerPodHandlers(informer cache.SharedIndexInformer) {
-	// 不注册 Pod 事件处理器
-	// Pod 的状态变化会反映在 Deployment 的 Ready 状态中
+// toWorkload 将 Informer 对象转换为 Workload，支持 DeletedFinalStateUnknown
+// 由 Deployment、StatefulSet 管理的 Pod 不是独立的路由来源，返回 false
+func toWorkload(obj any) (Workload, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return DeploymentWorkload{o}, true
+	case *appsv1.StatefulSet:
+		return StatefulSetWorkload{o}, true
+	case *corev1.Pod:
+		if !IsStandalonePod(o) {
+			return nil, false
+		}
+		return PodWorkload{o}, true
+	default:
+		return nil, false
+	}
 }
 
-// handleDeploymentAdd 处理 Deployment 创建事件
-func (w *Watcher) handleDeploymentAdd(obj any) {
-	deployment, ok := obj.(*appsv1.Deployment)
+// handleWorkloadAdd 处理工作负载创建事件
+func (w *Watcher) handleWorkloadAdd(obj any) {
+	workload, ok := toWorkload(obj)
 	if !ok {
 		return
 	}
 
-	// 只处理单副本 Deployment
-	if DesiredReplicaCount(deployment) != 1 {
+	if !wantsWorkloadAdd(workload) {
 		return
 	}
 
 	// 调用 EventHandler 处理
-	// EventHandler 会检查 Deployment 是否就绪，并查询 Pod IP
-	if err := w.eventHandler.OnDeploymentAdd(deployment); err != nil {
+	// EventHandler 会检查工作负载是否就绪，并查询 Pod IP
+	if err := w.eventHandler.OnWorkloadAdd(workload); err != nil {
 		// 错误已由 EventHandler 记录
 		return
 	}
 }
 
-// handleDeploymentUpdate 处理 Deployment 更新事件
-func (w *Watcher) handleDeploymentUpdate(oldObj, newObj any) {
-	oldDeployment, ok1 := oldObj.(*appsv1.Deployment)
-	newDeployment, ok2 := newObj.(*appsv1.Deployment)
+// wantsWorkloadAdd 判断工作负载创建事件是否需要交给 EventHandler
+// 处理单副本工作负载、开启了自动唤醒的 0 副本工作负载，
+// 以及任意副本数下等待路由清理 finalizer 的删除中工作负载（例如重启后首次同步）
+func wantsWorkloadAdd(workload Workload) bool {
+	if IsFinalizing(workload) {
+		return true
+	}
+	replicas := workload.DesiredReplicas()
+	return replicas == 1 || (replicas == 0 && IsAutowakeEnabled(workload.GetAnnotations()))
+}
+
+// handleWorkloadUpdate 处理工作负载更新事件
+func (w *Watcher) handleWorkloadUpdate(oldObj, newObj any) {
+	oldWorkload, ok1 := toWorkload(oldObj)
+	newWorkload, ok2 := toWorkload(newObj)
 	if !ok1 || !ok2 {
 		return
 	}
 
 	// 调用 EventHandler 处理所有更新
 	// EventHandler 会根据副本数、就绪状态等决定是创建、更新还是删除路由
-	if err := w.eventHandler.OnDeploymentUpdate(oldDeployment, newDeployment); err != nil {
+	if err := w.eventHandler.OnWorkloadUpdate(oldWorkload, newWorkload); err != nil {
 		return
 	}
 }
 
-// handleDeploymentDelete 处理 Deployment 删除事件
-func (w *Watcher) handleDeploymentDelete(obj any) {
-	deployment, ok := obj.(*appsv1.Deployment)
+// handleWorkloadDelete 处理工作负载删除事件
+func (w *Watcher) handleWorkloadDelete(obj any) {
+	workload, ok := toWorkload(obj)
 	if !ok {
-		// 处理 DeletedFinalStateUnknown 情况
-		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
-		if !ok {
-			return
-		}
-		deployment, ok = tombstone.Obj.(*appsv1.Deployment)
-		if !ok {
-			return
-		}
+		return
 	}
 
-	if err := w.eventHandler.OnDeploymentDelete(deployment); err != nil {
+	if err := w.eventHandler.OnWorkloadDelete(workload); err != nil {
+		return
+	}
+}
+
+// handleGatedPodAdd 处理声明了 route-ready readiness gate 的受管理 Pod 创建事件
+func (w *Watcher) handleGatedPodAdd(obj any) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || IsStandalonePod(pod) || !PodHasRouteReadinessGate(&pod.Spec) {
+		return
+	}
+	if !IsPodRoutable(pod) {
+		return
+	}
+	_ = w.eventHandler.OnGatedPodUpdate(pod)
+}
+
+// handleGatedPodUpdate 处理声明了 route-ready readiness gate 的受管理 Pod 更新事件
+// 只在可路由状态或 Pod IP 变化时回调
+func (w *Watcher) handleGatedPodUpdate(oldObj, newObj any) {
+	oldPod, ok1 := oldObj.(*corev1.Pod)
+	newPod, ok2 := newObj.(*corev1.Pod)
+	if !ok1 || !ok2 || IsStandalonePod(newPod) || !PodHasRouteReadinessGate(&newPod.Spec) {
+		return
+	}
+	if IsPodRoutable(oldPod) == IsPodRoutable(newPod) && oldPod.Status.PodIP == newPod.Status.PodIP {
 		return
 	}
+	_ = w.eventHandler.OnGatedPodUpdate(newPod)
 }
@@ -0,0 +1,258 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// 工作负载类型
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindPod         = "Pod"
+)
+
+// Workload 路由来源工作负载的统一抽象
+// 屏蔽 Deployment、StatefulSet、裸 Pod 在副本数、就绪状态和 Pod 选择器上的差异，
+// labels、annotations 等元数据通过 metav1.Object 访问
+type Workload interface {
+	metav1.Object
+
+	// Kind 返回工作负载类型（KindDeployment、KindStatefulSet、KindPod）
+	Kind() string
+
+	// DesiredReplicas 返回期望的副本数，spec.replicas 为空时为 1
+	DesiredReplicas() int32
+
+	// IsReady 判断工作负载是否已有可用副本
+	IsReady() bool
+
+	// PodSelector 返回选择工作负载 Pod 的 label selector
+	PodSelector() *metav1.LabelSelector
+
+	// Object 返回底层的 Kubernetes 对象（用于记录事件）
+	Object() runtime.Object
+}
+
+// DeploymentWorkload 将 Deployment 适配为 Workload
+type DeploymentWorkload struct {
+	*appsv1.Deployment
+}
+
+// Kind 返回 KindDeployment
+func (w DeploymentWorkload) Kind() string { return KindDeployment }
+
+// DesiredReplicas 返回 Deployment 期望的副本数
+func (w DeploymentWorkload) DesiredReplicas() int32 { return DesiredReplicaCount(w.Deployment) }
+
+// IsReady 检查 Deployment 的 Available 条件
+func (w DeploymentWorkload) IsReady() bool {
+	for _, cond := range w.Status.Conditions {
+		if cond.Type == appsv1.DeploymentAvailable {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// PodSelector 返回 Deployment 的 Pod 选择器
+func (w DeploymentWorkload) PodSelector() *metav1.LabelSelector { return w.Spec.Selector }
+
+// Object 返回底层的 Deployment
+func (w DeploymentWorkload) Object() runtime.Object { return w.Deployment }
+
+// StatefulSetWorkload 将 StatefulSet 适配为 Workload
+type StatefulSetWorkload struct {
+	*appsv1.StatefulSet
+}
+
+// Kind 返回 KindStatefulSet
+func (w StatefulSetWorkload) Kind() string { return KindStatefulSet }
+
+// DesiredReplicas 返回 StatefulSet 期望的副本数
+func (w StatefulSetWorkload) DesiredReplicas() int32 {
+	if w.StatefulSet == nil || w.Spec.Replicas == nil {
+		return 1
+	}
+	return *w.Spec.Replicas
+}
+
+// IsReady StatefulSet 没有 Available 条件，以可用副本数达到期望值为准
+func (w StatefulSetWorkload) IsReady() bool {
+	replicas := w.DesiredReplicas()
+	return replicas > 0 && w.Status.AvailableReplicas >= replicas
+}
+
+// PodSelector 返回 StatefulSet 的 Pod 选择器
+func (w StatefulSetWorkload) PodSelector() *metav1.LabelSelector { return w.Spec.Selector }
+
+// Object 返回底层的 StatefulSet
+func (w StatefulSetWorkload) Object() runtime.Object { return w.StatefulSet }
+
+// PodWorkload 将不受 Deployment、StatefulSet 管理的裸 Pod（包括 Job 创建的 Pod）适配为 Workload
+type PodWorkload struct {
+	*corev1.Pod
+}
+
+// Kind 返回 KindPod
+func (w PodWorkload) Kind() string { return KindPod }
+
+// DesiredReplicas Pod 未终止且未被删除时视为 1 副本，否则为 0
+func (w PodWorkload) DesiredReplicas() int32 {
+	if IsPodTerminated(w.Pod) {
+		return 0
+	}
+	return 1
+}
+
+// IsReady 检查 Pod 是否可以作为路由目标（带有 route-ready readiness gate 时以容器就绪为准）
+func (w PodWorkload) IsReady() bool {
+	return w.DesiredReplicas() == 1 && IsPodRoutable(w.Pod)
+}
+
+// PodSelector 裸 Pod 就是路由目标本身，没有选择器
+func (w PodWorkload) PodSelector() *metav1.LabelSelector { return nil }
+
+// Object 返回底层的 Pod
+func (w PodWorkload) Object() runtime.Object { return w.Pod }
+
+// IsStandalonePod 判断 Pod 是否作为独立的路由来源
+// 要求带有 gitspace label，且没有控制器或由 Job 创建；
+// Deployment、StatefulSet 管理的 Pod 通过所属工作负载路由
+func IsStandalonePod(pod *corev1.Pod) bool {
+	if GetGitspaceIdentifier(pod) == "" {
+		return false
+	}
+	owner := metav1.GetControllerOf(pod)
+	return owner == nil || owner.Kind == "Job"
+}
+
+// IsPodTerminated 判断 Pod 是否已结束运行或正在删除
+func IsPodTerminated(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return true
+	}
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// IdentifierOwners 返回每个 gitspace identifier 的所有者
+// 多个工作负载声明同一 identifier 时，creationTimestamp 最早的获胜（相同时按工作负载键排序），
+// 已结束的独立 Pod 不参与竞争
+func IdentifierOwners(workloads []Workload) map[string]Workload {
+	owners := make(map[string]Workload)
+	for _, workload := range workloads {
+		identifier := GetGitspaceIdentifier(workload)
+		if identifier == "" {
+			continue
+		}
+		if pod, ok := workload.(PodWorkload); ok && IsPodTerminated(pod.Pod) {
+			continue
+		}
+		if current, exists := owners[identifier]; !exists || claimsBefore(workload, current) {
+			owners[identifier] = workload
+		}
+	}
+	return owners
+}
+
+// OwnsIdentifier 判断工作负载是否拥有 identifier：没有所有者或所有者就是自身
+func OwnsIdentifier(owner, workload Workload) bool {
+	return owner == nil || WorkloadKey(owner) == WorkloadKey(workload)
+}
+
+// claimsBefore 判断 a 是否比 b 更早声明 identifier
+func claimsBefore(a, b Workload) bool {
+	aTime, bTime := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !aTime.Equal(&bTime) {
+		return aTime.Before(&bTime)
+	}
+	return WorkloadKey(a) < WorkloadKey(b)
+}
+
+// WorkloadKey 返回工作负载在 Tracker 和锁中使用的键：<kind>/<namespace>/<name>
+func WorkloadKey(w Workload) string {
+	return BuildWorkloadKey(w.Kind(), w.GetNamespace(), w.GetName())
+}
+
+// BuildWorkloadKey 由类型、命名空间和名称构造工作负载键
+func BuildWorkloadKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// SplitWorkloadKey 将工作负载键拆分为类型、命名空间和名称
+func SplitWorkloadKey(key string) (kind, namespace, name string, err error) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("invalid workload key: %s", key)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// GetWorkload 通过 API 读取指定类型的工作负载（不经过 Informer 缓存）
+func GetWorkload(ctx context.Context, client kubernetes.Interface, kind, namespace, name string) (Workload, error) {
+	switch kind {
+	case KindDeployment:
+		deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+		}
+		return DeploymentWorkload{deployment}, nil
+
+	case KindStatefulSet:
+		statefulSet, err := client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s/%s: %w", namespace, name, err)
+		}
+		return StatefulSetWorkload{statefulSet}, nil
+
+	case KindPod:
+		pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod %s/%s: %w", namespace, name, err)
+		}
+		return PodWorkload{pod}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+}
+
+// ListWorkloads 通过 API 列出命名空间下所有支持类型的工作负载（Pod 只包含独立 Pod）
+func ListWorkloads(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) ([]Workload, error) {
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	workloads := make([]Workload, 0, len(deployments.Items)+len(statefulSets.Items))
+	for i := range deployments.Items {
+		workloads = append(workloads, DeploymentWorkload{&deployments.Items[i]})
+	}
+	for i := range statefulSets.Items {
+		workloads = append(workloads, StatefulSetWorkload{&statefulSets.Items[i]})
+	}
+	for i := range pods.Items {
+		if IsStandalonePod(&pods.Items[i]) {
+			workloads = append(workloads, PodWorkload{&pods.Items[i]})
+		}
+	}
+
+	return workloads, nil
+}
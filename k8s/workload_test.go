@@ -0,0 +1,397 @@
+package k8s
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestStatefulSetWorkload 测试 StatefulSet 的副本数和就绪判断
+func TestStatefulSetWorkload(t *testing.T) {
+	replicas := func(n int32) *int32 { return &n }
+
+	tests := []struct {
+		name         string
+		replicas     *int32
+		available    int32
+		wantReplicas int32
+		wantReady    bool
+	}{
+		{"未设置副本数默认为 1", nil, 0, 1, false},
+		{"单副本可用", replicas(1), 1, 1, true},
+		{"单副本不可用", replicas(1), 0, 1, false},
+		{"缩容到 0", replicas(0), 0, 0, false},
+		{"缩容到 0 时旧副本仍可用", replicas(0), 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workload := StatefulSetWorkload{&appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: tt.replicas},
+				Status: appsv1.StatefulSetStatus{AvailableReplicas: tt.available},
+			}}
+			if got := workload.DesiredReplicas(); got != tt.wantReplicas {
+				t.Errorf("DesiredReplicas() = %d, want %d", got, tt.wantReplicas)
+			}
+			if got := workload.IsReady(); got != tt.wantReady {
+				t.Errorf("IsReady() = %v, want %v", got, tt.wantReady)
+			}
+			if workload.Kind() != KindStatefulSet {
+				t.Errorf("Kind() = %s, want %s", workload.Kind(), KindStatefulSet)
+			}
+		})
+	}
+}
+
+// TestCurrentRevisionLabel 测试 Deployment 和 StatefulSet 当前版本标签的解析
+func TestCurrentRevisionLabel(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "ide"}}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "ide", Namespace: "default", UID: "deploy-uid"},
+		Spec:       appsv1.DeploymentSpec{Selector: selector},
+	}
+	isController := true
+	replicaSet := func(name, revision, hash string, owned bool) *appsv1.ReplicaSet {
+		rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "ide", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+			Annotations: map[string]string{AnnotationDeploymentRevision: revision},
+		}}
+		if owned {
+			rs.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: "ide", UID: "deploy-uid", Controller: &isController,
+			}}
+		}
+		return rs
+	}
+
+	tests := []struct {
+		name      string
+		workload  Workload
+		objects   []*appsv1.ReplicaSet
+		wantKey   string
+		wantValue string
+	}{
+		{
+			"StatefulSet 使用 updateRevision",
+			StatefulSetWorkload{&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "ide", Namespace: "default"},
+				Status:     appsv1.StatefulSetStatus{UpdateRevision: "ide-6b8f9c"},
+			}},
+			nil, appsv1.ControllerRevisionHashLabelKey, "ide-6b8f9c",
+		},
+		{
+			"StatefulSet 尚无 updateRevision",
+			StatefulSetWorkload{&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "ide", Namespace: "default"}}},
+			nil, "", "",
+		},
+		{
+			"Deployment 使用 revision 最大的 ReplicaSet",
+			DeploymentWorkload{deployment},
+			[]*appsv1.ReplicaSet{replicaSet("ide-old", "1", "aaa", true), replicaSet("ide-new", "2", "bbb", true)},
+			appsv1.DefaultDeploymentUniqueLabelKey, "bbb",
+		},
+		{
+			"Deployment 忽略不属于它的 ReplicaSet",
+			DeploymentWorkload{deployment},
+			[]*appsv1.ReplicaSet{replicaSet("ide-old", "1", "aaa", true), replicaSet("other", "9", "zzz", false)},
+			appsv1.DefaultDeploymentUniqueLabelKey, "aaa",
+		},
+		{
+			"独立 Pod 没有版本",
+			PodWorkload{&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ide", Namespace: "default"}}},
+			nil, "", "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientset()
+			for _, rs := range tt.objects {
+				if _, err := client.AppsV1().ReplicaSets("default").Create(context.Background(), rs, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("Failed to create replicaset: %v", err)
+				}
+			}
+
+			key, value, err := CurrentRevisionLabel(context.Background(), client, tt.workload)
+			if err != nil {
+				t.Fatalf("CurrentRevisionLabel() error = %v", err)
+			}
+			if key != tt.wantKey || value != tt.wantValue {
+				t.Errorf("CurrentRevisionLabel() = (%q, %q), want (%q, %q)", key, value, tt.wantKey, tt.wantValue)
+			}
+		})
+	}
+}
+
+// TestGetWorkloadStatefulSet 测试按类型读取 StatefulSet
+func TestGetWorkloadStatefulSet(t *testing.T) {
+	client := fake.NewClientset(&appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "ide", Namespace: "default"},
+	})
+
+	workload, err := GetWorkload(context.Background(), client, KindStatefulSet, "default", "ide")
+	if err != nil {
+		t.Fatalf("GetWorkload() error = %v", err)
+	}
+	if _, ok := workload.(StatefulSetWorkload); !ok {
+		t.Errorf("Expected StatefulSetWorkload, got %T", workload)
+	}
+	if got := WorkloadKey(workload); got != "StatefulSet/default/ide" {
+		t.Errorf("WorkloadKey() = %q, want %q", got, "StatefulSet/default/ide")
+	}
+
+	if _, err := GetWorkload(context.Background(), client, KindStatefulSet, "default", "missing"); err == nil {
+		t.Error("Expected error for missing statefulset")
+	}
+}
+
+// gitspacePod 构造带有 gitspace label 的 Pod
+func gitspacePod(name string, owner *metav1.OwnerReference, phase corev1.PodPhase) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{DefaultIdentifierLabel: name},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if owner != nil {
+		pod.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return pod
+}
+
+// TestIsStandalonePod 测试独立 Pod 的识别：Job 创建的 Pod 视为独立 Pod，其他控制器管理的 Pod 不是
+func TestIsStandalonePod(t *testing.T) {
+	isController, notController := true, false
+	owner := func(kind string, controller *bool) *metav1.OwnerReference {
+		return &metav1.OwnerReference{APIVersion: "v1", Kind: kind, Name: "owner", UID: "owner-uid", Controller: controller}
+	}
+
+	unlabeled := gitspacePod("ws", nil, corev1.PodRunning)
+	unlabeled.Labels = nil
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{"没有 owner 的裸 Pod", gitspacePod("ws", nil, corev1.PodRunning), true},
+		{"Job 创建的 Pod", gitspacePod("ws", owner("Job", &isController), corev1.PodRunning), true},
+		{"ReplicaSet 管理的 Pod", gitspacePod("ws", owner("ReplicaSet", &isController), corev1.PodRunning), false},
+		{"StatefulSet 管理的 Pod", gitspacePod("ws", owner("StatefulSet", &isController), corev1.PodRunning), false},
+		{"非控制器 owner 不影响", gitspacePod("ws", owner("ConfigMap", &notController), corev1.PodRunning), true},
+		{"缺少 gitspace label", unlabeled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStandalonePod(tt.pod); got != tt.want {
+				t.Errorf("IsStandalonePod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPodWorkload 测试独立 Pod 的副本数和就绪判断
+func TestPodWorkload(t *testing.T) {
+	ready := func(pod *corev1.Pod) *corev1.Pod {
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		return pod
+	}
+	deleting := ready(gitspacePod("ws", nil, corev1.PodRunning))
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+
+	tests := []struct {
+		name         string
+		pod          *corev1.Pod
+		wantReplicas int32
+		wantReady    bool
+	}{
+		{"运行中且就绪", ready(gitspacePod("ws", nil, corev1.PodRunning)), 1, true},
+		{"运行中未就绪", gitspacePod("ws", nil, corev1.PodRunning), 1, false},
+		{"Job 完成", ready(gitspacePod("ws", nil, corev1.PodSucceeded)), 0, false},
+		{"运行失败", gitspacePod("ws", nil, corev1.PodFailed), 0, false},
+		{"删除中", deleting, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workload := PodWorkload{tt.pod}
+			if got := workload.DesiredReplicas(); got != tt.wantReplicas {
+				t.Errorf("DesiredReplicas() = %d, want %d", got, tt.wantReplicas)
+			}
+			if got := workload.IsReady(); got != tt.wantReady {
+				t.Errorf("IsReady() = %v, want %v", got, tt.wantReady)
+			}
+		})
+	}
+}
+
+// TestListWorkloadsStandalonePods 测试列出工作负载时只包含独立 Pod
+func TestListWorkloadsStandalonePods(t *testing.T) {
+	isController := true
+	client := fake.NewClientset(
+		gitspacePod("bare", nil, corev1.PodRunning),
+		gitspacePod("job", &metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: "job", UID: "job-uid", Controller: &isController}, corev1.PodRunning),
+		gitspacePod("managed", &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs", UID: "rs-uid", Controller: &isController}, corev1.PodRunning),
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "default"}},
+	)
+
+	workloads, err := ListWorkloads(context.Background(), client, "default", metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("ListWorkloads() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, workload := range workloads {
+		got[WorkloadKey(workload)] = true
+	}
+	for _, key := range []string{"Deployment/default/deploy", "Pod/default/bare", "Pod/default/job"} {
+		if !got[key] {
+			t.Errorf("Expected %s in workloads, got %v", key, got)
+		}
+	}
+	if got["Pod/default/managed"] {
+		t.Error("Expected ReplicaSet-managed pod to be excluded")
+	}
+}
+
+// TestToWorkload 测试 Informer 对象到工作负载的转换
+func TestToWorkload(t *testing.T) {
+	isController := true
+	tests := []struct {
+		name     string
+		obj      any
+		wantKind string
+	}{
+		{"Deployment", &appsv1.Deployment{}, KindDeployment},
+		{"StatefulSet", &appsv1.StatefulSet{}, KindStatefulSet},
+		{"独立 Pod", gitspacePod("ws", nil, corev1.PodRunning), KindPod},
+		{"受管理的 Pod", gitspacePod("ws", &metav1.OwnerReference{Kind: "ReplicaSet", Name: "rs", Controller: &isController}, corev1.PodRunning), ""},
+		{"其他对象", &corev1.Service{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workload, ok := toWorkload(tt.obj)
+			if ok != (tt.wantKind != "") {
+				t.Fatalf("toWorkload() ok = %v, want %v", ok, tt.wantKind != "")
+			}
+			if ok && workload.Kind() != tt.wantKind {
+				t.Errorf("Kind() = %s, want %s", workload.Kind(), tt.wantKind)
+			}
+		})
+	}
+}
+
+// TestIdentifierOwners 测试 identifier 所有者的选择：最早创建的获胜，时间相同时按 key 排序，已终止的独立 Pod 不参与
+func TestIdentifierOwners(t *testing.T) {
+	older := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	newer := metav1.NewTime(older.Add(time.Minute))
+
+	deployment := func(name, identifier string, created metav1.Time) Workload {
+		return DeploymentWorkload{&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            map[string]string{DefaultIdentifierLabel: identifier},
+			CreationTimestamp: created,
+		}}}
+	}
+	pod := func(name, identifier string, created metav1.Time, phase corev1.PodPhase) Workload {
+		p := gitspacePod(name, nil, phase)
+		p.Labels[DefaultIdentifierLabel] = identifier
+		p.CreationTimestamp = created
+		return PodWorkload{p}
+	}
+
+	tests := []struct {
+		name      string
+		workloads []Workload
+		want      map[string]string
+	}{
+		{
+			"最早创建的工作负载获胜",
+			[]Workload{deployment("b", "ws", newer), deployment("a", "ws", older)},
+			map[string]string{"ws": "Deployment/default/a"},
+		},
+		{
+			"创建时间相同时按 key 排序",
+			[]Workload{deployment("b", "ws", older), deployment("a", "ws", older)},
+			map[string]string{"ws": "Deployment/default/a"},
+		},
+		{
+			"时间相同时不同类型也按 key 排序",
+			[]Workload{pod("a", "ws", older, corev1.PodRunning), deployment("z", "ws", older)},
+			map[string]string{"ws": "Deployment/default/z"},
+		},
+		{
+			"已终止的独立 Pod 不参与",
+			[]Workload{pod("a", "ws", older, corev1.PodSucceeded), deployment("b", "ws", newer)},
+			map[string]string{"ws": "Deployment/default/b"},
+		},
+		{
+			"失败的独立 Pod 也不参与",
+			[]Workload{pod("a", "ws", older, corev1.PodFailed)},
+			map[string]string{},
+		},
+		{
+			"没有 identifier 的工作负载被忽略",
+			[]Workload{deployment("a", "", older), deployment("b", "ws", newer)},
+			map[string]string{"ws": "Deployment/default/b"},
+		},
+		{
+			"不同 identifier 各自选择所有者",
+			[]Workload{deployment("a", "ws1", newer), deployment("b", "ws2", older)},
+			map[string]string{"ws1": "Deployment/default/a", "ws2": "Deployment/default/b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owners := IdentifierOwners(tt.workloads)
+			got := make(map[string]string, len(owners))
+			for identifier, owner := range owners {
+				got[identifier] = WorkloadKey(owner)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("IdentifierOwners() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOwnsIdentifier 测试工作负载是否拥有 identifier
+func TestOwnsIdentifier(t *testing.T) {
+	a := DeploymentWorkload{&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}}}
+	b := DeploymentWorkload{&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"}}}
+	podA := PodWorkload{gitspacePod("a", nil, corev1.PodRunning)}
+
+	tests := []struct {
+		name  string
+		owner Workload
+		want  bool
+	}{
+		{"没有所有者", nil, true},
+		{"所有者是自身", a, true},
+		{"所有者是其他工作负载", b, false},
+		{"同名但类型不同", podA, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OwnsIdentifier(tt.owner, a); got != tt.want {
+				t.Errorf("OwnsIdentifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,23 +1,20 @@
 package caddy2k8s
 
 import (
-	"context"
-	"fmt"
 	"strconv"
-	"time"
+	"strings"
+	"sync/atomic"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddypki"
 	"github.com/ysicing/caddy2-gitspace/config"
 	"github.com/ysicing/caddy2-gitspace/k8s"
-	"github.com/ysicing/caddy2-gitspace/router"
 	"go.uber.org/zap"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 )
 
 func init() {
-	caddy.RegisterModule(K8sRouter{})
+	caddy.RegisterModule(new(K8sRouter))
 }
 
 // K8sRouter 实现 Caddy 模块接口
@@ -31,20 +28,97 @@ type K8sRouter struct {
 	ReconcilePeriod string `json:"reconcile_period,omitempty"`
 	CaddyAdminURL   string `json:"caddy_admin_url,omitempty"`
 	CaddyServerName string `json:"caddy_server_name,omitempty"`
+	WakeTimeout     string `json:"wake_timeout,omitempty"`
+	IdleCheckPeriod string `json:"idle_check_period,omitempty"`
+	DrainPeriod     string `json:"drain_period,omitempty"`
+
+	// 就绪状态抖动抑制
+	UnreadyGracePeriod     string `json:"unready_grace_period,omitempty"`
+	MinRouteChangeInterval string `json:"min_route_change_interval,omitempty"`
+	KeepUnreadyPods        bool   `json:"keep_unready_pods,omitempty"`
+
+	// 生成路由的上游健康检查
+	HealthCheckPath            string `json:"health_check_path,omitempty"`
+	HealthCheckInterval        string `json:"health_check_interval,omitempty"`
+	HealthCheckStatus          int    `json:"health_check_status,omitempty"`
+	HealthCheckFailDuration    string `json:"health_check_fail_duration,omitempty"`
+	HealthCheckUnhealthyStatus []int  `json:"health_check_unhealthy_status,omitempty"`
+
+	// 访问控制和分享令牌
+	Visibility       string `json:"visibility,omitempty"`
+	ShareTokenKey    string `json:"share_token_key,omitempty"`
+	ShareTokenTTL    string `json:"share_token_ttl,omitempty"`
+	MaxShareTokenTTL string `json:"max_share_token_ttl,omitempty"`
+
+	// OIDC 登录
+	OIDCIssuer        string   `json:"oidc_issuer,omitempty"`
+	OIDCClientID      string   `json:"oidc_client_id,omitempty"`
+	OIDCClientSecret  string   `json:"oidc_client_secret,omitempty"`
+	OIDCRedirectURL   string   `json:"oidc_redirect_url,omitempty"`
+	OIDCScopes        []string `json:"oidc_scopes,omitempty"`
+	OIDCUsernameClaim string   `json:"oidc_username_claim,omitempty"`
+	OIDCGroupsClaim   string   `json:"oidc_groups_claim,omitempty"`
+	OIDCCookieSecret  string   `json:"oidc_cookie_secret,omitempty"`
+	OIDCSessionTTL    string   `json:"oidc_session_ttl,omitempty"`
+
+	// 客户端证书校验
+	ClientCAFiles []string `json:"client_ca_files,omitempty"`
+
+	// 限流和带宽限制
+	RateLimitClient string `json:"rate_limit_client,omitempty"`
+	RateLimitRoute  string `json:"rate_limit_route,omitempty"`
+	BandwidthLimit  string `json:"bandwidth_limit,omitempty"`
+
+	// 上游 HTTPS 和 pki 签发的证书
+	UpstreamTLS         bool   `json:"upstream_tls,omitempty"`
+	UpstreamTLSCA       string `json:"upstream_tls_ca,omitempty"`
+	UpstreamTLSLifetime string `json:"upstream_tls_lifetime,omitempty"`
+
+	// 按需端口转发（<port>-<identifier>.<base_domain>）
+	PortForwarding           bool   `json:"port_forwarding,omitempty"`
+	PortForwardingPorts      string `json:"port_forwarding_ports,omitempty"`
+	PortForwardingVisibility string `json:"port_forwarding_visibility,omitempty"`
+
+	// 双栈集群的地址族偏好
+	IPFamily string `json:"ip_family,omitempty"`
+
+	// 删除工作负载前保证路由已删除
+	UseFinalizers    bool   `json:"use_finalizers,omitempty"`
+	FinalizerTimeout string `json:"finalizer_timeout,omitempty"`
+
+	// route-ready readiness gate 的上游探测
+	ReadinessProbePath    string `json:"readiness_probe_path,omitempty"`
+	ReadinessProbeTimeout string `json:"readiness_probe_timeout,omitempty"`
+
+	// 标准路由对象（Ingress、Gateway API HTTPRoute）
+	WatchIngress         bool   `json:"watch_ingress,omitempty"`
+	IngressClass         string `json:"ingress_class,omitempty"`
+	IngressStatusAddress string `json:"ingress_status_address,omitempty"`
+	WatchHTTPRoutes      bool   `json:"watch_httproutes,omitempty"`
+	GatewayName          string `json:"gateway_name,omitempty"`
+
+	// gitspace identifier 来源
+	IdentifierSource     string `json:"identifier_source,omitempty"`
+	IdentifierKey        string `json:"identifier_key,omitempty"`
+	IdentifierNameSuffix string `json:"identifier_name_suffix,omitempty"`
 
 	// 内部状态（运行时初始化）
-	config      *config.Config
-	adminClient *router.AdminAPIClient
-	tracker     *router.RouteIDTracker
-	watcher     *k8s.Watcher
-	k8sClient   kubernetes.Interface
-	ctx         context.Context
-	cancel      context.CancelFunc
-	logger      *zap.Logger
+	config *config.Config
+	logger *zap.Logger
+
+	// controller 在 Start 中获取，跨配置重载共享；
+	// 供 gitspace_activator 等 HTTP 处理器并发读取
+	controller atomic.Pointer[routerController]
+
+	// previousConfig 复用控制器时被本实例替换的配置，本实例未成功接管（配置加载失败回滚）时在 Stop 中恢复
+	previousConfig *config.Config
+
+	// upstreamCA 本次配置加载的 pki CA（未开启 upstream_tls 时为 nil），在 Start 中发布给控制器
+	upstreamCA *caddypki.CA
 }
 
 // CaddyModule 返回模块信息
-func (K8sRouter) CaddyModule() caddy.ModuleInfo {
+func (*K8sRouter) CaddyModule() caddy.ModuleInfo {
 	return caddy.ModuleInfo{
 		ID:  "k8s_router",
 		New: func() caddy.Module { return new(K8sRouter) },
@@ -65,6 +139,66 @@ func (kr *K8sRouter) Provision(ctx caddy.Context) error {
 		ReconcilePeriod: kr.ReconcilePeriod,
 		CaddyAdminURL:   kr.CaddyAdminURL,
 		CaddyServerName: kr.CaddyServerName,
+		WakeTimeout:     kr.WakeTimeout,
+		IdleCheckPeriod: kr.IdleCheckPeriod,
+		DrainPeriod:     kr.DrainPeriod,
+
+		UnreadyGracePeriod:     kr.UnreadyGracePeriod,
+		MinRouteChangeInterval: kr.MinRouteChangeInterval,
+		KeepUnreadyPods:        kr.KeepUnreadyPods,
+
+		HealthCheckPath:            kr.HealthCheckPath,
+		HealthCheckInterval:        kr.HealthCheckInterval,
+		HealthCheckStatus:          kr.HealthCheckStatus,
+		HealthCheckFailDuration:    kr.HealthCheckFailDuration,
+		HealthCheckUnhealthyStatus: kr.HealthCheckUnhealthyStatus,
+
+		Visibility:       kr.Visibility,
+		ShareTokenKey:    kr.ShareTokenKey,
+		ShareTokenTTL:    kr.ShareTokenTTL,
+		MaxShareTokenTTL: kr.MaxShareTokenTTL,
+
+		OIDCIssuer:        kr.OIDCIssuer,
+		OIDCClientID:      kr.OIDCClientID,
+		OIDCClientSecret:  kr.OIDCClientSecret,
+		OIDCRedirectURL:   kr.OIDCRedirectURL,
+		OIDCScopes:        kr.OIDCScopes,
+		OIDCUsernameClaim: kr.OIDCUsernameClaim,
+		OIDCGroupsClaim:   kr.OIDCGroupsClaim,
+		OIDCCookieSecret:  kr.OIDCCookieSecret,
+		OIDCSessionTTL:    kr.OIDCSessionTTL,
+
+		ClientCAFiles: kr.ClientCAFiles,
+
+		RateLimitClient: kr.RateLimitClient,
+		RateLimitRoute:  kr.RateLimitRoute,
+		BandwidthLimit:  kr.BandwidthLimit,
+
+		UpstreamTLS:         kr.UpstreamTLS,
+		UpstreamTLSCA:       kr.UpstreamTLSCA,
+		UpstreamTLSLifetime: kr.UpstreamTLSLifetime,
+
+		PortForwarding:           kr.PortForwarding,
+		PortForwardingPorts:      kr.PortForwardingPorts,
+		PortForwardingVisibility: kr.PortForwardingVisibility,
+
+		IPFamily: kr.IPFamily,
+
+		UseFinalizers:    kr.UseFinalizers,
+		FinalizerTimeout: kr.FinalizerTimeout,
+
+		ReadinessProbePath:    kr.ReadinessProbePath,
+		ReadinessProbeTimeout: kr.ReadinessProbeTimeout,
+
+		WatchIngress:         kr.WatchIngress,
+		IngressClass:         kr.IngressClass,
+		IngressStatusAddress: kr.IngressStatusAddress,
+		WatchHTTPRoutes:      kr.WatchHTTPRoutes,
+		GatewayName:          kr.GatewayName,
+
+		IdentifierSource:     kr.IdentifierSource,
+		IdentifierKey:        kr.IdentifierKey,
+		IdentifierNameSuffix: kr.IdentifierNameSuffix,
 	}
 
 	// 验证配置
@@ -72,6 +206,25 @@ func (kr *K8sRouter) Provision(ctx caddy.Context) error {
 		return err
 	}
 
+	// 订阅主动健康检查事件，用于上报生成路由的上游健康状态
+	if err := subscribeHealthEvents(ctx); err != nil {
+		return err
+	}
+
+	// 注册限流指标
+	if err := registerRateLimitMetrics(ctx); err != nil {
+		return err
+	}
+
+	// 加载签发上游证书的 pki CA
+	if kr.config.UpstreamTLS {
+		ca, err := loadUpstreamCA(ctx, kr.config.UpstreamTLSCA)
+		if err != nil {
+			return err
+		}
+		kr.upstreamCA = ca
+	}
+
 	kr.logger.Info("K8s router module provisioned",
 		zap.String("namespace", kr.config.Namespace),
 		zap.String("base_domain", kr.config.BaseDomain),
@@ -90,442 +243,436 @@ func (kr *K8sRouter) Validate() error {
 }
 
 // Start 启动模块（实现 caddy.App 接口）
+// WatchKey 不变时复用已有控制器（Informer 缓存和 Tracker 状态），不会重新 List；
+// 其余设置直接交给运行中的控制器
 func (kr *K8sRouter) Start() error {
 	kr.logger.Info("K8s router starting...")
 
-	// 创建 context
-	kr.ctx, kr.cancel = context.WithCancel(context.Background())
-
-	// 1. 创建 Kubernetes client
-	clientset, err := k8s.NewKubernetesClient(kr.config.KubeConfig)
+	key, err := kr.controllerKey()
 	if err != nil {
 		return err
 	}
-	kr.k8sClient = clientset
-
-	// 2. 创建 AdminAPIClient
-	kr.adminClient = router.NewAdminAPIClient(kr.config.CaddyAdminURL, kr.config.CaddyServerName)
-
-	// 3. 创建 RouteIDTracker
-	kr.tracker = router.NewRouteIDTracker()
-
-	// 4. 延迟恢复 Tracker（等待 Caddy Admin API 启动完成）
-	go kr.recoverTrackerWithRetry()
-
-	// 5. 创建 EventHandler
-	eventHandler := NewEventHandler(
-		kr.adminClient,
-		kr.tracker,
-		clientset,
-		kr.config.Namespace,
-		kr.config.BaseDomain,
-		kr.config.DefaultPort,
-		kr.logger,
-	)
-
-	// 6. 创建并启动 Watcher
-	kr.watcher = k8s.NewWatcher(
-		clientset,
-		kr.config.Namespace,
-		kr.config.GetLabelSelector(), // 使用硬编码的 label selector
-		kr.config.GetResyncPeriodDuration(),
-		eventHandler,
-	)
 
-	// 在后台启动 Watcher
-	go func() {
-		if err := kr.watcher.Start(kr.ctx); err != nil {
-			kr.logger.Error("Watcher stopped with error", zap.Error(err))
-		}
-	}()
-
-	// 7. 启动时执行一次对账
-	go func() {
-		if err := kr.reconcileRoutesWithK8s(); err != nil {
-			kr.logger.Warn("Initial reconciliation failed", zap.Error(err))
-		}
-	}()
-
-	// 8. 启动定期对账 goroutine
-	go kr.runPeriodicReconciliation()
+	value, loaded, err := controllers.LoadOrNew(key, func() (caddy.Destructor, error) {
+		return newRouterController(kr.config, kr.logger)
+	})
+	if err != nil {
+		return err
+	}
+	controller := value.(*routerController)
+	if loaded {
+		kr.previousConfig = controller.reconfigure(kr.config)
+	}
+	if kr.upstreamCA != nil {
+		upstreamTLSCA.Store(kr.upstreamCA)
+	}
+	// 启动成功后才发布为活跃控制器，新建的控制器随后启动后台任务
+	controller.activate()
+	if !loaded {
+		controller.start()
+	}
+	kr.controller.Store(controller)
 
 	kr.logger.Info("K8s router started",
 		zap.String("namespace", kr.config.Namespace),
 		zap.String("base_domain", kr.config.BaseDomain),
 		zap.Duration("reconcile_period", kr.config.GetReconcilePeriodDuration()),
+		zap.Bool("reused_controller", loaded),
 	)
 
 	return nil
 }
 
 // Stop 停止模块（实现 caddy.App 接口）
+// 释放对控制器的引用；最后一个引用释放时控制器停止并等待所有 goroutine 退出
 func (kr *K8sRouter) Stop() error {
 	kr.logger.Info("K8s router stopping...")
 
-	if kr.cancel != nil {
-		kr.cancel()
+	controller := kr.controller.Swap(nil)
+	if controller == nil {
+		return nil
+	}
+	// 新配置没有接管控制器（例如配置加载失败回滚）时，恢复旧配置的设置
+	if kr.previousConfig != nil && controller.config() == kr.config {
+		controller.reconfigure(kr.previousConfig)
 	}
 
-	if kr.watcher != nil {
-		kr.watcher.Stop()
+	key, err := kr.controllerKey()
+	if err != nil {
+		return err
+	}
+	if _, err := controllers.Delete(key); err != nil {
+		return err
 	}
 
 	kr.logger.Info("K8s router stopped")
 	return nil
 }
 
-// recoverTrackerWithRetry 带重试机制的异步恢复 Tracker
-func (kr *K8sRouter) recoverTrackerWithRetry() {
-	const (
-		maxRetries        = 5
-		initialDelay      = 2 * time.Second
-		maxDelay          = 30 * time.Second
-		healthCheckURL    = "/config/"
-		healthCheckTimeout = 2 * time.Second // 快速健康检查,避免阻塞
-	)
+// controllerKey 返回控制器在 UsagePool 中的键（必须重建控制器才能生效的设置）
+func (kr *K8sRouter) controllerKey() (string, error) {
+	return kr.config.WatchKey()
+}
 
-	kr.logger.Info("Starting delayed tracker recovery...")
-
-	// 首次延迟,等待 Caddy Admin API 启动
-	time.Sleep(initialDelay)
-
-	delay := initialDelay
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		kr.logger.Info("Attempting to recover tracker",
-			zap.Int("attempt", attempt),
-			zap.Int("max_retries", maxRetries),
-		)
-
-		// 健康检查:先测试 Admin API 是否可访问(使用短超时快速失败)
-		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
-		if err := kr.adminClient.HealthCheck(ctx, healthCheckURL); err != nil {
-			cancel()
-			kr.logger.Warn("Admin API health check failed",
-				zap.Int("attempt", attempt),
-				zap.Error(err),
-				zap.Duration("retry_after", delay),
-			)
-
-			if attempt < maxRetries {
-				time.Sleep(delay)
-				// 指数退避,但不超过 maxDelay
-				delay *= 2
-				if delay > maxDelay {
-					delay = maxDelay
-				}
-			}
-			continue
-		}
-		cancel()
+// UnmarshalCaddyfile 支持 Caddyfile 配置格式
+func (kr *K8sRouter) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	// 跳过指令名称
+	d.Next()
 
-		// Admin API 健康,先清理重复路由
-		ctx2, cancel2 := context.WithTimeout(context.Background(), 15*time.Second)
-		deletedCount, err := kr.adminClient.CleanupDuplicateRoutes(ctx2)
-		cancel2()
+	// 解析块内容
+	for d.NextBlock(0) {
+		switch d.Val() {
+		case "namespace":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.Namespace = d.Val()
 
-		if err != nil {
-			kr.logger.Warn("Failed to cleanup duplicate routes",
-				zap.Int("attempt", attempt),
-				zap.Error(err),
-			)
-			// 清理失败不阻塞恢复流程,继续尝试恢复
-		} else if deletedCount > 0 {
-			kr.logger.Info("Cleaned up duplicate routes",
-				zap.Int("deleted_count", deletedCount),
-			)
-		}
+		case "base_domain":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.BaseDomain = d.Val()
 
-		// 尝试恢复 Tracker
-		// 注意：不再创建基础路由，它们由 Caddyfile 定义
-		if err := kr.recoverTracker(); err != nil {
-			kr.logger.Warn("Failed to recover tracker",
-				zap.Int("attempt", attempt),
-				zap.Error(err),
-			)
-
-			if attempt < maxRetries {
-				time.Sleep(delay)
-				delay *= 2
-				if delay > maxDelay {
-					delay = maxDelay
-				}
-			}
-			continue
-		}
+		case "default_port":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			port, err := strconv.Atoi(d.Val())
+			if err != nil {
+				return d.Errf("invalid default_port: %v", err)
+			}
+			kr.DefaultPort = port
 
-		// 恢复成功
-		kr.logger.Info("Tracker recovery completed successfully",
-			zap.Int("attempt", attempt),
-		)
-		return
-	}
+		case "kubeconfig":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.KubeConfig = d.Val()
 
-	// 所有重试都失败
-	kr.logger.Error("Failed to recover tracker after all retries",
-		zap.Int("max_retries", maxRetries),
-	)
-}
+		case "resync_period":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.ResyncPeriod = d.Val()
 
-// recoverTracker 从 Caddy Admin API 和 K8s 恢复 RouteIDTracker
-// 参考 gitness 的修复思路：不从 routeID 反推，而是通过 K8s Deployments 匹配
-// 使用 gitspace identifier（来自 deployment label）而不是 deployment name
-//
-// 简化架构：不再管理基础路由（healthz, catch-all）
-// 基础路由由 Caddyfile 定义，插件只管理动态 deployment 路由
-func (kr *K8sRouter) recoverTracker() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// 1. 从 Caddy 获取所有路由
-	routes, err := kr.adminClient.ListRoutes(ctx)
-	if err != nil {
-		return err
-	}
+		case "reconcile_period":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.ReconcilePeriod = d.Val()
 
-	// 构建 routeID -> route 映射（只关注有 ID 的动态路由）
-	routeMap := make(map[string]*router.RouteConfig)
-	for _, route := range routes {
-		// 只处理有 @id 的路由（动态创建的 deployment 路由）
-		// Caddyfile 创建的路由没有 @id，我们不管理它们
-		if route.ID != "" {
-			routeMap[route.ID] = route
-		}
-	}
+		case "caddy_admin_url":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.CaddyAdminURL = d.Val()
 
-	// 2. 从 K8s 获取所有 Deployments
-	deployments, err := kr.k8sClient.AppsV1().Deployments(kr.config.Namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to list deployments: %w", err)
-	}
+		case "caddy_server_name":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.CaddyServerName = d.Val()
 
-	// 3. 遍历 Deployments，恢复 tracker 映射
-	recoveredCount := 0
-	skippedCount := 0
-	for i := range deployments.Items {
-		deployment := &deployments.Items[i]
-
-		// 从 deployment labels 获取 gitspace identifier
-		gitspaceIdentifier := k8s.GetGitspaceIdentifier(deployment)
-		if gitspaceIdentifier == "" {
-			kr.logger.Debug("Deployment missing gitspace identifier, skipping recovery",
-				zap.String("deployment", deployment.Name),
-			)
-			skippedCount++
-			continue
-		}
+		case "wake_timeout":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.WakeTimeout = d.Val()
 
-		// 使用 gitspace identifier 构造期望的 routeID
-		routeID := router.BuildRouteID(gitspaceIdentifier)
-
-		// 检查 Caddy 中是否存在对应的路由
-		if route, exists := routeMap[routeID]; exists {
-			deploymentKey := fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name)
-			kr.tracker.Set(deploymentKey, route.ID, route.TargetAddr)
-			kr.logger.Info("Recovered route",
-				zap.String("route_id", route.ID),
-				zap.String("deployment", deployment.Name),
-				zap.String("gitspace_identifier", gitspaceIdentifier),
-				zap.String("deployment_key", deploymentKey),
-				zap.String("target_addr", route.TargetAddr),
-			)
-			recoveredCount++
-		}
-	}
+		case "idle_check_period":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.IdleCheckPeriod = d.Val()
 
-	kr.logger.Info("Tracker recovered",
-		zap.Int("total_routes", len(routes)),
-		zap.Int("recovered_mappings", recoveredCount),
-		zap.Int("skipped_deployments", skippedCount),
-	)
+		case "drain_period":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.DrainPeriod = d.Val()
 
-	return nil
-}
+		case "unready_grace_period":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.UnreadyGracePeriod = d.Val()
 
-// reconcileRoutesWithK8s 全量对账 Caddy 路由与 K8s Deployment 状态
-// 简化架构：只处理动态 deployment 路由，不管理 Caddyfile 定义的基础路由
-func (kr *K8sRouter) reconcileRoutesWithK8s() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+		case "min_route_change_interval":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.MinRouteChangeInterval = d.Val()
 
-	kr.logger.Info("Starting route reconciliation...")
+		case "keep_unready_pods":
+			enabled, err := parseOptionalBool(d)
+			if err != nil {
+				return err
+			}
+			kr.KeepUnreadyPods = enabled
 
-	// 1. 获取 Caddy 中所有管理的路由（只包含有 @id 的动态路由）
-	routes, err := kr.adminClient.ListRoutes(ctx)
-	if err != nil {
-		kr.logger.Error("Failed to list Caddy routes during reconciliation", zap.Error(err))
-		return err
-	}
+		case "health_check_path":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.HealthCheckPath = d.Val()
 
-	// 构建 Caddy 路由集合 (routeID -> route)
-	// IsManagedRouteID 会过滤掉 Caddyfile 路由（它们没有 @id 或不符合命名规则）
-	caddyRoutes := make(map[string]*router.RouteConfig)
-	for _, route := range routes {
-		if router.IsManagedRouteID(route.ID) {
-			caddyRoutes[route.ID] = route
-		}
-	}
+		case "health_check_interval":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.HealthCheckInterval = d.Val()
 
-	// 2. 获取 K8s 中所有符合条件的 Deployment (replicas=1 && ready)
-	deployments, err := kr.k8sClient.AppsV1().Deployments(kr.config.Namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		kr.logger.Error("Failed to list K8s deployments during reconciliation", zap.Error(err))
-		return err
-	}
+		case "health_check_status":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			status, err := strconv.Atoi(d.Val())
+			if err != nil {
+				return d.Errf("invalid health_check_status: %v", err)
+			}
+			kr.HealthCheckStatus = status
 
-	// 构建期望的路由集合
-	expectedRoutes := make(map[string]bool)
-	// gitspaceIdentifierToDeploymentKey 映射，用于清理时查找 deploymentKey
-	gitspaceIdentifierToDeploymentKey := make(map[string]string)
+		case "health_check_fail_duration":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.HealthCheckFailDuration = d.Val()
 
-	for i := range deployments.Items {
-		deployment := &deployments.Items[i]
+		case "health_check_unhealthy_status":
+			args := d.RemainingArgs()
+			if len(args) == 0 {
+				return d.ArgErr()
+			}
+			statuses, err := k8s.ParseStatusCodes(strings.Join(args, " "))
+			if err != nil {
+				return d.Errf("invalid health_check_unhealthy_status: %v", err)
+			}
+			kr.HealthCheckUnhealthyStatus = statuses
 
-		// 只处理单副本 Deployment
-		replicas := k8s.DesiredReplicaCount(deployment)
-		if replicas != 1 {
-			continue
-		}
+		case "visibility":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.Visibility = d.Val()
 
-		// 只处理就绪的 Deployment
-		if !isDeploymentReady(deployment) {
-			continue
-		}
+		case "share_token_key":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.ShareTokenKey = d.Val()
 
-		// 使用 gitspaceIdentifier 而不是 deployment.Name
-		gitspaceIdentifier := k8s.GetGitspaceIdentifier(deployment)
-		if gitspaceIdentifier == "" {
-			kr.logger.Warn("Deployment missing gitspace identifier, skipping",
-				zap.String("deployment", deployment.Name),
-			)
-			continue
-		}
+		case "share_token_ttl":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.ShareTokenTTL = d.Val()
 
-		routeID := router.BuildRouteID(gitspaceIdentifier)
-		expectedRoutes[routeID] = true
+		case "max_share_token_ttl":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.MaxShareTokenTTL = d.Val()
 
-		// 记录映射关系，用于后续清理 tracker
-		deploymentKey := fmt.Sprintf("%s/%s", deployment.Namespace, deployment.Name)
-		gitspaceIdentifierToDeploymentKey[gitspaceIdentifier] = deploymentKey
-	}
+		case "oidc_issuer":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.OIDCIssuer = d.Val()
 
-	// 3. 删除 Caddy 中存在但 K8s 中不存在的路由（清理孤立路由）
-	deletedCount := 0
-	for routeID := range caddyRoutes {
-		if !expectedRoutes[routeID] {
-			kr.logger.Info("Reconciliation: deleting orphaned route",
-				zap.String("route_id", routeID),
-			)
-
-			if err := kr.adminClient.DeleteRoute(ctx, routeID); err != nil {
-				kr.logger.Warn("Failed to delete orphaned route during reconciliation",
-					zap.String("route_id", routeID),
-					zap.Error(err),
-				)
-			} else {
-				// 从 tracker 中清理
-				// 从 routeID 解析出 gitspaceIdentifier
-				gitspaceIdentifier, err := router.ParseRouteID(routeID)
-				if err == nil {
-					// 使用映射找到对应的 deploymentKey
-					if deploymentKey, exists := gitspaceIdentifierToDeploymentKey[gitspaceIdentifier]; exists {
-						kr.tracker.Delete(deploymentKey)
-					}
-				}
-				deletedCount++
+		case "oidc_client_id":
+			if !d.NextArg() {
+				return d.ArgErr()
 			}
-		}
-	}
+			kr.OIDCClientID = d.Val()
 
-	// 4. 对于 K8s 中存在但 Caddy 中缺失的路由，由 Informer 的 resync 机制自动创建
-	// 这里不主动创建，避免与事件处理冲突
+		case "oidc_client_secret":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.OIDCClientSecret = d.Val()
 
-	kr.logger.Info("Route reconciliation completed",
-		zap.Int("caddy_routes", len(caddyRoutes)),
-		zap.Int("expected_routes", len(expectedRoutes)),
-		zap.Int("deleted_orphaned", deletedCount),
-	)
+		case "oidc_redirect_url":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.OIDCRedirectURL = d.Val()
 
-	return nil
-}
+		case "oidc_scopes":
+			kr.OIDCScopes = d.RemainingArgs()
+			if len(kr.OIDCScopes) == 0 {
+				return d.ArgErr()
+			}
 
-// runPeriodicReconciliation 定期执行对账
-func (kr *K8sRouter) runPeriodicReconciliation() {
-	ticker := time.NewTicker(kr.config.GetReconcilePeriodDuration())
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			kr.logger.Debug("Running periodic reconciliation...")
-			if err := kr.reconcileRoutesWithK8s(); err != nil {
-				kr.logger.Warn("Periodic reconciliation failed", zap.Error(err))
-			}
-		case <-kr.ctx.Done():
-			kr.logger.Info("Stopping periodic reconciliation")
-			return
-		}
-	}
-}
+		case "oidc_username_claim":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.OIDCUsernameClaim = d.Val()
 
-// UnmarshalCaddyfile 支持 Caddyfile 配置格式
-func (kr *K8sRouter) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
-	// 跳过指令名称
-	d.Next()
+		case "oidc_groups_claim":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.OIDCGroupsClaim = d.Val()
 
-	// 解析块内容
-	for d.NextBlock(0) {
-		switch d.Val() {
-		case "namespace":
+		case "oidc_cookie_secret":
 			if !d.NextArg() {
 				return d.ArgErr()
 			}
-			kr.Namespace = d.Val()
+			kr.OIDCCookieSecret = d.Val()
 
-		case "base_domain":
+		case "oidc_session_ttl":
 			if !d.NextArg() {
 				return d.ArgErr()
 			}
-			kr.BaseDomain = d.Val()
+			kr.OIDCSessionTTL = d.Val()
 
-		case "default_port":
+		case "client_ca_files":
+			kr.ClientCAFiles = d.RemainingArgs()
+			if len(kr.ClientCAFiles) == 0 {
+				return d.ArgErr()
+			}
+
+		case "rate_limit_client":
+			args := d.RemainingArgs()
+			if len(args) == 0 || len(args) > 2 {
+				return d.ArgErr()
+			}
+			kr.RateLimitClient = strings.Join(args, ",")
+
+		case "rate_limit_route":
+			args := d.RemainingArgs()
+			if len(args) == 0 || len(args) > 2 {
+				return d.ArgErr()
+			}
+			kr.RateLimitRoute = strings.Join(args, ",")
+
+		case "bandwidth_limit":
 			if !d.NextArg() {
 				return d.ArgErr()
 			}
-			port, err := strconv.Atoi(d.Val())
+			kr.BandwidthLimit = d.Val()
+
+		case "upstream_tls":
+			enabled, err := parseOptionalBool(d)
 			if err != nil {
-				return d.Errf("invalid default_port: %v", err)
+				return err
 			}
-			kr.DefaultPort = port
+			kr.UpstreamTLS = enabled
 
-		case "kubeconfig":
+		case "upstream_tls_ca":
 			if !d.NextArg() {
 				return d.ArgErr()
 			}
-			kr.KubeConfig = d.Val()
+			kr.UpstreamTLSCA = d.Val()
 
-		case "resync_period":
+		case "upstream_tls_lifetime":
 			if !d.NextArg() {
 				return d.ArgErr()
 			}
-			kr.ResyncPeriod = d.Val()
+			kr.UpstreamTLSLifetime = d.Val()
 
-		case "reconcile_period":
+		case "port_forwarding":
+			enabled, err := parseOptionalBool(d)
+			if err != nil {
+				return err
+			}
+			kr.PortForwarding = enabled
+
+		case "port_forwarding_ports":
+			args := d.RemainingArgs()
+			if len(args) == 0 {
+				return d.ArgErr()
+			}
+			kr.PortForwardingPorts = strings.Join(args, ",")
+
+		case "port_forwarding_visibility":
 			if !d.NextArg() {
 				return d.ArgErr()
 			}
-			kr.ReconcilePeriod = d.Val()
+			kr.PortForwardingVisibility = d.Val()
 
-		case "caddy_admin_url":
+		case "ip_family":
 			if !d.NextArg() {
 				return d.ArgErr()
 			}
-			kr.CaddyAdminURL = d.Val()
+			kr.IPFamily = d.Val()
 
-		case "caddy_server_name":
+		case "use_finalizers":
+			enabled, err := parseOptionalBool(d)
+			if err != nil {
+				return err
+			}
+			kr.UseFinalizers = enabled
+
+		case "finalizer_timeout":
 			if !d.NextArg() {
 				return d.ArgErr()
 			}
-			kr.CaddyServerName = d.Val()
+			kr.FinalizerTimeout = d.Val()
+
+		case "readiness_probe_path":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.ReadinessProbePath = d.Val()
+
+		case "readiness_probe_timeout":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.ReadinessProbeTimeout = d.Val()
+
+		case "watch_ingress":
+			enabled, err := parseOptionalBool(d)
+			if err != nil {
+				return err
+			}
+			kr.WatchIngress = enabled
+
+		case "ingress_class":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.IngressClass = d.Val()
+
+		case "ingress_status_address":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.IngressStatusAddress = d.Val()
+
+		case "watch_httproutes":
+			enabled, err := parseOptionalBool(d)
+			if err != nil {
+				return err
+			}
+			kr.WatchHTTPRoutes = enabled
+
+		case "gateway_name":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.GatewayName = d.Val()
+
+		case "identifier_source":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.IdentifierSource = d.Val()
+
+		case "identifier_key":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.IdentifierKey = d.Val()
+
+		case "identifier_name_suffix":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			kr.IdentifierNameSuffix = d.Val()
 
 		default:
 			return d.Errf("unrecognized subdirective: %s", d.Val())
@@ -535,6 +682,18 @@ func (kr *K8sRouter) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 	return nil
 }
 
+// parseOptionalBool 解析可省略参数的布尔选项，省略时为 true
+func parseOptionalBool(d *caddyfile.Dispenser) (bool, error) {
+	if !d.NextArg() {
+		return true, nil
+	}
+	value, err := strconv.ParseBool(d.Val())
+	if err != nil {
+		return false, d.Errf("invalid boolean %s: %v", d.Val(), err)
+	}
+	return value, nil
+}
+
 // Interface guards
 var (
 	_ caddy.Provisioner     = (*K8sRouter)(nil)
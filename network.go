@@ -0,0 +1,138 @@
+package caddy2k8s
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+)
+
+// eventReasonInvalidNetworkPolicy 网络访问限制注解无效的事件原因
+const eventReasonInvalidNetworkPolicy = "InvalidGitspaceNetworkPolicy"
+
+// clientCertPolicyPrefix 插件生成的客户端证书 TLS 连接策略的 @id 前缀
+const clientCertPolicyPrefix = "gitspace-mtls:"
+
+// clientCertPolicyID 返回路由对应的客户端证书策略 ID
+func clientCertPolicyID(routeID string) string {
+	return clientCertPolicyPrefix + routeID
+}
+
+// networkPolicyFor 解析工作负载的网络访问限制，注解无效时标记路由失败并返回 false
+func (h *EventHandler) networkPolicyFor(workload k8s.Workload) (k8s.NetworkPolicy, bool) {
+	policy, err := k8s.ParseNetworkPolicy(workload.GetAnnotations())
+	if err == nil && policy.RequireClientCert && len(h.settings().clientCAFiles) == 0 {
+		err = fmt.Errorf("%s requires client_ca_files to be configured", k8s.AnnotationRequireClientCert)
+	}
+	if err != nil {
+		h.logger.Warn("Invalid network policy, route not created",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+		h.markRouteFailed(workload, eventReasonInvalidNetworkPolicy, err.Error())
+		return k8s.NetworkPolicy{}, false
+	}
+	return policy, true
+}
+
+// syncClientCertPolicy 按工作负载的要求创建或删除路由域名的客户端证书策略
+// 开启按需端口转发时策略同时匹配 <port>-<identifier> 域名
+func (h *EventHandler) syncClientCertPolicy(ctx context.Context, routeID, domain string, policy k8s.NetworkPolicy) error {
+	if !policy.RequireClientCert {
+		return h.deleteClientCertPolicy(ctx, routeID)
+	}
+
+	spec := router.TLSPolicySpec{
+		ID:            clientCertPolicyID(routeID),
+		ServerNames:   []string{domain},
+		ClientCAFiles: h.settings().clientCAFiles,
+	}
+	if h.settings().portForwarding {
+		spec.ServerNamePattern = fmt.Sprintf(`^([0-9]{1,5}-)?%s$`, regexp.QuoteMeta(strings.ToLower(domain)))
+	}
+	if err := h.adminClient.ApplyTLSPolicy(ctx, spec); err != nil {
+		return fmt.Errorf("failed to apply client certificate policy: %w", err)
+	}
+	h.clientCertPolicies.Store(routeID, struct{}{})
+	return nil
+}
+
+// deleteClientCertPolicy 删除路由的客户端证书策略
+// 只删除本进程创建过的策略，重启前遗留的策略由对账清理
+func (h *EventHandler) deleteClientCertPolicy(ctx context.Context, routeID string) error {
+	if _, ok := h.clientCertPolicies.Load(routeID); !ok {
+		return nil
+	}
+	if err := h.adminClient.DeleteTLSPolicy(ctx, clientCertPolicyID(routeID)); err != nil {
+		return fmt.Errorf("failed to delete client certificate policy: %w", err)
+	}
+	h.clientCertPolicies.Delete(routeID)
+	return nil
+}
+
+// cleanupClientCertPolicies 删除不再需要的客户端证书策略，expected 为仍要求客户端证书的路由 ID
+func (c *routerController) cleanupClientCertPolicies(ctx context.Context, expected map[string]bool) (int, error) {
+	ids, err := c.adminClient.ListTLSPolicyIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		routeID, ok := strings.CutPrefix(id, clientCertPolicyPrefix)
+		if !ok || expected[routeID] {
+			continue
+		}
+		if err := c.adminClient.DeleteTLSPolicy(ctx, id); err != nil {
+			c.logger.Warn("Failed to delete orphaned client certificate policy",
+				zap.String("policy_id", id),
+				zap.Error(err),
+			)
+			continue
+		}
+		c.eventHandler.clientCertPolicies.Delete(routeID)
+		deleted++
+	}
+	return deleted, nil
+}
+
+// checkNetworkPolicy 在请求处理时再次检查地址段和客户端证书
+// 端口转发路由由所有 gitspace 共用，无法在路由中生成 remote_ip 匹配器；
+// 客户端证书策略按 SNI 生效，还需防止以其他 SNI 握手后通过 Host 访问该 gitspace
+func (c *routerController) checkNetworkPolicy(r *http.Request, workload k8s.Workload) error {
+	policy, err := k8s.ParseNetworkPolicy(workload.GetAnnotations())
+	if err != nil {
+		return err
+	}
+
+	if len(policy.AllowCIDRs) > 0 {
+		addr, err := remoteAddr(r)
+		if err != nil || !policy.AllowsAddr(addr) {
+			return fmt.Errorf("client address %s is not allowed", r.RemoteAddr)
+		}
+	}
+	if policy.RequireClientCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+		return fmt.Errorf("verified client certificate required")
+	}
+	return nil
+}
+
+// remoteAddr 返回直连客户端的地址（与 remote_ip 匹配器一致，不考虑代理头）
+func remoteAddr(r *http.Request) (netip.Addr, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid remote address %q: %w", r.RemoteAddr, err)
+	}
+	return addr, nil
+}
@@ -0,0 +1,194 @@
+package caddy2k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/ysicing/caddy2-gitspace/auth"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+)
+
+func init() {
+	caddy.RegisterModule(OIDCCallback{})
+}
+
+const (
+	// oidcRouteID 登录回调路由的 ID
+	oidcRouteID = "gitspace:oidc"
+
+	// oidcLogoutPath 退出登录的路径（与回调地址同一域名）
+	oidcLogoutPath = "/_gitspace/oidc/logout"
+
+	// oidcSharePath 已登录用户签发分享链接的路径（与回调地址同一域名）
+	oidcSharePath = "/_gitspace/share"
+)
+
+// OIDCCallback 处理 OIDC 授权码回调、退出登录和所有者签发分享链接的 HTTP 处理器
+// 由 k8s_router 注入到 oidc_redirect_url 所在域名的路由中，不需要在 Caddyfile 中手动配置
+type OIDCCallback struct {
+	router *K8sRouter
+	logger *zap.Logger
+}
+
+// CaddyModule 返回模块信息
+func (OIDCCallback) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.gitspace_oidc",
+		New: func() caddy.Module { return new(OIDCCallback) },
+	}
+}
+
+// Provision 获取 k8s_router 应用实例
+func (o *OIDCCallback) Provision(ctx caddy.Context) error {
+	o.logger = ctx.Logger()
+
+	app, err := ctx.App("k8s_router")
+	if err != nil {
+		return fmt.Errorf("gitspace_oidc requires the k8s_router app: %w", err)
+	}
+	o.router = app.(*K8sRouter)
+	return nil
+}
+
+// ServeHTTP 完成登录后重定向回原 gitspace 地址；退出登录时清除会话
+func (o *OIDCCallback) ServeHTTP(w http.ResponseWriter, r *http.Request, _ caddyhttp.Handler) error {
+	controller := o.router.controller.Load()
+	if controller == nil || controller.authenticator == nil {
+		return caddyhttp.Error(http.StatusServiceUnavailable, fmt.Errorf("oidc login is not enabled"))
+	}
+	authenticator := controller.authenticator
+
+	callback, err := url.Parse(controller.config().OIDCRedirectURL)
+	if err != nil {
+		return caddyhttp.Error(http.StatusInternalServerError, err)
+	}
+
+	switch r.URL.Path {
+	case callback.Path:
+		user, err := authenticator.HandleCallback(w, r)
+		if err != nil {
+			o.logger.Warn("OIDC login failed", zap.Error(err))
+			if errors.Is(err, auth.ErrLoginFailed) {
+				return caddyhttp.Error(http.StatusForbidden, err)
+			}
+			return caddyhttp.Error(http.StatusBadGateway, err)
+		}
+		o.logger.Info("User logged in",
+			zap.String("user", user.Name),
+			zap.Strings("groups", user.Groups),
+		)
+		return nil
+
+	case oidcLogoutPath:
+		authenticator.Logout(w)
+		if rd := r.URL.Query().Get("rd"); rd != "" && authenticator.AllowedReturnTo(rd) {
+			http.Redirect(w, r, rd, http.StatusSeeOther)
+			return nil
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err := w.Write([]byte("logged out\n"))
+		return err
+
+	case oidcSharePath:
+		return controller.handleOwnerShare(w, r)
+
+	default:
+		return caddyhttp.Error(http.StatusNotFound, fmt.Errorf("unknown oidc path %s", r.URL.Path))
+	}
+}
+
+// handleOwnerShare 已登录的 gitspace 所有者或用户组成员为自己的 gitspace 签发分享链接
+// 只接受 JSON 请求体的 POST：跨站表单无法发送 JSON，SameSite=Lax 的会话 Cookie 也不会随跨站 POST 发送
+func (c *routerController) handleOwnerShare(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return caddyhttp.Error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return caddyhttp.Error(http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/json"))
+	}
+
+	user, ok := c.authenticator.User(r)
+	if !ok {
+		return caddyhttp.Error(http.StatusUnauthorized, fmt.Errorf("login required"))
+	}
+
+	var req shareRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		return caddyhttp.Error(http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+	}
+
+	link, err := c.issueShareLink(req, user)
+	if err != nil {
+		return caddyhttp.Error(shareErrorStatus(err), err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	return json.NewEncoder(w).Encode(link)
+}
+
+// startLogin 将未登录的浏览器请求重定向到身份提供方，登录完成后回到当前地址
+// 非 GET/HEAD 请求和 WebSocket 升级请求无法完成重定向，直接返回 401
+func (c *routerController) startLogin(w http.ResponseWriter, r *http.Request) (bool, error) {
+	if (r.Method != http.MethodGet && r.Method != http.MethodHead) || r.Header.Get("Upgrade") != "" {
+		return true, caddyhttp.Error(http.StatusUnauthorized, fmt.Errorf("login required"))
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	returnTo := scheme + "://" + r.Host + r.URL.RequestURI()
+
+	if err := c.authenticator.StartLogin(w, r, returnTo); err != nil {
+		c.logger.Error("Failed to start oidc login", zap.Error(err))
+		return true, caddyhttp.Error(http.StatusBadGateway, err)
+	}
+	return true, nil
+}
+
+// setForwardedUser 将已验证的用户通过请求头传给上游
+func setForwardedUser(r *http.Request, user *auth.User) {
+	r.Header.Set("X-Forwarded-User", user.Name)
+	if user.Email != "" {
+		r.Header.Set("X-Forwarded-Email", user.Email)
+	}
+}
+
+// ensureOIDCRoute 确保 oidc_redirect_url 所在域名的登录回调路由存在且配置一致
+func (c *routerController) ensureOIDCRoute() error {
+	callback, err := url.Parse(c.config().OIDCRedirectURL)
+	if err != nil {
+		return fmt.Errorf("invalid oidc_redirect_url: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	spec := router.RouteSpec{
+		ID:     oidcRouteID,
+		Domain: callback.Hostname(),
+		Handlers: []map[string]any{
+			{"handler": "gitspace_oidc"},
+		},
+	}
+	if err := c.adminClient.ApplyRoute(ctx, spec); err != nil {
+		return fmt.Errorf("failed to apply oidc callback route: %w", err)
+	}
+	return nil
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner           = (*OIDCCallback)(nil)
+	_ caddyhttp.MiddlewareHandler = (*OIDCCallback)(nil)
+)
@@ -0,0 +1,370 @@
+package caddy2k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func init() {
+	caddy.RegisterModule(Placeholder{})
+	httpcaddyfile.RegisterHandlerDirective("gitspace_placeholder", parsePlaceholderDirective)
+	httpcaddyfile.RegisterDirectiveOrder("gitspace_placeholder", httpcaddyfile.Before, "respond")
+}
+
+// gitspace 状态（同时也是模板名称）
+const (
+	placeholderStarting = k8s.GitspaceStarting
+	placeholderStopped  = k8s.GitspaceStopped
+	placeholderFailed   = k8s.GitspaceFailed
+	placeholderNotFound = "not_found"
+	// placeholderStopping 路由排空结束后由 stopping 路由固定渲染
+	placeholderStopping = "stopping"
+)
+
+// defaultPlaceholderTemplate 默认页面模板，所有状态共用
+const defaultPlaceholderTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+{{if .RefreshSeconds}}<meta http-equiv="refresh" content="{{.RefreshSeconds}}">{{end}}
+<title>{{.Identifier}} - {{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; background: #f6f8fa; color: #24292f; }
+main { max-width: 560px; margin: 15vh auto; padding: 32px; background: #fff; border: 1px solid #d0d7de; border-radius: 8px; }
+h1 { font-size: 20px; margin-top: 0; }
+code { background: #eff1f3; padding: 2px 6px; border-radius: 4px; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+{{if eq .State "starting"}}<p>Gitspace <code>{{.Identifier}}</code> is starting. This page refreshes automatically.</p>{{end}}
+{{if eq .State "stopped"}}<p>Gitspace <code>{{.Identifier}}</code> is stopped. Start it from your control plane to continue.</p>{{end}}
+{{if eq .State "failed"}}<p>Gitspace <code>{{.Identifier}}</code> failed to start.</p>
+<p><strong>{{.Reason}}</strong>{{if .Message}}: {{.Message}}{{end}}</p>{{end}}
+{{if eq .State "stopping"}}<p>Gitspace <code>{{.Identifier}}</code> is stopping.</p>{{end}}
+{{if eq .State "not_found"}}<p>No gitspace is serving <code>{{.Host}}</code>.</p>{{end}}
+</main>
+</body>
+</html>
+`
+
+// placeholderTitles 各状态的页面标题
+var placeholderTitles = map[string]string{
+	placeholderStarting: "Gitspace starting",
+	placeholderStopped:  "Gitspace stopped",
+	placeholderFailed:   "Gitspace failed",
+	placeholderNotFound: "Gitspace not found",
+	placeholderStopping: "Gitspace stopping",
+}
+
+// placeholderStatusCodes 各状态的 HTTP 状态码
+var placeholderStatusCodes = map[string]int{
+	placeholderStarting: http.StatusServiceUnavailable,
+	placeholderStopped:  http.StatusServiceUnavailable,
+	placeholderFailed:   http.StatusBadGateway,
+	placeholderNotFound: http.StatusNotFound,
+	placeholderStopping: http.StatusServiceUnavailable,
+}
+
+// Placeholder 没有匹配路由时，根据 gitspace 状态渲染占位页面的 HTTP 处理器
+// 通常放在通配符站点的 catch-all 位置，替代固定的 404 响应
+type Placeholder struct {
+	// Templates 覆盖默认页面模板的文件路径
+	// 键为 starting、stopped、failed、not_found、stopping
+	Templates map[string]string `json:"templates,omitempty"`
+
+	// State 固定渲染的状态，不查询 gitspace；目前只支持 stopping（排空结束后的 stopping 路由使用）
+	State string `json:"state,omitempty"`
+
+	// RefreshInterval starting 页面的自动刷新间隔，默认 5s
+	RefreshInterval caddy.Duration `json:"refresh_interval,omitempty"`
+
+	router    *K8sRouter
+	templates map[string]*template.Template
+	logger    *zap.Logger
+}
+
+// placeholderData 模板渲染数据
+type placeholderData struct {
+	State          string
+	Title          string
+	Host           string
+	Identifier     string
+	Kind           string
+	Workload       string
+	Reason         string
+	Message        string
+	RefreshSeconds int
+}
+
+// CaddyModule 返回模块信息
+func (Placeholder) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.gitspace_placeholder",
+		New: func() caddy.Module { return new(Placeholder) },
+	}
+}
+
+// Provision 加载模板并获取 k8s_router 应用实例
+func (p *Placeholder) Provision(ctx caddy.Context) error {
+	p.logger = ctx.Logger()
+
+	app, err := ctx.App("k8s_router")
+	if err != nil {
+		return fmt.Errorf("gitspace_placeholder requires the k8s_router app: %w", err)
+	}
+	p.router = app.(*K8sRouter)
+
+	if p.RefreshInterval == 0 {
+		p.RefreshInterval = caddy.Duration(5 * time.Second)
+	}
+	if p.State != "" && p.State != placeholderStopping {
+		return fmt.Errorf("unsupported placeholder state %q", p.State)
+	}
+
+	templates, err := parsePlaceholderTemplates(p.Templates)
+	if err != nil {
+		return err
+	}
+	p.templates = templates
+
+	return nil
+}
+
+// ServeHTTP 根据请求 Host 查找 gitspace 并渲染对应状态的页面
+func (p *Placeholder) ServeHTTP(w http.ResponseWriter, r *http.Request, _ caddyhttp.Handler) error {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if p.State != "" {
+		identifier, _ := p.identifierFromHost(host)
+		w.Header().Set("Retry-After", "30")
+		return p.render(w, placeholderData{State: p.State, Host: host, Identifier: identifier})
+	}
+
+	controller := p.router.controller.Load()
+	if controller == nil || !controller.watcher.IsReady() {
+		return caddyhttp.Error(http.StatusServiceUnavailable, fmt.Errorf("k8s router is not ready"))
+	}
+
+	data := placeholderData{
+		State: placeholderNotFound,
+		Host:  host,
+	}
+
+	if identifier, ok := p.identifierFromHost(host); ok {
+		data.Identifier = identifier
+
+		workload, err := findWorkloadByIdentifier(controller.watcher, identifier)
+		if err != nil {
+			return caddyhttp.Error(http.StatusInternalServerError, err)
+		}
+		if workload != nil {
+			data.Kind = workload.Kind()
+			data.Workload = workload.GetName()
+			data.State, data.Reason, data.Message = p.gitspaceState(r.Context(), controller, workload)
+		}
+	}
+
+	if data.State == placeholderStarting {
+		data.RefreshSeconds = int(time.Duration(p.RefreshInterval).Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(data.RefreshSeconds))
+	}
+	return p.render(w, data)
+}
+
+// parsePlaceholderTemplates 解析占位页面模板
+// 所有状态默认使用 defaultPlaceholderTemplate，overrides 按状态指定覆盖模板的文件路径；
+// 覆盖未知状态、文件无法读取或模板语法错误时返回错误
+func parsePlaceholderTemplates(overrides map[string]string) (map[string]*template.Template, error) {
+	defaultTmpl, err := template.New("placeholder").Parse(defaultPlaceholderTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse default placeholder template: %w", err)
+	}
+
+	templates := make(map[string]*template.Template, len(placeholderTitles))
+	for state := range placeholderTitles {
+		templates[state] = defaultTmpl
+	}
+
+	for state, path := range overrides {
+		if _, known := placeholderTitles[state]; !known {
+			return nil, fmt.Errorf("unknown placeholder template %q", state)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read placeholder template %s: %w", path, err)
+		}
+		tmpl, err := template.New(state).Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse placeholder template %s: %w", path, err)
+		}
+		templates[state] = tmpl
+	}
+
+	return templates, nil
+}
+
+// render 渲染对应状态的页面
+func (p *Placeholder) render(w http.ResponseWriter, data placeholderData) error {
+	data.Title = placeholderTitles[data.State]
+
+	var buf bytes.Buffer
+	if err := p.templates[data.State].Execute(&buf, data); err != nil {
+		p.logger.Error("Failed to render placeholder template",
+			zap.String("state", data.State),
+			zap.Error(err),
+		)
+		return caddyhttp.Error(http.StatusInternalServerError, err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(placeholderStatusCodes[data.State])
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// identifierFromHost 从 "<identifier>.<base_domain>" 中提取 gitspace identifier
+func (p *Placeholder) identifierFromHost(host string) (string, bool) {
+	suffix := "." + p.router.config.BaseDomain
+	if !strings.HasSuffix(host, suffix) {
+		return "", false
+	}
+	identifier := strings.TrimSuffix(host, suffix)
+	if identifier == "" || strings.Contains(identifier, ".") {
+		return "", false
+	}
+	return identifier, true
+}
+
+// findWorkloadByIdentifier 在 Informer 缓存中查找 identifier 的所有者
+func findWorkloadByIdentifier(watcher *k8s.Watcher, identifier string) (k8s.Workload, error) {
+	workloads, err := watcher.ListWorkloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workloads: %w", err)
+	}
+	if owner := k8s.IdentifierOwners(workloads)[identifier]; owner != nil {
+		return owner, nil
+	}
+
+	// 没有所有者时可能只剩已结束的独立 Pod（用于展示失败状态）
+	for _, workload := range workloads {
+		if k8s.GetGitspaceIdentifier(workload) == identifier {
+			return workload, nil
+		}
+	}
+	return nil, nil
+}
+
+// gitspaceState 根据工作负载及其当前 Pod 判断 gitspace 所处阶段
+// 查询 Pod 失败时只根据工作负载状态判断
+func (p *Placeholder) gitspaceState(ctx context.Context, controller *routerController, workload k8s.Workload) (state, reason, message string) {
+	var pod *corev1.Pod
+	if _, standalone := workload.(k8s.PodWorkload); !standalone && workload.DesiredReplicas() > 0 {
+		var err error
+		if pod, err = latestPod(ctx, controller.k8sClient, workload); err != nil {
+			p.logger.Warn("Failed to look up gitspace pod",
+				zap.String("workload", k8s.WorkloadKey(workload)),
+				zap.Error(err),
+			)
+		}
+	}
+	return k8s.GitspaceState(workload, pod)
+}
+
+// latestPod 查找工作负载当前版本最新的 Pod（不要求就绪）
+func latestPod(ctx context.Context, client kubernetes.Interface, workload k8s.Workload) (*corev1.Pod, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	pods, err := client.CoreV1().Pods(workload.GetNamespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(workload.PodSelector()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	revisionKey, revisionValue, err := k8s.CurrentRevisionLabel(ctx, client, workload)
+	if err != nil {
+		revisionKey, revisionValue = "", ""
+	}
+	return k8s.SelectLatestPod(pods.Items, revisionKey, revisionValue), nil
+}
+
+// parsePlaceholderDirective 解析 Caddyfile 中的 gitspace_placeholder 指令
+//
+//	gitspace_placeholder {
+//	    template <starting|stopped|failed|not_found|stopping> <path>
+//	    refresh_interval <duration>
+//	}
+func parsePlaceholderDirective(h httpcaddyfile.Helper) (caddyhttp.MiddlewareHandler, error) {
+	p := new(Placeholder)
+	if err := p.UnmarshalCaddyfile(h.Dispenser); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UnmarshalCaddyfile 支持 Caddyfile 配置格式
+func (p *Placeholder) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	// 跳过指令名称
+	d.Next()
+
+	for d.NextBlock(0) {
+		switch d.Val() {
+		case "template":
+			var state, path string
+			if !d.Args(&state, &path) {
+				return d.ArgErr()
+			}
+			if p.Templates == nil {
+				p.Templates = make(map[string]string)
+			}
+			p.Templates[state] = path
+
+		case "refresh_interval":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			interval, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid refresh_interval: %v", err)
+			}
+			p.RefreshInterval = caddy.Duration(interval)
+
+		default:
+			return d.Errf("unrecognized subdirective: %s", d.Val())
+		}
+	}
+
+	return nil
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner           = (*Placeholder)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Placeholder)(nil)
+	_ caddyfile.Unmarshaler       = (*Placeholder)(nil)
+)
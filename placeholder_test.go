@@ -0,0 +1,75 @@
+package caddy2k8s
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParsePlaceholderTemplates 测试按状态覆盖占位页面模板
+func TestParsePlaceholderTemplates(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTemplate := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+		return path
+	}
+	failedPath := writeTemplate("failed.html", "custom {{.Identifier}}")
+	brokenPath := writeTemplate("broken.html", "{{if}}")
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      map[string]string // state -> 渲染结果中应包含的内容
+		wantErr   bool
+	}{
+		{
+			name: "默认模板用于所有状态",
+			want: map[string]string{
+				placeholderStarting: "Gitspace <code>ws</code> is starting",
+				placeholderFailed:   "Gitspace <code>ws</code> failed to start",
+				placeholderStopping: "Gitspace <code>ws</code> is stopping",
+			},
+		},
+		{
+			name:      "只覆盖指定状态",
+			overrides: map[string]string{placeholderFailed: failedPath},
+			want: map[string]string{
+				placeholderFailed:  "custom ws",
+				placeholderStopped: "Gitspace <code>ws</code> is stopped",
+			},
+		},
+		{"未知状态", map[string]string{"crashed": failedPath}, nil, true},
+		{"模板文件不存在", map[string]string{placeholderFailed: filepath.Join(dir, "missing.html")}, nil, true},
+		{"模板语法错误", map[string]string{placeholderFailed: brokenPath}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := parsePlaceholderTemplates(tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePlaceholderTemplates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(templates) != len(placeholderTitles) {
+				t.Errorf("Expected %d templates, got %d", len(placeholderTitles), len(templates))
+			}
+			for state, want := range tt.want {
+				var buf bytes.Buffer
+				if err := templates[state].Execute(&buf, placeholderData{State: state, Identifier: "ws"}); err != nil {
+					t.Fatalf("Failed to render %s template: %v", state, err)
+				}
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Template %s rendered %q, want it to contain %q", state, buf.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package caddy2k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+)
+
+func init() {
+	caddy.RegisterModule(PortForwarder{})
+}
+
+const (
+	// portForwardRouteID 按需端口转发路由的 ID（所有 gitspace 共用一条路由）
+	portForwardRouteID = "gitspace:ports"
+
+	// portVisibilityVar gitspace_ports 写入端口可见性的请求变量名
+	portVisibilityVar = "gitspace_visibility"
+)
+
+// 端口转发失败的原因，ServeHTTP 据此返回对应的状态码
+var (
+	errPortNotAllowed     = errors.New("port is not allowed for forwarding")
+	errGitspaceNotFound   = errors.New("gitspace not found")
+	errGitspaceNotRunning = errors.New("gitspace is not running")
+)
+
+// PortForwarder 将 <port>-<identifier>.<base_domain> 的请求转发到 gitspace Pod 的对应端口
+// 由 k8s_router 注入到端口转发路由中，不需要在 Caddyfile 中手动配置
+type PortForwarder struct {
+	router *K8sRouter
+	logger *zap.Logger
+}
+
+// CaddyModule 返回模块信息
+func (PortForwarder) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.gitspace_ports",
+		New: func() caddy.Module { return new(PortForwarder) },
+	}
+}
+
+// Provision 获取 k8s_router 应用实例
+func (p *PortForwarder) Provision(ctx caddy.Context) error {
+	p.logger = ctx.Logger()
+
+	app, err := ctx.App("k8s_router")
+	if err != nil {
+		return fmt.Errorf("gitspace_ports requires the k8s_router app: %w", err)
+	}
+	p.router = app.(*K8sRouter)
+	return nil
+}
+
+// ServeHTTP 解析请求的端口和 identifier，检查白名单和可见性后把上游地址交给后续的 reverse_proxy
+func (p *PortForwarder) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	controller := p.router.controller.Load()
+	if controller == nil {
+		return caddyhttp.Error(http.StatusServiceUnavailable, fmt.Errorf("k8s router is not started"))
+	}
+
+	port, identifier, ok := parsePortHost(r.Host, p.router.config.BaseDomain)
+	if !ok {
+		return caddyhttp.Error(http.StatusNotFound, fmt.Errorf("host %s is not a port forwarding host", r.Host))
+	}
+
+	forward, err := controller.resolveForwardedPort(identifier, port)
+	if err != nil {
+		switch {
+		case errors.Is(err, errPortNotAllowed):
+			return caddyhttp.Error(http.StatusForbidden, err)
+		case errors.Is(err, errGitspaceNotFound):
+			return caddyhttp.Error(http.StatusNotFound, err)
+		default:
+			return caddyhttp.Error(http.StatusServiceUnavailable, err)
+		}
+	}
+
+	caddyhttp.SetVar(r.Context(), portVisibilityVar, forward.visibility)
+	if handled, err := controller.authorize(w, r, forward.workload, forward.visibility); err != nil || handled {
+		return err
+	}
+
+	// 端口上的流量同样计入 gitspace 的活动，避免被空闲缩容
+	routeActivity.Begin(forward.routeID)
+	defer routeActivity.End(forward.routeID)
+
+	// 后续的 gitspace_ratelimit 按目标 gitspace 的设置限流
+	if limit := controller.eventHandler.gitspaceRateLimit(forward.workload, forward.routeID); limit != nil {
+		caddyhttp.SetVar(r.Context(), rateLimitVar, limit)
+	}
+
+	caddyhttp.SetVar(r.Context(), activatorUpstreamVar, forward.target)
+	return next.ServeHTTP(w, r)
+}
+
+// parsePortHost 从 "<port>-<identifier>.<base_domain>" 中提取端口和 gitspace identifier
+// host 可以带端口，比较时忽略大小写，返回的 identifier 为小写；
+// 端口必须是 1-65535 的十进制数字，是否允许转发由调用方按白名单检查
+func parsePortHost(host, baseDomain string) (int, string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	label, found := strings.CutSuffix(host, "."+strings.ToLower(baseDomain))
+	if !found || label == "" || strings.Contains(label, ".") {
+		return 0, "", false
+	}
+	portStr, identifier, found := strings.Cut(label, "-")
+	if !found || identifier == "" || portStr == "" || len(portStr) > 5 {
+		return 0, "", false
+	}
+	for _, r := range portStr {
+		if r < '0' || r > '9' {
+			return 0, "", false
+		}
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, "", false
+	}
+	return port, identifier, true
+}
+
+// forwardedPort 一次端口转发请求的解析结果
+type forwardedPort struct {
+	workload   k8s.Workload
+	target     string // Pod IP:端口
+	routeID    string // gitspace 主路由的 ID
+	visibility string // 端口可见性
+}
+
+// resolveForwardedPort 查找 identifier 对应的 gitspace 当前路由的 Pod，返回该 Pod 指定端口的上游地址
+func (c *routerController) resolveForwardedPort(identifier string, port int) (*forwardedPort, error) {
+	if !c.config().GetPortForwardingRanges().Contains(port) {
+		return nil, fmt.Errorf("%w: %d", errPortNotAllowed, port)
+	}
+
+	workload, err := findWorkloadByIdentifier(c.watcher, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if workload == nil {
+		return nil, fmt.Errorf("%w: %s", errGitspaceNotFound, identifier)
+	}
+
+	// 复用主路由当前指向的 Pod，端口转发不会唤醒缩容的 gitspace
+	routeInfo, exists := c.tracker.Get(k8s.WorkloadKey(workload))
+	if !exists || routeInfo.TargetAddr == activatorUpstream {
+		return nil, fmt.Errorf("%w: %s", errGitspaceNotRunning, identifier)
+	}
+	host, _, err := net.SplitHostPort(routeInfo.TargetAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid route target %s: %w", routeInfo.TargetAddr, err)
+	}
+
+	// 注解无效时按 private 处理，不会意外公开端口
+	visibility, err := k8s.PortVisibility(workload.GetAnnotations(), port, c.config().PortForwardingVisibility)
+	if err != nil {
+		c.logger.Warn("Invalid port visibility annotation, treating port as private",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+		visibility = k8s.VisibilityPrivate
+	}
+
+	return &forwardedPort{
+		workload:   workload,
+		target:     router.JoinTarget(host, port),
+		routeID:    routeInfo.RouteID,
+		visibility: visibility,
+	}, nil
+}
+
+// portForwardRouteSpec 构造端口转发路由：按正则匹配 <port>-<identifier>.<base_domain>，
+// 由 gitspace_ports 解析上游，gitspace_ratelimit 按目标 gitspace 限流后交给 reverse_proxy
+func (h *EventHandler) portForwardRouteSpec() router.RouteSpec {
+	return router.RouteSpec{
+		ID:          portForwardRouteID,
+		HostPattern: `^[0-9]{1,5}-[^.]+\.` + regexp.QuoteMeta(h.baseDomain) + `(:[0-9]+)?$`,
+		Upstream:    activatorUpstream,
+		Handlers: []map[string]any{
+			{"handler": "gitspace_ports"},
+			{"handler": "gitspace_ratelimit", "route_id": portForwardRouteID, "per_gitspace": true},
+		},
+		StreamCloseDelay: h.settings().drainPeriod,
+	}
+}
+
+// ensurePortForwardRoute 开启端口转发时确保端口转发路由存在且配置一致
+func (c *routerController) ensurePortForwardRoute() error {
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := c.adminClient.ApplyRoute(ctx, c.eventHandler.portForwardRouteSpec()); err != nil {
+		return fmt.Errorf("failed to apply port forwarding route: %w", err)
+	}
+	return nil
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner           = (*PortForwarder)(nil)
+	_ caddyhttp.MiddlewareHandler = (*PortForwarder)(nil)
+)
@@ -0,0 +1,40 @@
+package caddy2k8s
+
+import "testing"
+
+// TestParsePortHost 测试从端口转发域名中解析端口和 identifier
+func TestParsePortHost(t *testing.T) {
+	tests := []struct {
+		name           string
+		host           string
+		wantPort       int
+		wantIdentifier string
+		wantOK         bool
+	}{
+		{"普通端口域名", "8080-ws.example.com", 8080, "ws", true},
+		{"带请求端口", "3000-ws.example.com:443", 3000, "ws", true},
+		{"大写域名", "8080-WS.Example.COM", 8080, "ws", true},
+		{"identifier 含连字符", "8080-my-ws.example.com", 8080, "my-ws", true},
+		{"最大端口", "65535-ws.example.com", 65535, "ws", true},
+		{"IPv6 地址", "[::1]:8080", 0, "", false},
+		{"IPv6 地址不带端口", "::1", 0, "", false},
+		{"端口为 0", "0-ws.example.com", 0, "", false},
+		{"端口超出范围", "70000-ws.example.com", 0, "", false},
+		{"端口带符号", "+80-ws.example.com", 0, "", false},
+		{"缺少端口", "ws.example.com", 0, "", false},
+		{"缺少 identifier", "8080-.example.com", 0, "", false},
+		{"多级子域名", "8080-ws.team.example.com", 0, "", false},
+		{"其他域名", "8080-ws.example.org", 0, "", false},
+		{"基础域名本身", "example.com", 0, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, identifier, ok := parsePortHost(tt.host, "example.com")
+			if port != tt.wantPort || identifier != tt.wantIdentifier || ok != tt.wantOK {
+				t.Errorf("parsePortHost(%q) = (%d, %q, %v), want (%d, %q, %v)",
+					tt.host, port, identifier, ok, tt.wantPort, tt.wantIdentifier, tt.wantOK)
+			}
+		})
+	}
+}
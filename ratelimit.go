@@ -0,0 +1,303 @@
+package caddy2k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ysicing/caddy2-gitspace/config"
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+func init() {
+	caddy.RegisterModule(RateLimit{})
+}
+
+const (
+	// throttleChunkSize 带宽限制时每次写入的最大字节数，避免大块写入造成突发
+	throttleChunkSize = 32 * 1024
+
+	// rateLimitVar gitspace_ports 写入目标 gitspace 限流设置（*RateLimit）的请求变量名
+	rateLimitVar = "gitspace_ratelimit"
+)
+
+// rateLimiters 请求限流和带宽限制的令牌桶
+// 与 routeActivity 一样使用包级变量，使令牌桶跨越 Caddy 配置重载
+var rateLimiters = router.NewRateLimiters()
+
+// rateLimitRejected 被限流拒绝的请求数，按路由和限流范围（client、route）统计
+var rateLimitRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "caddy",
+	Subsystem: "gitspace",
+	Name:      "ratelimit_rejected_total",
+	Help:      "Number of requests rejected by gitspace rate limits.",
+}, []string{"route", "scope"})
+
+// registerRateLimitMetrics 将限流指标注册到当前配置的指标 registry（每次配置加载都会新建 registry）
+func registerRateLimitMetrics(ctx caddy.Context) error {
+	if err := ctx.GetMetricsRegistry().Register(rateLimitRejected); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &registered) {
+			return fmt.Errorf("failed to register rate limit metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// RateLimit 按客户端 IP 和路由限流、限制响应带宽的 HTTP 处理器
+// 由 k8s_router 注入到工作负载路由的最前面，不需要在 Caddyfile 中手动配置
+type RateLimit struct {
+	RouteID string `json:"route_id"`
+
+	// ClientRate、ClientBurst 每个客户端 IP 的令牌桶参数，ClientRate 为 0 时不限制
+	ClientRate  float64 `json:"client_rate,omitempty"`
+	ClientBurst int     `json:"client_burst,omitempty"`
+
+	// RouteRate、RouteBurst 路由所有请求共用的令牌桶参数，RouteRate 为 0 时不限制
+	RouteRate  float64 `json:"route_rate,omitempty"`
+	RouteBurst int     `json:"route_burst,omitempty"`
+
+	// Bandwidth 路由所有响应共用的带宽上限（每秒字节数），0 表示不限速
+	Bandwidth int64 `json:"bandwidth,omitempty"`
+
+	// PerGitspace 端口转发路由由所有 gitspace 共用，开启后改用 gitspace_ports 解析出的
+	// gitspace 的限流设置，并与该 gitspace 的主路由共用令牌桶
+	PerGitspace bool `json:"per_gitspace,omitempty"`
+
+	logger *zap.Logger
+}
+
+// CaddyModule 返回模块信息
+func (RateLimit) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.gitspace_ratelimit",
+		New: func() caddy.Module { return new(RateLimit) },
+	}
+}
+
+// Provision 初始化日志
+func (l *RateLimit) Provision(ctx caddy.Context) error {
+	l.logger = ctx.Logger()
+	return nil
+}
+
+// Validate 验证配置
+func (l *RateLimit) Validate() error {
+	if l.RouteID == "" {
+		return fmt.Errorf("gitspace_ratelimit requires route_id")
+	}
+	if (l.ClientRate > 0 && l.ClientBurst < 1) || (l.RouteRate > 0 && l.RouteBurst < 1) {
+		return fmt.Errorf("gitspace_ratelimit burst must be at least 1")
+	}
+	return nil
+}
+
+// ServeHTTP 先检查客户端令牌桶再检查路由令牌桶，超出时返回 429；通过的请求按需限制响应带宽
+func (l *RateLimit) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	if l.PerGitspace {
+		limit, ok := caddyhttp.GetVar(r.Context(), rateLimitVar).(*RateLimit)
+		if !ok || limit == nil {
+			return next.ServeHTTP(w, r)
+		}
+		limit.logger = l.logger
+		return limit.serve(w, r, next)
+	}
+	return l.serve(w, r, next)
+}
+
+// serve 按当前设置限流并调用后续处理器
+func (l *RateLimit) serve(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	now := time.Now()
+
+	if l.ClientRate > 0 {
+		key := l.RouteID + "|" + clientIP(r)
+		if ok, retryAfter := rateLimiters.Allow(key, l.ClientRate, l.ClientBurst, now); !ok {
+			return l.reject(w, r, "client", retryAfter)
+		}
+	}
+	if l.RouteRate > 0 {
+		if ok, retryAfter := rateLimiters.Allow(l.RouteID, l.RouteRate, l.RouteBurst, now); !ok {
+			return l.reject(w, r, "route", retryAfter)
+		}
+	}
+
+	if l.Bandwidth > 0 {
+		burst := int(min(l.Bandwidth, math.MaxInt32))
+		w = &throttledWriter{
+			ResponseWriter: w,
+			limiter:        rateLimiters.Limiter("bandwidth|"+l.RouteID, float64(l.Bandwidth), burst),
+			ctx:            r.Context(),
+		}
+	}
+	return next.ServeHTTP(w, r)
+}
+
+// reject 记录指标并返回 429，Retry-After 为下一个令牌的等待时间（向上取整到秒）
+func (l *RateLimit) reject(w http.ResponseWriter, r *http.Request, scope string, retryAfter time.Duration) error {
+	rateLimitRejected.WithLabelValues(l.RouteID, scope).Inc()
+	l.logger.Debug("Request rejected by rate limit",
+		zap.String("route_id", l.RouteID),
+		zap.String("scope", scope),
+		zap.String("client_ip", clientIP(r)),
+	)
+
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(retryAfter.Seconds())), 1)))
+	return caddyhttp.Error(http.StatusTooManyRequests, fmt.Errorf("%s rate limit exceeded for %s", scope, l.RouteID))
+}
+
+// throttledWriter 按令牌桶限制写入速度的 ResponseWriter
+// 升级后的连接（WebSocket）通过 Unwrap 取得原始连接，不受带宽限制
+type throttledWriter struct {
+	http.ResponseWriter
+	limiter *rate.Limiter
+	ctx     context.Context
+}
+
+// Write 分块等待令牌后写入
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), throttleChunkSize, w.limiter.Burst())
+		if err := w.limiter.WaitN(w.ctx, n); err != nil {
+			return written, err
+		}
+		m, err := w.ResponseWriter.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Unwrap 返回原始 ResponseWriter（供 http.ResponseController 使用）
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// rateLimitSettings 工作负载路由的限流设置
+type rateLimitSettings struct {
+	client    config.RateLimit
+	route     config.RateLimit
+	bandwidth int64
+}
+
+// applyRateLimitAnnotations 用工作负载注解覆盖限流设置
+func applyRateLimitAnnotations(settings rateLimitSettings, annotations map[string]string) (rateLimitSettings, error) {
+	if value, exists := annotations[k8s.AnnotationRateLimitClient]; exists {
+		limit, err := config.ParseRateLimit(value)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s annotation: %w", k8s.AnnotationRateLimitClient, err)
+		}
+		settings.client = limit
+	}
+	if value, exists := annotations[k8s.AnnotationRateLimitRoute]; exists {
+		limit, err := config.ParseRateLimit(value)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s annotation: %w", k8s.AnnotationRateLimitRoute, err)
+		}
+		settings.route = limit
+	}
+	if value, exists := annotations[k8s.AnnotationBandwidthLimit]; exists {
+		bandwidth, err := config.ParseBandwidth(value)
+		if err != nil {
+			return settings, fmt.Errorf("invalid %s annotation: %w", k8s.AnnotationBandwidthLimit, err)
+		}
+		settings.bandwidth = bandwidth
+	}
+	return settings, nil
+}
+
+// enabled 判断是否启用了任一限制
+func (s rateLimitSettings) enabled() bool {
+	return s.client.Enabled() || s.route.Enabled() || s.bandwidth > 0
+}
+
+// rateLimitSettingsFor 返回工作负载的限流设置，注解无效时返回全局配置和错误
+func (h *EventHandler) rateLimitSettingsFor(workload k8s.Workload) (rateLimitSettings, error) {
+	settings, err := applyRateLimitAnnotations(h.settings().rateLimits, workload.GetAnnotations())
+	if err != nil {
+		return h.settings().rateLimits, err
+	}
+	return settings, nil
+}
+
+// gitspaceRateLimit 返回端口转发请求使用的限流设置，未启用任何限制时返回 nil
+// routeID 为 gitspace 主路由的 ID，端口转发与主路由共用令牌桶
+func (h *EventHandler) gitspaceRateLimit(workload k8s.Workload, routeID string) *RateLimit {
+	// 注解无效的警告已在写入主路由时记录，这里直接使用全局配置
+	settings, _ := h.rateLimitSettingsFor(workload)
+	if !settings.enabled() {
+		return nil
+	}
+
+	limit := &RateLimit{RouteID: routeID, Bandwidth: settings.bandwidth}
+	if settings.client.Enabled() {
+		limit.ClientRate, limit.ClientBurst = settings.client.Rate, settings.client.Burst
+	}
+	if settings.route.Enabled() {
+		limit.RouteRate, limit.RouteBurst = settings.route.Rate, settings.route.Burst
+	}
+	return limit
+}
+
+// rateLimitFor 返回工作负载路由的限流处理器配置，未启用任何限制时返回 nil
+// 注解无效时记录警告并使用全局配置
+func (h *EventHandler) rateLimitFor(workload k8s.Workload, routeID string) map[string]any {
+	settings, err := h.rateLimitSettingsFor(workload)
+	if err != nil {
+		h.logger.Warn("Invalid rate limit annotation, using defaults",
+			zap.String("workload", k8s.WorkloadKey(workload)),
+			zap.Error(err),
+		)
+	}
+	if !settings.enabled() {
+		return nil
+	}
+
+	handler := map[string]any{
+		"handler":  "gitspace_ratelimit",
+		"route_id": routeID,
+	}
+	if settings.client.Enabled() {
+		handler["client_rate"] = settings.client.Rate
+		handler["client_burst"] = settings.client.Burst
+	}
+	if settings.route.Enabled() {
+		handler["route_rate"] = settings.route.Rate
+		handler["route_burst"] = settings.route.Burst
+	}
+	if settings.bandwidth > 0 {
+		handler["bandwidth"] = settings.bandwidth
+	}
+	return handler
+}
+
+// clientIP 返回客户端 IP：优先使用 Caddy 按 trusted_proxies 解析的地址，否则使用直连地址
+func clientIP(r *http.Request) string {
+	if ip, ok := caddyhttp.GetVar(r.Context(), caddyhttp.ClientIPVarKey).(string); ok && ip != "" {
+		return ip
+	}
+	if addr, err := remoteAddr(r); err == nil {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
+
+// Interface guards
+var (
+	_ caddy.Provisioner           = (*RateLimit)(nil)
+	_ caddy.Validator             = (*RateLimit)(nil)
+	_ caddyhttp.MiddlewareHandler = (*RateLimit)(nil)
+)
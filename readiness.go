@@ -0,0 +1,256 @@
+package caddy2k8s
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"github.com/ysicing/caddy2-gitspace/router"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// readinessProbeInterval 上游探测的重试间隔
+	readinessProbeInterval = time.Second
+
+	// readinessProbeRetryDelay 探测超时后重新探测的间隔
+	readinessProbeRetryDelay = 10 * time.Second
+)
+
+// OnGatedPodUpdate 处理声明了 route-ready readiness gate 的 Pod 容器就绪变化
+// Deployment/StatefulSet 的 status 在 Pod 写入 route-ready 条件前不会变化，需要由 Pod 事件驱动路由创建
+// 路由指向的 Pod 容器不再就绪时按工作负载变为未就绪处理，与未声明 readiness gate 的工作负载一致
+func (h *EventHandler) OnGatedPodUpdate(pod *corev1.Pod) error {
+	if !h.active() {
+		return nil
+	}
+
+	workload := h.podOwnerWorkload(pod)
+	if workload == nil {
+		return nil
+	}
+
+	workloadKey := k8s.WorkloadKey(workload)
+	lock := h.getWorkloadLock(workloadKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// 容器不再就绪：路由指向该 Pod 且没有其他可路由的 Pod 时按未就绪处理（抖动抑制、移除路由）
+	if !k8s.IsPodRoutable(pod) {
+		if workload.DesiredReplicas() != 1 || !h.routesToTarget(workload, pod) || h.workloadRoutable(workload) {
+			return nil
+		}
+		return h.handleUnready(workload)
+	}
+
+	// 路由已指向该 Pod 时只需补充 route-ready 条件；端口无法确定时由 syncWorkload 标记失败
+	if port, err := k8s.ResolveWorkloadPort(workload, h.settings().defaultPort); err == nil {
+		targetAddr := h.podTarget(pod, port)
+		if routeInfo, exists := h.tracker.Get(workloadKey); exists && router.SameTarget(routeInfo.TargetAddr, targetAddr) {
+			h.markRouteReady(workload, pod, targetAddr)
+			return nil
+		}
+	}
+
+	return h.syncWorkload(workload)
+}
+
+// routesToTarget 判断工作负载的路由是否指向该 Pod
+func (h *EventHandler) routesToTarget(workload k8s.Workload, pod *corev1.Pod) bool {
+	port, err := k8s.ResolveWorkloadPort(workload, h.settings().defaultPort)
+	if err != nil {
+		return false
+	}
+	routeInfo, exists := h.tracker.Get(k8s.WorkloadKey(workload))
+	return exists && router.SameTarget(routeInfo.TargetAddr, h.podTarget(pod, port))
+}
+
+// podOwnerWorkload 从 Informer 缓存中查找 selector 匹配 Pod 的工作负载，找不到时返回 nil
+func (h *EventHandler) podOwnerWorkload(pod *corev1.Pod) k8s.Workload {
+	if h.workloads == nil || !h.workloads.IsReady() {
+		return nil
+	}
+
+	workloads, err := h.workloads.ListWorkloads()
+	if err != nil {
+		h.logger.Warn("Failed to list workloads for pod", zap.Error(err))
+		return nil
+	}
+
+	for _, workload := range workloads {
+		if workload.Kind() == k8s.KindPod || workload.GetNamespace() != pod.Namespace || workload.PodSelector() == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(workload.PodSelector())
+		if err != nil || selector.Empty() {
+			continue
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return workload
+		}
+	}
+	return nil
+}
+
+// markRouteReady 在后台将 Pod 的 route-ready 条件设置为 True
+// 配置了 readiness_probe_path 时先探测上游，超时后将条件设置为 False，
+// 并在 readinessProbeRetryDelay 后重新探测，直到成功或路由不再指向该 Pod
+func (h *EventHandler) markRouteReady(workload k8s.Workload, pod *corev1.Pod, targetAddr string) {
+	if !k8s.PodHasRouteReadinessGate(&pod.Spec) || k8s.RouteReadyStatus(pod) == corev1.ConditionTrue {
+		return
+	}
+
+	podKey := pod.Namespace + "/" + pod.Name
+	if _, probing := h.readinessProbes.LoadOrStore(podKey, struct{}{}); probing {
+		return
+	}
+
+	h.wg.Go(func() {
+		retry := h.setRouteReady(pod, k8s.GetGitspaceIdentifier(workload), targetAddr)
+		h.readinessProbes.Delete(podKey)
+		if retry {
+			h.retryRouteReady(workload, pod, targetAddr)
+		}
+	})
+}
+
+// setRouteReady 探测上游并写入 route-ready 条件，探测或写入失败时返回 true 表示需要重试
+func (h *EventHandler) setRouteReady(pod *corev1.Pod, identifier, targetAddr string) bool {
+	podKey := pod.Namespace + "/" + pod.Name
+
+	ready, reason, message := true, k8s.ReasonRouteReady, ""
+	if h.settings().readinessProbePath != "" {
+		if err := h.probeUpstream(identifier, targetAddr); err != nil {
+			if h.ctx.Err() != nil {
+				return false
+			}
+			h.logger.Warn("Upstream readiness probe failed",
+				zap.String("pod", podKey),
+				zap.String("target", targetAddr),
+				zap.Error(err),
+			)
+			ready, reason, message = false, k8s.ReasonProbeFailed, err.Error()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := k8s.PatchPodRouteReady(ctx, h.k8sClient, pod, ready, reason, message); err != nil {
+		h.logger.Warn("Failed to set route-ready condition",
+			zap.String("pod", podKey),
+			zap.Error(err),
+		)
+		return true
+	}
+
+	if ready {
+		h.logger.Info("Pod route ready",
+			zap.String("pod", podKey),
+			zap.String("target", targetAddr),
+		)
+	}
+	return !ready
+}
+
+// retryRouteReady 延迟后重新探测，路由已删除或切换到其他 Pod 时停止
+func (h *EventHandler) retryRouteReady(workload k8s.Workload, pod *corev1.Pod, targetAddr string) {
+	select {
+	case <-h.ctx.Done():
+		return
+	case <-time.After(readinessProbeRetryDelay):
+	}
+	if !h.active() {
+		return
+	}
+
+	workloadKey := k8s.WorkloadKey(workload)
+	lock := h.getWorkloadLock(workloadKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if routeInfo, exists := h.tracker.Get(workloadKey); !exists || !router.SameTarget(routeInfo.TargetAddr, targetAddr) {
+		return
+	}
+
+	// 读取最新的 Pod，Pod 已重建或结束时不再重试
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+	latest, err := h.k8sClient.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil || latest.UID != pod.UID || k8s.IsPodTerminated(latest) {
+		return
+	}
+
+	h.markRouteReady(workload, latest, targetAddr)
+}
+
+// probeUpstream 周期性请求上游的 readiness_probe_path，直到返回非 5xx 响应或超时
+// 开启 upstream_tls 时与路由一样使用 HTTPS 和客户端证书，并校验上游证书的 SAN
+func (h *EventHandler) probeUpstream(identifier, targetAddr string) error {
+	settings := h.settings()
+	ctx, cancel := context.WithTimeout(h.ctx, settings.readinessProbeTimeout)
+	defer cancel()
+
+	scheme := "http"
+	client := &http.Client{Timeout: 2 * time.Second}
+	if h.upstreamTLS != nil {
+		tlsConfig, err := h.upstreamTLS.clientTLSConfig(identifier)
+		if err != nil {
+			return fmt.Errorf("failed to prepare probe TLS config: %w", err)
+		}
+		scheme = "https"
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		defer client.CloseIdleConnections()
+	}
+
+	url := scheme + "://" + targetAddr + settings.readinessProbePath
+	if err := router.ProbeUpstream(ctx, client, url, readinessProbeInterval); err != nil {
+		return fmt.Errorf("upstream not ready within %s: %w", settings.readinessProbeTimeout, err)
+	}
+	return nil
+}
+
+// clearRouteReady 路由被删除或切换为唤醒路由后，将工作负载 Pod 的 route-ready 条件设置为 False
+func (h *EventHandler) clearRouteReady(workload k8s.Workload) {
+	if !k8s.HasRouteReadinessGate(workload) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 5*time.Second)
+	defer cancel()
+
+	var pods []corev1.Pod
+	if w, ok := workload.(k8s.PodWorkload); ok {
+		pods = []corev1.Pod{*w.Pod}
+	} else {
+		list, err := h.k8sClient.CoreV1().Pods(workload.GetNamespace()).List(ctx, metav1.ListOptions{
+			LabelSelector: metav1.FormatLabelSelector(workload.PodSelector()),
+		})
+		if err != nil {
+			h.logger.Warn("Failed to list pods for route-ready condition",
+				zap.String("workload", k8s.WorkloadKey(workload)),
+				zap.Error(err),
+			)
+			return
+		}
+		pods = list.Items
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		if k8s.RouteReadyStatus(pod) != corev1.ConditionTrue {
+			continue
+		}
+		if err := k8s.PatchPodRouteReady(ctx, h.k8sClient, pod, false, k8s.ReasonRouteRemoved, "route removed from caddy"); err != nil {
+			h.logger.Warn("Failed to clear route-ready condition",
+				zap.String("pod", pod.Namespace+"/"+pod.Name),
+				zap.Error(err),
+			)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"sync"
+	"time"
+)
+
+// ActivityTracker 记录每条路由最近的请求时间和进行中的连接数
+// 线程安全，WebSocket 等升级连接在整个生命周期内都计为进行中
+type ActivityTracker struct {
+	// routes 映射: routeID → routeActivity
+	routes map[string]*routeActivity
+	mu     sync.Mutex
+
+	// now 返回当前时间，测试中可替换
+	now func() time.Time
+}
+
+// routeActivity 单条路由的活动状态
+type routeActivity struct {
+	lastActivity time.Time
+	active       int
+}
+
+// NewActivityTracker 创建新的 ActivityTracker
+func NewActivityTracker() *ActivityTracker {
+	return &ActivityTracker{
+		routes: make(map[string]*routeActivity),
+		now:    time.Now,
+	}
+}
+
+// Begin 记录一个请求或连接开始
+func (t *ActivityTracker) Begin(routeID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	activity, exists := t.routes[routeID]
+	if !exists {
+		activity = &routeActivity{}
+		t.routes[routeID] = activity
+	}
+	activity.active++
+	activity.lastActivity = t.now()
+}
+
+// End 记录一个请求或连接结束
+func (t *ActivityTracker) End(routeID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	activity, exists := t.routes[routeID]
+	if !exists {
+		return
+	}
+	if activity.active > 0 {
+		activity.active--
+	}
+	activity.lastActivity = t.now()
+}
+
+// LastActivity 返回路由最近的活动时间
+// 存在进行中的连接时返回当前时间；没有任何记录时 exists 为 false
+func (t *ActivityTracker) LastActivity(routeID string) (last time.Time, exists bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	activity, exists := t.routes[routeID]
+	if !exists {
+		return time.Time{}, false
+	}
+	if activity.active > 0 {
+		return t.now(), true
+	}
+	return activity.lastActivity, true
+}
+
+// Active 返回路由当前进行中的请求和连接数
+func (t *ActivityTracker) Active(routeID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if activity, exists := t.routes[routeID]; exists {
+		return activity.active
+	}
+	return 0
+}
+
+// Forget 清除路由的活动记录（进行中的连接仍会保留计数）
+func (t *ActivityTracker) Forget(routeID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if activity, exists := t.routes[routeID]; exists && activity.active == 0 {
+		delete(t.routes, routeID)
+	}
+}
+
+// ActivityRefreshInterval 未设置空闲超时的工作负载持续活跃时刷新最近活动时间注解的间隔
+const ActivityRefreshInterval = 10 * time.Minute
+
+// LastActivityDue 判断是否需要写回最近活动时间注解
+// 只在路由由活跃变为空闲（最近一个检查周期内没有新的活动）时写回；持续活跃时只在注解
+// 早于 idleTimeout - checkPeriod（未设置空闲超时时为 ActivityRefreshInterval）时写回，
+// 避免每个检查周期都更新工作负载、触发 update 事件
+func LastActivityDue(now, annotated, observed time.Time, idleTimeout, checkPeriod time.Duration) bool {
+	// 注解精度为秒
+	if !observed.Truncate(time.Second).After(annotated) {
+		return false
+	}
+	if now.Sub(observed) >= checkPeriod {
+		return true
+	}
+	if idleTimeout <= 0 {
+		return now.Sub(annotated) >= ActivityRefreshInterval
+	}
+	return now.Sub(annotated) >= idleTimeout-checkPeriod
+}
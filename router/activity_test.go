@@ -0,0 +1,105 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock 测试用的可控时钟
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestActivityTracker 创建使用可控时钟的 ActivityTracker
+func newTestActivityTracker() (*ActivityTracker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tracker := NewActivityTracker()
+	tracker.now = clock.Now
+	return tracker, clock
+}
+
+// TestActivityTrackerActiveConnection 测试进行中的连接始终视为活跃
+func TestActivityTrackerActiveConnection(t *testing.T) {
+	tracker, clock := newTestActivityTracker()
+
+	if _, exists := tracker.LastActivity("ws"); exists {
+		t.Fatal("Expected no activity before any request")
+	}
+
+	tracker.Begin("ws")
+	clock.Advance(time.Minute)
+
+	last, exists := tracker.LastActivity("ws")
+	if !exists {
+		t.Fatal("Expected activity after Begin")
+	}
+	if !last.Equal(clock.Now()) {
+		t.Errorf("Expected open connection to report current time %v, got %v", clock.Now(), last)
+	}
+
+	tracker.End("ws")
+	ended := clock.Now()
+	clock.Advance(time.Minute)
+
+	last, _ = tracker.LastActivity("ws")
+	if !last.Equal(ended) {
+		t.Errorf("Expected last activity to stay at connection end, got %v want %v", last, ended)
+	}
+	if tracker.Active("ws") != 0 {
+		t.Errorf("Expected 0 active connections, got %d", tracker.Active("ws"))
+	}
+}
+
+// TestActivityTrackerForgetKeepsActive 测试 Forget 不会丢弃进行中的连接
+func TestActivityTrackerForgetKeepsActive(t *testing.T) {
+	tracker := NewActivityTracker()
+
+	tracker.Begin("ws")
+	tracker.Forget("ws")
+	if tracker.Active("ws") != 1 {
+		t.Fatalf("Expected active connection to survive Forget, got %d", tracker.Active("ws"))
+	}
+
+	tracker.End("ws")
+	tracker.Forget("ws")
+	if _, exists := tracker.LastActivity("ws"); exists {
+		t.Error("Expected activity to be forgotten after connection ended")
+	}
+}
+
+// TestLastActivityDue 测试最近活动时间注解的写回时机
+func TestLastActivityDue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	idleTimeout := 30 * time.Minute
+	checkPeriod := time.Minute
+
+	tests := []struct {
+		name        string
+		idleTimeout time.Duration
+		annotated   time.Time
+		observed    time.Time
+		want        bool
+	}{
+		{"没有新的活动", idleTimeout, now.Add(-5 * time.Minute), now.Add(-5 * time.Minute), false},
+		{"同一秒内的活动", idleTimeout, now.Add(-5 * time.Minute), now.Add(-5*time.Minute + 500*time.Millisecond), false},
+		{"由活跃变为空闲", idleTimeout, now.Add(-10 * time.Minute), now.Add(-2 * time.Minute), true},
+		{"持续活跃且注解较新", idleTimeout, now.Add(-10 * time.Minute), now, false},
+		{"持续活跃且注解即将过期", idleTimeout, now.Add(-29 * time.Minute), now, true},
+		{"从未写入注解", idleTimeout, time.Time{}, now, true},
+		{"未设置空闲超时且由活跃变为空闲", 0, now.Add(-5 * time.Minute), now.Add(-2 * time.Minute), true},
+		{"未设置空闲超时且持续活跃", 0, now.Add(-5 * time.Minute), now, false},
+		{"未设置空闲超时且注解超过刷新间隔", 0, now.Add(-ActivityRefreshInterval), now, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LastActivityDue(now, tt.annotated, tt.observed, tt.idleTimeout, checkPeriod); got != tt.want {
+				t.Errorf("LastActivityDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
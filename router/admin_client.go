@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"reflect"
 	"strings"
 	"time"
 )
@@ -17,13 +18,18 @@ type AdminAPIClient struct {
 	baseURL    string // http://localhost:2019
 	serverName string // srv0
 	httpClient *http.Client
+	writeGuard WriteGuard
 }
 
+// WriteGuard 在写入路由（创建、替换、删除）前调用
+// 返回释放函数；返回错误时放弃本次写入
+type WriteGuard func() (release func(), err error)
+
 // RouteConfig 路由配置（从 Caddy 返回）
 type RouteConfig struct {
 	ID         string // @id
 	Domain     string // match.host[0]
-	TargetAddr string // upstreams[0].dial (格式: "ip:port")
+	TargetAddr string // upstreams[0].dial（规范化后的 "ip:port"，IPv6 为 "[ip]:port"）
 }
 
 // NewAdminAPIClient 创建新的 AdminAPIClient
@@ -53,6 +59,46 @@ func NewAdminAPIClient(baseURL, serverName string) *AdminAPIClient {
 	}
 }
 
+// RouteSpec 描述一条由插件生成的路由
+// Upstream 可以是 "ip:port"，也可以是请求时才解析的占位符（如 "{http.vars.gitspace_upstream}"）
+// Upstream 为空时路由只包含 Handlers（如返回固定响应的路由）
+type RouteSpec struct {
+	ID       string           // @id
+	Domain   string           // match.host[0]
+	Upstream string           // reverse_proxy upstreams[0].dial
+	Handlers []map[string]any // 插入到 reverse_proxy 之前的处理器（按顺序执行）
+
+	AliasDomains []string // 除 Domain 外额外匹配的域名（match.host[1:]）
+	HostPattern  string   // 按正则匹配 Host（match.header_regexp），设置后忽略 Domain 和 AliasDomains
+	PathPrefix   string   // 只匹配该路径前缀（match.path），为空时匹配所有路径
+
+	// AllowCIDRs 允许访问的客户端地址段，不在其中的请求在所有处理器之前返回 403，为空时不限制
+	AllowCIDRs []string
+
+	// StreamCloseDelay 配置重载后保留已升级连接（WebSocket）的时长
+	// 路由被替换或重载时，已建立的连接继续使用旧上游，直到结束或超过该时长
+	StreamCloseDelay time.Duration
+
+	// HealthChecks reverse_proxy 的 health_checks 配置（active、passive），为 nil 时不启用
+	HealthChecks map[string]any
+
+	// Transport reverse_proxy 的 transport 配置（如上游 HTTPS），为 nil 时使用默认的 HTTP transport
+	Transport map[string]any
+}
+
+// SetWriteGuard 设置路由写入前的检查，用于串行化多个写入方并拒绝过期的写入方
+func (c *AdminAPIClient) SetWriteGuard(guard WriteGuard) {
+	c.writeGuard = guard
+}
+
+// acquireWrite 执行 WriteGuard，未设置时直接放行
+func (c *AdminAPIClient) acquireWrite() (func(), error) {
+	if c.writeGuard == nil {
+		return func() {}, nil
+	}
+	return c.writeGuard()
+}
+
 // CreateRoute 通过 Admin API 创建路由（幂等操作）
 // 会先检查路由是否已存在，如果存在且配置一致则跳过创建
 func (c *AdminAPIClient) CreateRoute(
@@ -61,12 +107,6 @@ func (c *AdminAPIClient) CreateRoute(
 	targetPort int,
 ) error {
 	// 参数验证
-	if routeID == "" {
-		return fmt.Errorf("routeID cannot be empty")
-	}
-	if domain == "" {
-		return fmt.Errorf("domain cannot be empty")
-	}
 	if net.ParseIP(targetIP) == nil {
 		return fmt.Errorf("invalid IP address: %s", targetIP)
 	}
@@ -74,45 +114,52 @@ func (c *AdminAPIClient) CreateRoute(
 		return fmt.Errorf("port out of range (1-65535): %d", targetPort)
 	}
 
-	expectedTargetAddr := fmt.Sprintf("%s:%d", targetIP, targetPort)
+	return c.ApplyRoute(ctx, RouteSpec{
+		ID:       routeID,
+		Domain:   domain,
+		Upstream: JoinTarget(targetIP, targetPort),
+	})
+}
+
+// ApplyRoute 按 RouteSpec 创建或更新路由（幂等操作）
+// 如果路由已存在且完整配置一致则跳过，否则原地替换已有路由
+func (c *AdminAPIClient) ApplyRoute(ctx context.Context, spec RouteSpec) error {
+	// 参数验证
+	if spec.ID == "" {
+		return fmt.Errorf("routeID cannot be empty")
+	}
+	if spec.Domain == "" {
+		return fmt.Errorf("domain cannot be empty")
+	}
+	if spec.Upstream == "" && len(spec.Handlers) == 0 {
+		return fmt.Errorf("upstream and handlers cannot both be empty")
+	}
+
+	release, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// 构造路由配置
+	routeConfig := buildRouteConfig(spec)
 
 	// 幂等性检查: 先查询路由是否已存在
-	existingRoute, err := c.GetRoute(ctx, routeID)
+	existing, err := c.getRawRoute(ctx, spec.ID)
 	if err != nil {
 		return fmt.Errorf("failed to check existing route: %w", err)
 	}
 
-	if existingRoute != nil {
+	if existing != nil {
 		// 路由已存在，检查配置是否一致
-		if existingRoute.Domain == domain && existingRoute.TargetAddr == expectedTargetAddr {
+		if sameRouteConfig(existing, routeConfig) {
 			// 配置完全一致，跳过创建（幂等）
 			return nil
 		}
 
-		// 配置不一致，先删除旧路由
-		if err := c.DeleteRoute(ctx, routeID); err != nil {
-			return fmt.Errorf("failed to delete old route before recreating: %w", err)
-		}
-	}
-
-	// 构造路由配置
-	routeConfig := map[string]any{
-		"@id": routeID,
-		"match": []map[string]any{
-			{
-				"host": []string{domain},
-			},
-		},
-		"handle": []map[string]any{
-			{
-				"handler": "reverse_proxy",
-				"upstreams": []map[string]string{
-					{
-						"dial": expectedTargetAddr,
-					},
-				},
-			},
-		},
+		// 配置不一致，原地替换路由：切换是原子的，不会出现路由缺失的窗口，
+		// 路由在列表中的位置也保持不变
+		return c.replaceRoute(ctx, spec.ID, routeConfig)
 	}
 
 	// 序列化为 JSON
@@ -147,6 +194,112 @@ func (c *AdminAPIClient) CreateRoute(
 	return fmt.Errorf("Caddy Admin API error: %d - %s", resp.StatusCode, string(body))
 }
 
+// replaceRoute 通过 PATCH /id/{routeID} 原地替换已有路由（调用方需已通过 WriteGuard）
+func (c *AdminAPIClient) replaceRoute(ctx context.Context, routeID string, routeConfig map[string]any) error {
+	payload, err := json.Marshal(routeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal route config: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/id/%s", c.baseURL, routeID)
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Caddy Admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("Caddy Admin API error: %d - %s", resp.StatusCode, string(body))
+}
+
+// buildRouteConfig 将 RouteSpec 转换为 Caddy 路由 JSON 结构
+func buildRouteConfig(spec RouteSpec) map[string]any {
+	handle := make([]map[string]any, 0, len(spec.Handlers)+2)
+	if len(spec.AllowCIDRs) > 0 {
+		handle = append(handle, denyOutsideCIDRs(spec.AllowCIDRs))
+	}
+	handle = append(handle, spec.Handlers...)
+	if spec.Upstream != "" {
+		proxy := map[string]any{
+			"handler": "reverse_proxy",
+			"upstreams": []map[string]string{
+				{
+					"dial": spec.Upstream,
+				},
+			},
+		}
+		if spec.StreamCloseDelay > 0 {
+			proxy["stream_close_delay"] = spec.StreamCloseDelay.String()
+		}
+		if len(spec.HealthChecks) > 0 {
+			proxy["health_checks"] = spec.HealthChecks
+		}
+		if len(spec.Transport) > 0 {
+			proxy["transport"] = spec.Transport
+		}
+		handle = append(handle, proxy)
+	}
+
+	match := map[string]any{}
+	if spec.HostPattern != "" {
+		match["header_regexp"] = map[string]any{
+			"Host": map[string]string{"pattern": spec.HostPattern},
+		}
+	} else {
+		match["host"] = append([]string{spec.Domain}, spec.AliasDomains...)
+	}
+	if spec.PathPrefix != "" {
+		match["path"] = []string{spec.PathPrefix + "*"}
+	}
+
+	return map[string]any{
+		"@id":    spec.ID,
+		"match":  []map[string]any{match},
+		"handle": handle,
+	}
+}
+
+// denyOutsideCIDRs 构造拒绝地址段外请求的子路由：remote_ip 不匹配时返回 403，匹配时继续执行后续处理器
+func denyOutsideCIDRs(cidrs []string) map[string]any {
+	return map[string]any{
+		"handler": "subroute",
+		"routes": []map[string]any{
+			{
+				"match": []map[string]any{
+					{"not": []map[string]any{{"remote_ip": map[string]any{"ranges": cidrs}}}},
+				},
+				"handle": []map[string]any{
+					{"handler": "static_response", "status_code": 403, "body": "Forbidden"},
+				},
+			},
+		},
+	}
+}
+
+// sameRouteConfig 比较 Caddy 中已有的路由与期望配置是否一致
+// 双方都经过一次 JSON 往返，消除 map/slice 具体类型和数字类型的差异
+func sameRouteConfig(existing map[string]any, expected map[string]any) bool {
+	payload, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(payload, &normalized); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(existing, normalized)
+}
+
 // DeleteRoute 通过 Admin API 删除路由
 // 如果路由不存在（404），不返回错误（幂等）
 // 使用 /id/{routeID} 端点直接删除配置
@@ -155,6 +308,18 @@ func (c *AdminAPIClient) DeleteRoute(ctx context.Context, routeID string) error
 		return fmt.Errorf("routeID cannot be empty")
 	}
 
+	release, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return c.deleteRoute(ctx, routeID)
+}
+
+// deleteRoute 删除路由（调用方需已通过 WriteGuard）
+func (c *AdminAPIClient) deleteRoute(ctx context.Context, routeID string) error {
+
 	// 使用 /id/ 端点删除配置
 	url := fmt.Sprintf("%s/id/%s", c.baseURL, routeID)
 
@@ -183,6 +348,16 @@ func (c *AdminAPIClient) DeleteRoute(ctx context.Context, routeID string) error
 // GetRoute 查询路由配置（可选，用于调试）
 // 使用 /id/{routeID} 端点直接访问配置
 func (c *AdminAPIClient) GetRoute(ctx context.Context, routeID string) (*RouteConfig, error) {
+	rawConfig, err := c.getRawRoute(ctx, routeID)
+	if err != nil || rawConfig == nil {
+		return nil, err
+	}
+	return parseRouteConfig(routeID, rawConfig), nil
+}
+
+// getRawRoute 查询路由的原始 JSON 配置
+// 路由不存在时返回 nil, nil
+func (c *AdminAPIClient) getRawRoute(ctx context.Context, routeID string) (map[string]any, error) {
 	if routeID == "" {
 		return nil, fmt.Errorf("routeID cannot be empty")
 	}
@@ -218,7 +393,11 @@ func (c *AdminAPIClient) GetRoute(ctx context.Context, routeID string) (*RouteCo
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	// 提取字段
+	return rawConfig, nil
+}
+
+// parseRouteConfig 从原始路由 JSON 中提取 RouteConfig
+func parseRouteConfig(routeID string, rawConfig map[string]any) *RouteConfig {
 	config := &RouteConfig{
 		ID: routeID,
 	}
@@ -232,18 +411,25 @@ func (c *AdminAPIClient) GetRoute(ctx context.Context, routeID string) (*RouteCo
 		}
 	}
 
-	// 提取 targetAddr（upstreams[0].dial）
-	if handle, ok := rawConfig["handle"].([]any); ok && len(handle) > 0 {
-		if handleItem, ok := handle[0].(map[string]any); ok {
+	// 提取 targetAddr（reverse_proxy 处理器的 upstreams[0].dial）
+	// reverse_proxy 之前可能还有其他处理器（如 gitspace_activator）
+	if handle, ok := rawConfig["handle"].([]any); ok {
+		for _, item := range handle {
+			handleItem, ok := item.(map[string]any)
+			if !ok || handleItem["handler"] != "reverse_proxy" {
+				continue
+			}
 			if upstreams, ok := handleItem["upstreams"].([]any); ok && len(upstreams) > 0 {
 				if upstream, ok := upstreams[0].(map[string]any); ok {
-					config.TargetAddr, _ = upstream["dial"].(string)
+					dial, _ := upstream["dial"].(string)
+					config.TargetAddr = NormalizeTargetAddr(dial)
 				}
 			}
+			break
 		}
 	}
 
-	return config, nil
+	return config
 }
 
 // ListRoutes 列出所有由插件管理的路由（用于恢复 RouteIDTracker）
@@ -284,27 +470,7 @@ func (c *AdminAPIClient) ListRoutes(ctx context.Context) ([]*RouteConfig, error)
 			continue
 		}
 
-		config := &RouteConfig{ID: id}
-
-		// 提取 domain（match.host[0]）
-		if match, ok := route["match"].([]any); ok && len(match) > 0 {
-			if matchItem, ok := match[0].(map[string]any); ok {
-				if hosts, ok := matchItem["host"].([]any); ok && len(hosts) > 0 {
-					config.Domain, _ = hosts[0].(string)
-				}
-			}
-		}
-
-		// 提取 targetAddr（upstreams[0].dial）
-		if handle, ok := route["handle"].([]any); ok && len(handle) > 0 {
-			if handleItem, ok := handle[0].(map[string]any); ok {
-				if upstreams, ok := handleItem["upstreams"].([]any); ok && len(upstreams) > 0 {
-					if upstream, ok := upstreams[0].(map[string]any); ok {
-						config.TargetAddr, _ = upstream["dial"].(string)
-					}
-				}
-			}
-		}
+		config := parseRouteConfig(id, route)
 
 		// 去重: 如果已存在相同 ID,覆盖之前的配置
 		configMap[id] = config
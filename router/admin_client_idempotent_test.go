@@ -3,6 +3,7 @@ package router
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -99,6 +100,8 @@ func TestCreateRouteIdempotent(t *testing.T) {
 func TestCreateRouteUpdateWhenChanged(t *testing.T) {
 	deleteCallCount := 0
 	postCallCount := 0
+	patchCallCount := 0
+	var patchedDial string
 
 	// 模拟服务器
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -137,6 +140,18 @@ func TestCreateRouteUpdateWhenChanged(t *testing.T) {
 			return
 		}
 
+		// PATCH 请求 - 原地替换路由
+		if r.Method == "PATCH" && r.URL.Path == "/id/test-deployment" {
+			patchCallCount++
+			var route map[string]any
+			json.NewDecoder(r.Body).Decode(&route)
+			handle := route["handle"].([]any)
+			proxy := handle[len(handle)-1].(map[string]any)
+			patchedDial = proxy["upstreams"].([]any)[0].(map[string]any)["dial"].(string)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
 		http.NotFound(w, r)
 	}))
 	defer server.Close()
@@ -150,12 +165,15 @@ func TestCreateRouteUpdateWhenChanged(t *testing.T) {
 		t.Fatalf("CreateRoute failed: %v", err)
 	}
 
-	// 应该先删除旧路由，再创建新路由
-	if deleteCallCount != 1 {
-		t.Errorf("Expected 1 DELETE call, got %d", deleteCallCount)
+	// 应该原地替换路由，而不是删除后重新创建
+	if patchCallCount != 1 {
+		t.Errorf("Expected 1 PATCH call, got %d", patchCallCount)
 	}
-	if postCallCount != 1 {
-		t.Errorf("Expected 1 POST call, got %d", postCallCount)
+	if deleteCallCount != 0 || postCallCount != 0 {
+		t.Errorf("Expected no DELETE/POST calls, got %d DELETE, %d POST", deleteCallCount, postCallCount)
+	}
+	if patchedDial != "10.0.0.2:8080" {
+		t.Errorf("Expected patched upstream 10.0.0.2:8080, got %q", patchedDial)
 	}
 }
 
@@ -213,3 +231,171 @@ func TestCreateRoutePreventsMultiplePOST(t *testing.T) {
 		t.Errorf("Expected 0 POST calls (all idempotent), got %d", postCallCount)
 	}
 }
+
+// TestApplyRouteWithHandlers 测试带前置处理器的路由幂等与上游解析
+func TestApplyRouteWithHandlers(t *testing.T) {
+	postCallCount := 0
+	var stored map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/id/") {
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(stored)
+			return
+		}
+
+		if r.Method == "POST" && strings.Contains(r.URL.Path, "/routes") {
+			postCallCount++
+			json.NewDecoder(r.Body).Decode(&stored)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := NewAdminAPIClient(server.URL, "srv0")
+	ctx := context.Background()
+
+	spec := RouteSpec{
+		ID:       "test-deployment",
+		Domain:   "test-deployment.example.com",
+		Upstream: "{http.vars.gitspace_upstream}",
+		Handlers: []map[string]any{
+			{"handler": "gitspace_activator", "namespace": "default", "deployment": "test"},
+		},
+	}
+
+	for i := range 3 {
+		if err := client.ApplyRoute(ctx, spec); err != nil {
+			t.Fatalf("ApplyRoute call %d failed: %v", i+1, err)
+		}
+	}
+
+	if postCallCount != 1 {
+		t.Errorf("Expected 1 POST call, got %d", postCallCount)
+	}
+
+	route, err := client.GetRoute(ctx, "test-deployment")
+	if err != nil {
+		t.Fatalf("GetRoute failed: %v", err)
+	}
+	if route.TargetAddr != spec.Upstream {
+		t.Errorf("Expected target %q, got %q", spec.Upstream, route.TargetAddr)
+	}
+}
+
+// TestApplyRouteWithAliasesAndPathPrefix 测试额外域名和路径前缀写入 match
+func TestApplyRouteWithAliasesAndPathPrefix(t *testing.T) {
+	var stored map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&stored)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewAdminAPIClient(server.URL, "srv0")
+
+	err := client.ApplyRoute(context.Background(), RouteSpec{
+		ID:           "gitspaceroute:default:docs",
+		Domain:       "docs.example.com",
+		AliasDomains: []string{"docs.internal.example.com"},
+		PathPrefix:   "/api/",
+		Upstream:     "docs.default.svc:8080",
+	})
+	if err != nil {
+		t.Fatalf("ApplyRoute failed: %v", err)
+	}
+
+	match := stored["match"].([]any)[0].(map[string]any)
+	hosts := match["host"].([]any)
+	if len(hosts) != 2 || hosts[0] != "docs.example.com" || hosts[1] != "docs.internal.example.com" {
+		t.Errorf("Unexpected hosts: %v", hosts)
+	}
+	paths := match["path"].([]any)
+	if len(paths) != 1 || paths[0] != "/api/*" {
+		t.Errorf("Unexpected paths: %v", paths)
+	}
+}
+
+// TestBuildRouteConfigWithTransport 测试上游 transport 写入 reverse_proxy，未设置时不生成
+func TestBuildRouteConfigWithTransport(t *testing.T) {
+	spec := RouteSpec{
+		ID:       "vscode",
+		Domain:   "vscode.example.com",
+		Upstream: "10.0.0.1:8080",
+	}
+	handle := buildRouteConfig(spec)["handle"].([]map[string]any)
+	if _, exists := handle[len(handle)-1]["transport"]; exists {
+		t.Errorf("Expected no transport by default, got %v", handle[len(handle)-1])
+	}
+
+	spec.Transport = map[string]any{
+		"protocol": "http",
+		"tls":      map[string]any{"server_name": "vscode"},
+	}
+	handle = buildRouteConfig(spec)["handle"].([]map[string]any)
+	proxy := handle[len(handle)-1]
+	transport, ok := proxy["transport"].(map[string]any)
+	if proxy["handler"] != "reverse_proxy" || !ok || transport["protocol"] != "http" {
+		t.Errorf("Unexpected reverse_proxy: %v", proxy)
+	}
+}
+
+// TestWriteGuardRejectsWrites 测试 WriteGuard 拒绝时不会发出写请求
+func TestWriteGuardRejectsWrites(t *testing.T) {
+	writeCallCount := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeCallCount++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewAdminAPIClient(server.URL, "srv0")
+	ctx := context.Background()
+
+	released := 0
+	allow := true
+	client.SetWriteGuard(func() (func(), error) {
+		if !allow {
+			return nil, errors.New("inactive")
+		}
+		return func() { released++ }, nil
+	})
+
+	if err := client.CreateRoute(ctx, "test-deployment", "test-deployment.example.com", "10.0.0.1", 8080); err != nil {
+		t.Fatalf("CreateRoute failed: %v", err)
+	}
+	if err := client.DeleteRoute(ctx, "test-deployment"); err != nil {
+		t.Fatalf("DeleteRoute failed: %v", err)
+	}
+	if writeCallCount != 2 || released != 2 {
+		t.Fatalf("Expected 2 writes and 2 releases, got %d writes and %d releases", writeCallCount, released)
+	}
+
+	allow = false
+	if err := client.CreateRoute(ctx, "test-deployment", "test-deployment.example.com", "10.0.0.1", 8080); err == nil {
+		t.Error("Expected CreateRoute to be rejected by write guard")
+	}
+	if err := client.DeleteRoute(ctx, "test-deployment"); err == nil {
+		t.Error("Expected DeleteRoute to be rejected by write guard")
+	}
+	if writeCallCount != 2 {
+		t.Errorf("Expected no further writes after rejection, got %d", writeCallCount)
+	}
+}
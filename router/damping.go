@@ -0,0 +1,40 @@
+package router
+
+import "time"
+
+// UnreadyAction 单副本工作负载变为未就绪时对路由的处理方式
+type UnreadyAction int
+
+const (
+	// UnreadyRemove 移除路由（仍受 min_route_change_interval 约束）
+	UnreadyRemove UnreadyAction = iota
+
+	// UnreadyKeepDegraded 保留指向运行中 Pod 的路由并标记为降级
+	UnreadyKeepDegraded
+
+	// UnreadyDelay 等待 unready_grace_period 结束后重新判断
+	UnreadyDelay
+)
+
+// DecideUnready 决定未就绪工作负载的路由处理方式
+// runningPod 表示开启了 keep_unready_pods 且路由指向的 Pod 仍在运行，此时保留降级路由；
+// 否则已有路由且设置了宽限期时延迟移除；其余情况立即移除
+func DecideUnready(runningPod, routed bool, gracePeriod time.Duration) UnreadyAction {
+	switch {
+	case runningPod:
+		return UnreadyKeepDegraded
+	case routed && gracePeriod > 0:
+		return UnreadyDelay
+	default:
+		return UnreadyRemove
+	}
+}
+
+// RouteChangeDelay 返回距下一次允许路由变化的剩余时间
+// 未设置最小间隔、从未记录变化或间隔已过时返回 0
+func RouteChangeDelay(lastChange time.Time, minInterval time.Duration, now time.Time) time.Duration {
+	if minInterval <= 0 || lastChange.IsZero() {
+		return 0
+	}
+	return max(minInterval-now.Sub(lastChange), 0)
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDecideUnready 测试未就绪工作负载的路由处理方式
+func TestDecideUnready(t *testing.T) {
+	tests := []struct {
+		name        string
+		runningPod  bool
+		routed      bool
+		gracePeriod time.Duration
+		want        UnreadyAction
+	}{
+		{"未开启抑制时立即移除", false, true, 0, UnreadyRemove},
+		{"路由指向的 Pod 仍在运行时保留降级路由", true, true, 0, UnreadyKeepDegraded},
+		{"保留降级路由优先于宽限期", true, true, time.Minute, UnreadyKeepDegraded},
+		{"已有路由时等待宽限期", false, true, time.Minute, UnreadyDelay},
+		{"没有路由时无需等待宽限期", false, false, time.Minute, UnreadyRemove},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecideUnready(tt.runningPod, tt.routed, tt.gracePeriod); got != tt.want {
+				t.Errorf("DecideUnready() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRouteChangeDelay 测试 min_route_change_interval 的剩余等待时间
+func TestRouteChangeDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		lastChange  time.Time
+		minInterval time.Duration
+		want        time.Duration
+	}{
+		{"未设置最小间隔", now.Add(-time.Second), 0, 0},
+		{"从未记录路由变化", time.Time{}, time.Minute, 0},
+		{"间隔未到时延迟剩余时间", now.Add(-20 * time.Second), time.Minute, 40 * time.Second},
+		{"刚好到达间隔", now.Add(-time.Minute), time.Minute, 0},
+		{"间隔已过", now.Add(-time.Hour), time.Minute, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RouteChangeDelay(tt.lastChange, tt.minInterval, now); got != tt.want {
+				t.Errorf("RouteChangeDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DrainTask 一次正在进行的路由排空
+type DrainTask struct {
+	RouteID string
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// Done 排空超时或被取消时关闭
+func (t *DrainTask) Done() <-chan struct{} {
+	return t.ctx.Done()
+}
+
+// DrainSet 记录正在排空的路由和排空后切换为 stopping 响应的路由，每个工作负载最多各一个
+// 线程安全
+type DrainSet struct {
+	// tasks 映射: workloadKey (kind/namespace/name) → DrainTask
+	tasks map[string]*DrainTask
+	// stopping 映射: workloadKey → 排空结束后返回 stopping 响应的路由 ID
+	stopping map[string]string
+	mu       sync.Mutex
+}
+
+// NewDrainSet 创建新的 DrainSet
+func NewDrainSet() *DrainSet {
+	return &DrainSet{
+		tasks:    make(map[string]*DrainTask),
+		stopping: make(map[string]string),
+	}
+}
+
+// Start 开始排空工作负载的路由，取代并取消该工作负载此前的排空
+// 返回的任务在 timeout 后或被取消时结束
+func (d *DrainSet) Start(parent context.Context, workloadKey, routeID string, timeout time.Duration) *DrainTask {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	task := &DrainTask{RouteID: routeID, ctx: ctx, cancel: cancel}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if previous, exists := d.tasks[workloadKey]; exists {
+		previous.cancel()
+	}
+	d.tasks[workloadKey] = task
+	return task
+}
+
+// Cancel 取消工作负载正在进行的排空（路由被重新创建或重定向时调用）
+// 重新创建的路由会替换 stopping 路由，因此一并清除其记录
+func (d *DrainSet) Cancel(workloadKey string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if task, exists := d.tasks[workloadKey]; exists {
+		task.cancel()
+		delete(d.tasks, workloadKey)
+	}
+	delete(d.stopping, workloadKey)
+}
+
+// Finish 排空结束时移除任务
+// 返回 false 表示任务已被取消或被新的排空取代，调用方不应再处理该路由
+func (d *DrainSet) Finish(workloadKey string, task *DrainTask) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	task.cancel()
+	if d.tasks[workloadKey] != task {
+		return false
+	}
+	delete(d.tasks, workloadKey)
+	return true
+}
+
+// IsDraining 判断路由是否正在排空（对账时不应删除排空中的路由）
+func (d *DrainSet) IsDraining(routeID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, task := range d.tasks {
+		if task.RouteID == routeID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStopping 记录工作负载排空结束后切换为 stopping 响应的路由
+func (d *DrainSet) SetStopping(workloadKey, routeID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopping[workloadKey] = routeID
+}
+
+// TakeStopping 取出并清除工作负载的 stopping 路由记录
+func (d *DrainSet) TakeStopping(workloadKey string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	routeID, exists := d.stopping[workloadKey]
+	delete(d.stopping, workloadKey)
+	return routeID, exists
+}
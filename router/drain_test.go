@@ -0,0 +1,152 @@
+package router
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// isDone 判断排空任务是否已结束
+func isDone(task *DrainTask) bool {
+	select {
+	case <-task.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// TestDrainSetIsDraining 测试对账期间排空中的路由不被视为孤立路由
+func TestDrainSetIsDraining(t *testing.T) {
+	drains := NewDrainSet()
+	task := drains.Start(context.Background(), "deployment/default/ws", "gitspace-ws", time.Minute)
+
+	if !drains.IsDraining("gitspace-ws") {
+		t.Error("Expected route to be draining after Start")
+	}
+	if drains.IsDraining("gitspace-other") {
+		t.Error("Expected unrelated route not to be draining")
+	}
+
+	if !drains.Finish("deployment/default/ws", task) {
+		t.Error("Expected Finish to succeed for current task")
+	}
+	if drains.IsDraining("gitspace-ws") {
+		t.Error("Expected route not to be draining after Finish")
+	}
+	if !isDone(task) {
+		t.Error("Expected task context to be released after Finish")
+	}
+}
+
+// TestDrainSetCancel 测试路由重新创建或重定向时取消排空
+func TestDrainSetCancel(t *testing.T) {
+	tests := []struct {
+		name       string
+		cancelKey  string
+		wantFinish bool
+	}{
+		{
+			name:       "同一工作负载重新创建路由时取消排空",
+			cancelKey:  "deployment/default/ws",
+			wantFinish: false,
+		},
+		{
+			name:       "其他工作负载不影响排空",
+			cancelKey:  "deployment/default/other",
+			wantFinish: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drains := NewDrainSet()
+			task := drains.Start(context.Background(), "deployment/default/ws", "gitspace-ws", time.Minute)
+
+			drains.Cancel(tt.cancelKey)
+
+			if isDone(task) == tt.wantFinish {
+				t.Errorf("Expected task done=%v after Cancel", !tt.wantFinish)
+			}
+			if drains.IsDraining("gitspace-ws") != tt.wantFinish {
+				t.Errorf("Expected IsDraining=%v after Cancel", tt.wantFinish)
+			}
+			if got := drains.Finish("deployment/default/ws", task); got != tt.wantFinish {
+				t.Errorf("Finish() = %v, want %v", got, tt.wantFinish)
+			}
+		})
+	}
+}
+
+// TestDrainSetStartSupersedes 测试同一工作负载新的排空取代旧的排空
+func TestDrainSetStartSupersedes(t *testing.T) {
+	drains := NewDrainSet()
+	first := drains.Start(context.Background(), "deployment/default/ws", "gitspace-old", time.Minute)
+	second := drains.Start(context.Background(), "deployment/default/ws", "gitspace-new", time.Minute)
+
+	if !isDone(first) {
+		t.Error("Expected superseded task to be cancelled")
+	}
+	if drains.Finish("deployment/default/ws", first) {
+		t.Error("Expected superseded task not to finish")
+	}
+	if drains.IsDraining("gitspace-old") {
+		t.Error("Expected superseded route not to be draining")
+	}
+	if !drains.IsDraining("gitspace-new") {
+		t.Error("Expected new route to be draining")
+	}
+	if !drains.Finish("deployment/default/ws", second) {
+		t.Error("Expected current task to finish")
+	}
+}
+
+// TestDrainSetTimeout 测试排空超过时长后任务结束但仍可完成
+func TestDrainSetTimeout(t *testing.T) {
+	drains := NewDrainSet()
+	task := drains.Start(context.Background(), "deployment/default/ws", "gitspace-ws", time.Millisecond)
+
+	select {
+	case <-task.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected task to time out")
+	}
+	if !drains.IsDraining("gitspace-ws") {
+		t.Error("Expected timed-out route to stay draining until Finish")
+	}
+	if !drains.Finish("deployment/default/ws", task) {
+		t.Error("Expected timed-out task to finish")
+	}
+}
+
+// TestDrainSetStopping 测试排空结束后的 stopping 路由记录：下一次同步取出，重新创建路由时清除
+func TestDrainSetStopping(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancelKey string
+		wantRoute string
+		wantOK    bool
+	}{
+		{"下一次同步取出 stopping 路由", "", "gitspace-ws", true},
+		{"重新创建路由时清除记录", "deployment/default/ws", "", false},
+		{"其他工作负载不影响记录", "deployment/default/other", "gitspace-ws", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drains := NewDrainSet()
+			drains.SetStopping("deployment/default/ws", "gitspace-ws")
+			if tt.cancelKey != "" {
+				drains.Cancel(tt.cancelKey)
+			}
+
+			routeID, ok := drains.TakeStopping("deployment/default/ws")
+			if routeID != tt.wantRoute || ok != tt.wantOK {
+				t.Errorf("TakeStopping() = (%q, %v), want (%q, %v)", routeID, ok, tt.wantRoute, tt.wantOK)
+			}
+			if _, ok := drains.TakeStopping("deployment/default/ws"); ok {
+				t.Error("Expected stopping route to be taken only once")
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// 上游健康状态
+const (
+	// UpstreamHealthy 上游健康（或未启用健康检查）
+	UpstreamHealthy = "Healthy"
+
+	// UpstreamUnhealthy 主动健康检查失败，或被动健康检查在 fail_duration 内记录了失败
+	UpstreamUnhealthy = "Unhealthy"
+)
+
+// UpstreamHealth 记录生成路由上游的健康状态
+// 主动检查结果来自 reverse_proxy 发出的 healthy/unhealthy 事件，被动检查结果来自 Admin API 的失败计数
+type UpstreamHealth struct {
+	mu           sync.RWMutex
+	activeDown   map[string]bool // key: 规范化的上游地址
+	passiveFails map[string]int  // key: 规范化的上游地址
+}
+
+// NewUpstreamHealth 创建 UpstreamHealth
+func NewUpstreamHealth() *UpstreamHealth {
+	return &UpstreamHealth{
+		activeDown:   make(map[string]bool),
+		passiveFails: make(map[string]int),
+	}
+}
+
+// SetActive 记录主动健康检查的结果
+func (u *UpstreamHealth) SetActive(addr string, healthy bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	addr = NormalizeTargetAddr(addr)
+	if healthy {
+		delete(u.activeDown, addr)
+	} else {
+		u.activeDown[addr] = true
+	}
+}
+
+// SetPassiveFails 用最新的失败计数替换被动健康检查的状态
+func (u *UpstreamHealth) SetPassiveFails(fails map[string]int) {
+	normalized := make(map[string]int, len(fails))
+	for addr, count := range fails {
+		if count > 0 {
+			normalized[NormalizeTargetAddr(addr)] = count
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.passiveFails = normalized
+}
+
+// Status 返回上游的健康状态
+func (u *UpstreamHealth) Status(addr string) string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	addr = NormalizeTargetAddr(addr)
+	if u.activeDown[addr] || u.passiveFails[addr] > 0 {
+		return UpstreamUnhealthy
+	}
+	return UpstreamHealthy
+}
+
+// ListUpstreamFails 通过 Admin API 查询所有 reverse_proxy 上游的被动健康检查失败计数
+func (c *AdminAPIClient) ListUpstreamFails(ctx context.Context) (map[string]int, error) {
+	url := fmt.Sprintf("%s/reverse_proxy/upstreams", c.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Caddy Admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Caddy Admin API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var upstreams []struct {
+		Address string `json:"address"`
+		Fails   int    `json:"fails"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&upstreams); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	fails := make(map[string]int, len(upstreams))
+	for _, upstream := range upstreams {
+		fails[upstream.Address] += upstream.Fails
+	}
+	return fails, nil
+}
@@ -0,0 +1,36 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ProbeUpstream 每隔 interval 请求一次 url，直到返回非 5xx 响应
+// ctx 结束时返回最后一次失败的原因
+func ProbeUpstream(ctx context.Context, client *http.Client, url string, interval time.Duration) error {
+	var lastErr error
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to build probe request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < http.StatusInternalServerError {
+				return nil
+			}
+			err = fmt.Errorf("probe %s returned status %d", url, resp.StatusCode)
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return lastErr
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestProbeUpstream 测试上游探测在 5xx 时重试、非 5xx 时成功
+func TestProbeUpstream(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32 // 返回 503 的次数
+		status    int   // 之后返回的状态码
+		wantErr   string
+		wantCalls int32
+	}{
+		{"立即就绪", 0, http.StatusOK, "", 1},
+		{"重试后就绪", 2, http.StatusOK, "", 3},
+		{"4xx 视为就绪", 0, http.StatusNotFound, "", 1},
+		{"始终 5xx 超时", 100, http.StatusOK, "returned status 503", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			err := ProbeUpstream(ctx, server.Client(), server.URL+"/healthz", 10*time.Millisecond)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ProbeUpstream() error = %v", err)
+				}
+				if got := calls.Load(); got != tt.wantCalls {
+					t.Errorf("Expected %d probe requests, got %d", tt.wantCalls, got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ProbeUpstream() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestProbeUpstreamUnreachable 测试上游无法连接时返回连接错误
+func TestProbeUpstreamUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := ProbeUpstream(ctx, http.DefaultClient, url, 10*time.Millisecond); err == nil {
+		t.Error("Expected error for unreachable upstream")
+	}
+}
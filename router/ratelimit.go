@@ -0,0 +1,92 @@
+package router
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterIdleTimeout 超过该时长未使用的限流器会被回收
+const limiterIdleTimeout = 10 * time.Minute
+
+// RateLimiters 按 key 保存的令牌桶（如 "routeID|clientIP"、"routeID"）
+// 参数变化时原地调整已有令牌桶，长时间未使用的令牌桶在后续调用时回收
+type RateLimiters struct {
+	mu        sync.Mutex
+	limiters  map[string]*limiterEntry
+	lastSweep time.Time
+}
+
+// limiterEntry 令牌桶和最近一次使用时间
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// NewRateLimiters 创建 RateLimiters
+func NewRateLimiters() *RateLimiters {
+	return &RateLimiters{limiters: make(map[string]*limiterEntry)}
+}
+
+// Allow 从 key 对应的令牌桶中取出一个令牌，桶为空时返回 false 和下一个令牌的等待时间
+func (l *RateLimiters) Allow(key string, rps float64, burst int, now time.Time) (bool, time.Duration) {
+	reservation := l.limiter(key, rps, burst, now).ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Second
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// 不等待令牌，归还本次预留
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// Limiter 返回 key 对应的令牌桶（不存在时创建），用于按字节数等待的带宽限制
+func (l *RateLimiters) Limiter(key string, rps float64, burst int) *rate.Limiter {
+	return l.limiter(key, rps, burst, time.Now())
+}
+
+// Len 返回当前保存的令牌桶数量
+func (l *RateLimiters) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.limiters)
+}
+
+// limiter 返回 key 对应的令牌桶并更新参数和使用时间
+func (l *RateLimiters) limiter(key string, rps float64, burst int, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	entry, exists := l.limiters[key]
+	if !exists {
+		entry = &limiterEntry{limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+		l.limiters[key] = entry
+	} else {
+		if entry.limiter.Limit() != rate.Limit(rps) {
+			entry.limiter.SetLimitAt(now, rate.Limit(rps))
+		}
+		if entry.limiter.Burst() != burst {
+			entry.limiter.SetBurstAt(now, burst)
+		}
+	}
+	entry.lastUsed = now
+	return entry.limiter
+}
+
+// sweep 回收长时间未使用的令牌桶，每个回收周期最多执行一次（调用方需持有锁）
+func (l *RateLimiters) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterIdleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for key, entry := range l.limiters {
+		if now.Sub(entry.lastUsed) > limiterIdleTimeout {
+			delete(l.limiters, key)
+		}
+	}
+}
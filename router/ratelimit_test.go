@@ -0,0 +1,55 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRateLimitersAllow 测试令牌桶的突发、补充和参数调整
+func TestRateLimitersAllow(t *testing.T) {
+	limiters := NewRateLimiters()
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiters.Allow("vscode", 1, 3, now); !ok {
+			t.Fatalf("Expected request %d within burst to be allowed", i+1)
+		}
+	}
+	ok, retryAfter := limiters.Allow("vscode", 1, 3, now)
+	if ok {
+		t.Fatal("Expected request beyond burst to be rejected")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("retryAfter = %v, want (0, 1s]", retryAfter)
+	}
+
+	// 被拒绝的请求不消耗令牌，1 秒后补充一个令牌
+	if ok, _ := limiters.Allow("vscode", 1, 3, now.Add(time.Second)); !ok {
+		t.Error("Expected token to be refilled after 1s")
+	}
+
+	// 其他 key 使用独立的令牌桶
+	if ok, _ := limiters.Allow("jupyter", 1, 3, now); !ok {
+		t.Error("Expected another key to have its own bucket")
+	}
+
+	// 提高速率后按新速率补充令牌
+	raised := now.Add(time.Second + 10*time.Millisecond)
+	limiters.Allow("vscode", 100, 3, raised)
+	if ok, _ := limiters.Allow("vscode", 100, 3, raised.Add(20*time.Millisecond)); !ok {
+		t.Error("Expected raised rate to refill tokens")
+	}
+}
+
+// TestRateLimitersSweep 测试长时间未使用的令牌桶被回收
+func TestRateLimitersSweep(t *testing.T) {
+	limiters := NewRateLimiters()
+	now := time.Now()
+
+	limiters.Allow("idle", 1, 1, now)
+	limiters.Allow("busy", 1, 1, now.Add(limiterIdleTimeout+time.Second))
+
+	if limiters.Len() != 1 {
+		t.Errorf("Len() = %d, want 1 after idle limiter is swept", limiters.Len())
+	}
+}
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 分享令牌校验失败的原因
+var (
+	ErrShareTokenInvalid = errors.New("invalid share token")
+	ErrShareTokenExpired = errors.New("share token expired")
+)
+
+// ShareClaims 分享令牌的内容
+// Host 限定令牌只能用于一个预览域名；Nonce 为签发时 gitspace 的 share-nonce 注解值，轮换注解即可吊销令牌
+type ShareClaims struct {
+	Identifier string `json:"id"`
+	Host       string `json:"host"`
+	Nonce      string `json:"nonce,omitempty"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// SignShareToken 用 HMAC-SHA256 签发分享令牌，格式为 base64url(claims).base64url(signature)
+func SignShareToken(key []byte, claims ShareClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode share claims: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(shareSignature(key, encoded)), nil
+}
+
+// VerifyShareToken 校验分享令牌的签名和有效期，返回令牌内容
+func VerifyShareToken(key []byte, token string, now time.Time) (*ShareClaims, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrShareTokenInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, shareSignature(key, encoded)) {
+		return nil, ErrShareTokenInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrShareTokenInvalid
+	}
+	var claims ShareClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrShareTokenInvalid
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrShareTokenExpired
+	}
+	return &claims, nil
+}
+
+// shareSignature 计算令牌内容的 HMAC-SHA256 签名
+func shareSignature(key []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,53 @@
+package router
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestShareToken 测试分享令牌的签发和校验
+func TestShareToken(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Now()
+	claims := ShareClaims{
+		Identifier: "vscode",
+		Host:       "3000-vscode.example.com",
+		Nonce:      "n1",
+		ExpiresAt:  now.Add(time.Hour).Unix(),
+	}
+
+	token, err := SignShareToken(key, claims)
+	if err != nil {
+		t.Fatalf("SignShareToken() error = %v", err)
+	}
+
+	got, err := VerifyShareToken(key, token, now)
+	if err != nil {
+		t.Fatalf("VerifyShareToken() error = %v", err)
+	}
+	if *got != claims {
+		t.Errorf("VerifyShareToken() = %+v, want %+v", *got, claims)
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{"过期", key, token, now.Add(2 * time.Hour), ErrShareTokenExpired},
+		{"密钥不同", []byte("another-key-another-key-another-k"), token, now, ErrShareTokenInvalid},
+		{"内容被篡改", key, "e30" + token[3:], now, ErrShareTokenInvalid},
+		{"格式错误", key, "not-a-token", now, ErrShareTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyShareToken(tt.key, tt.token, tt.now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyShareToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"net"
+	"strconv"
+)
+
+// JoinTarget 将 IP 和端口拼接为上游地址，IPv6 地址使用方括号（如 "[fd00::1]:8080"）
+func JoinTarget(ip string, port int) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
+// NormalizeTargetAddr 将上游地址转换为规范形式，便于比较
+// IP 地址统一为 net.IP.String() 的格式（IPv6 压缩写法、IPv4 映射地址还原为 IPv4）；
+// 无法解析的地址（如主机名、旧版本写入的未加方括号的 IPv6 地址）原样返回
+func NormalizeTargetAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// SameTarget 判断两个上游地址是否指向同一 IP 和端口
+func SameTarget(a, b string) bool {
+	return NormalizeTargetAddr(a) == NormalizeTargetAddr(b)
+}
@@ -0,0 +1,39 @@
+package router
+
+import "testing"
+
+// TestJoinTarget 测试 IPv4 和 IPv6 上游地址拼接
+func TestJoinTarget(t *testing.T) {
+	tests := []struct {
+		ip   string
+		port int
+		want string
+	}{
+		{"10.0.0.1", 8080, "10.0.0.1:8080"},
+		{"fd00::1", 8080, "[fd00::1]:8080"},
+		{"fd00:0:0:0:0:0:0:1", 8080, "[fd00::1]:8080"},
+	}
+
+	for _, tt := range tests {
+		if got := JoinTarget(tt.ip, tt.port); got != tt.want {
+			t.Errorf("JoinTarget(%q, %d) = %q, want %q", tt.ip, tt.port, got, tt.want)
+		}
+	}
+}
+
+// TestSameTarget 测试不同写法的同一地址比较
+func TestSameTarget(t *testing.T) {
+	if !SameTarget("[fd00:0::1]:8080", "[fd00::1]:8080") {
+		t.Error("Expected equivalent IPv6 addresses to match")
+	}
+	if SameTarget("[fd00::1]:8080", "[fd00::2]:8080") {
+		t.Error("Expected different IPv6 addresses not to match")
+	}
+	if SameTarget("10.0.0.1:8080", "10.0.0.1:8081") {
+		t.Error("Expected different ports not to match")
+	}
+	// 旧版本写入的未加方括号的 IPv6 地址原样保留，与正确格式不相同，路由会被替换
+	if got := NormalizeTargetAddr("fd00::1:8080"); got != "fd00::1:8080" {
+		t.Errorf("Expected invalid address unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,183 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// TLSPolicySpec 描述一条按 SNI 要求客户端证书的 TLS 连接策略
+type TLSPolicySpec struct {
+	ID                string   // @id
+	ServerNames       []string // match.sni
+	ServerNamePattern string   // 按正则匹配 SNI（match.sni_regexp），设置后忽略 ServerNames
+	ClientCAFiles     []string // 签发客户端证书的 CA（PEM 文件）
+}
+
+// ApplyTLSPolicy 按 TLSPolicySpec 创建或更新服务器的 TLS 连接策略（幂等操作）
+// 新策略插入到最前面，优先于 Caddyfile 生成的策略；服务器还没有任何策略时同时追加一条空的兜底策略，
+// 保证其他域名的握手行为不变
+func (c *AdminAPIClient) ApplyTLSPolicy(ctx context.Context, spec TLSPolicySpec) error {
+	if spec.ID == "" {
+		return fmt.Errorf("policy id cannot be empty")
+	}
+	if len(spec.ServerNames) == 0 && spec.ServerNamePattern == "" {
+		return fmt.Errorf("server names cannot be empty")
+	}
+	if len(spec.ClientCAFiles) == 0 {
+		return fmt.Errorf("client CA files cannot be empty")
+	}
+
+	release, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	policyConfig := buildTLSPolicyConfig(spec)
+
+	// 策略和路由共用 /id/ 端点
+	existing, err := c.getRawRoute(ctx, spec.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check existing tls policy: %w", err)
+	}
+	if existing != nil {
+		if sameRouteConfig(existing, policyConfig) {
+			return nil
+		}
+		return c.replaceRoute(ctx, spec.ID, policyConfig)
+	}
+
+	policies, err := c.listTLSPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	url := c.tlsPoliciesURL()
+	if len(policies) == 0 {
+		return c.sendConfig(ctx, http.MethodPost, url, []map[string]any{policyConfig, {}})
+	}
+	// PUT 到数组下标表示插入
+	return c.sendConfig(ctx, http.MethodPut, url+"/0", policyConfig)
+}
+
+// DeleteTLSPolicy 删除 TLS 连接策略，策略不存在时不返回错误（幂等）
+func (c *AdminAPIClient) DeleteTLSPolicy(ctx context.Context, policyID string) error {
+	if policyID == "" {
+		return fmt.Errorf("policy id cannot be empty")
+	}
+
+	release, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return c.deleteRoute(ctx, policyID)
+}
+
+// ListTLSPolicyIDs 列出服务器中带有 @id 的 TLS 连接策略 ID
+func (c *AdminAPIClient) ListTLSPolicyIDs(ctx context.Context) ([]string, error) {
+	policies, err := c.listTLSPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, policy := range policies {
+		if id, ok := policy["@id"].(string); ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// listTLSPolicies 查询服务器的 TLS 连接策略，未配置时返回空列表
+func (c *AdminAPIClient) listTLSPolicies(ctx context.Context) ([]map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.tlsPoliciesURL(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Caddy Admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Caddy Admin API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var policies []map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&policies); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return policies, nil
+}
+
+// sendConfig 将 payload 以 JSON 写入指定配置路径
+func (c *AdminAPIClient) sendConfig(ctx context.Context, method, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Caddy Admin API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("Caddy Admin API error: %d - %s", resp.StatusCode, string(respBody))
+}
+
+// tlsPoliciesURL 返回服务器 TLS 连接策略的配置路径
+func (c *AdminAPIClient) tlsPoliciesURL() string {
+	return fmt.Sprintf("%s/config/apps/http/servers/%s/tls_connection_policies", c.baseURL, c.serverName)
+}
+
+// buildTLSPolicyConfig 将 TLSPolicySpec 转换为 Caddy TLS 连接策略 JSON 结构
+func buildTLSPolicyConfig(spec TLSPolicySpec) map[string]any {
+	match := map[string]any{}
+	if spec.ServerNamePattern != "" {
+		match["sni_regexp"] = map[string]string{"pattern": spec.ServerNamePattern}
+	} else {
+		names := make([]string, 0, len(spec.ServerNames))
+		for _, name := range spec.ServerNames {
+			names = append(names, strings.ToLower(name))
+		}
+		match["sni"] = names
+	}
+
+	return map[string]any{
+		"@id":   spec.ID,
+		"match": match,
+		"client_authentication": map[string]any{
+			"ca": map[string]any{
+				"provider":  "file",
+				"pem_files": spec.ClientCAFiles,
+			},
+			"mode": "require_and_verify",
+		},
+	}
+}
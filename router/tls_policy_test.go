@@ -0,0 +1,104 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestApplyTLSPolicy 测试 TLS 连接策略的插入位置和兜底策略
+func TestApplyTLSPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   string
+		wantMethod string
+		wantPath   string
+		wantLen    int
+	}{
+		{"服务器没有策略时追加兜底策略", "null", http.MethodPost, "/config/apps/http/servers/srv0/tls_connection_policies", 2},
+		{"已有策略时插入到最前面", `[{"match":{"sni":["example.com"]}}]`, http.MethodPut, "/config/apps/http/servers/srv0/tls_connection_policies/0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path string
+			var body []byte
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/id/"):
+					w.WriteHeader(http.StatusNotFound)
+				case r.Method == http.MethodGet:
+					w.Write([]byte(tt.existing))
+				default:
+					method, path = r.Method, r.URL.Path
+					body, _ = io.ReadAll(r.Body)
+				}
+			}))
+			defer server.Close()
+
+			client := NewAdminAPIClient(server.URL, "srv0")
+			err := client.ApplyTLSPolicy(context.Background(), TLSPolicySpec{
+				ID:            "gitspace-mtls:vscode",
+				ServerNames:   []string{"VSCode.example.com"},
+				ClientCAFiles: []string{"/etc/caddy/client-ca.pem"},
+			})
+			if err != nil {
+				t.Fatalf("ApplyTLSPolicy() error = %v", err)
+			}
+			if method != tt.wantMethod || path != tt.wantPath {
+				t.Fatalf("request = %s %s, want %s %s", method, path, tt.wantMethod, tt.wantPath)
+			}
+
+			if tt.wantLen > 0 {
+				var policies []map[string]any
+				if err := json.Unmarshal(body, &policies); err != nil || len(policies) != tt.wantLen {
+					t.Fatalf("policies = %s, want %d policies", body, tt.wantLen)
+				}
+				if len(policies[1]) != 0 {
+					t.Errorf("fallback policy = %v, want empty policy", policies[1])
+				}
+				return
+			}
+
+			var policy map[string]any
+			if err := json.Unmarshal(body, &policy); err != nil {
+				t.Fatalf("invalid policy %s: %v", body, err)
+			}
+			sni := policy["match"].(map[string]any)["sni"].([]any)
+			if len(sni) != 1 || sni[0] != "vscode.example.com" {
+				t.Errorf("sni = %v, want [vscode.example.com]", sni)
+			}
+			auth := policy["client_authentication"].(map[string]any)
+			if auth["mode"] != "require_and_verify" {
+				t.Errorf("mode = %v, want require_and_verify", auth["mode"])
+			}
+		})
+	}
+}
+
+// TestApplyRouteWithAllowCIDRs 测试地址段限制生成的 remote_ip 拒绝子路由
+func TestApplyRouteWithAllowCIDRs(t *testing.T) {
+	spec := RouteSpec{
+		ID:         "vscode",
+		Domain:     "vscode.example.com",
+		Upstream:   "10.0.0.1:8080",
+		Handlers:   []map[string]any{{"handler": "gitspace_access"}},
+		AllowCIDRs: []string{"10.8.0.0/16"},
+	}
+
+	handle := buildRouteConfig(spec)["handle"].([]map[string]any)
+	if len(handle) != 3 || handle[0]["handler"] != "subroute" || handle[1]["handler"] != "gitspace_access" {
+		t.Fatalf("handle = %v, want deny subroute before other handlers", handle)
+	}
+
+	payload, _ := json.Marshal(handle[0])
+	if !strings.Contains(string(payload), `"not":[{"remote_ip":{"ranges":["10.8.0.0/16"]}}]`) ||
+		!strings.Contains(string(payload), `"status_code":403`) {
+		t.Errorf("deny subroute = %s", payload)
+	}
+}
@@ -1,19 +1,20 @@
 package router
 
 import (
+	"slices"
 	"sync"
 )
 
 // RouteInfo 路由信息（包含 RouteID 和目标地址）
 type RouteInfo struct {
 	RouteID    string // Caddy 路由 ID
-	TargetAddr string // 目标地址（格式: "ip:port"）
+	TargetAddr string // 目标地址（格式: "ip:port"，IPv6 为 "[ip]:port"）
 }
 
 // RouteIDTracker 维护 Deployment 到 Route 信息的映射
 // 线程安全，缓存 Pod IP 和端口以避免频繁查询 Caddy Admin API
 type RouteIDTracker struct {
-	// routes 映射: deploymentKey (namespace/name) → RouteInfo
+	// routes 映射: workloadKey (kind/namespace/name) → RouteInfo
 	routes map[string]*RouteInfo
 	mu     sync.RWMutex
 }
@@ -26,27 +27,28 @@ func NewRouteIDTracker() *RouteIDTracker {
 }
 
 // Set 记录 Deployment 到 Route 信息的映射
-func (t *RouteIDTracker) Set(deploymentKey, routeID, targetAddr string) {
+// targetAddr 会被规范化，同一 IP 的不同写法比较时视为相同
+func (t *RouteIDTracker) Set(workloadKey, routeID, targetAddr string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.routes[deploymentKey] = &RouteInfo{
+	t.routes[workloadKey] = &RouteInfo{
 		RouteID:    routeID,
-		TargetAddr: targetAddr,
+		TargetAddr: NormalizeTargetAddr(targetAddr),
 	}
 }
 
 // Get 查询 Deployment 对应的 Route 信息
 // 返回 RouteInfo 和 exists 标志
-func (t *RouteIDTracker) Get(deploymentKey string) (*RouteInfo, bool) {
+func (t *RouteIDTracker) Get(workloadKey string) (*RouteInfo, bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	info, exists := t.routes[deploymentKey]
+	info, exists := t.routes[workloadKey]
 	return info, exists
 }
 
 // GetRouteID 仅查询 Route ID（兼容旧代码）
-func (t *RouteIDTracker) GetRouteID(deploymentKey string) (string, bool) {
-	info, exists := t.Get(deploymentKey)
+func (t *RouteIDTracker) GetRouteID(workloadKey string) (string, bool) {
+	info, exists := t.Get(workloadKey)
 	if !exists || info == nil {
 		return "", false
 	}
@@ -54,14 +56,48 @@ func (t *RouteIDTracker) GetRouteID(deploymentKey string) (string, bool) {
 }
 
 // Delete 删除 Deployment 的映射
-func (t *RouteIDTracker) Delete(deploymentKey string) {
+func (t *RouteIDTracker) Delete(workloadKey string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	delete(t.routes, deploymentKey)
+	delete(t.routes, workloadKey)
+}
+
+// Holds 判断工作负载当前是否持有指定路由
+func (t *RouteIDTracker) Holds(workloadKey, routeID string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	info, exists := t.routes[workloadKey]
+	return exists && info != nil && info.RouteID == routeID
+}
+
+// StaleRoute 返回工作负载持有的、与 routeID 不同的旧路由 ID
+// 未持有路由或已持有 routeID 时返回 false
+func (t *RouteIDTracker) StaleRoute(workloadKey, routeID string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	info, exists := t.routes[workloadKey]
+	if !exists || info == nil || info.RouteID == routeID {
+		return "", false
+	}
+	return info.RouteID, true
+}
+
+// Holders 返回除 exceptKey 外持有指定路由的工作负载，按 key 排序
+func (t *RouteIDTracker) Holders(routeID, exceptKey string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var keys []string
+	for k, v := range t.routes {
+		if k != exceptKey && v != nil && v.RouteID == routeID {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
 }
 
 // List 列出所有映射（用于调试）
-// 返回 deploymentKey → RouteInfo 的映射副本
+// 返回 workloadKey → RouteInfo 的映射副本
 func (t *RouteIDTracker) List() map[string]*RouteInfo {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
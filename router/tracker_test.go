@@ -0,0 +1,90 @@
+package router
+
+import (
+	"slices"
+	"testing"
+)
+
+// newTestTracker 创建包含以下映射的 Tracker：a → gitspace-ws，b → gitspace-ws，c → gitspace-other
+func newTestTracker() *RouteIDTracker {
+	tracker := NewRouteIDTracker()
+	tracker.Set("Deployment/default/a", "gitspace-ws", "10.0.0.1:8080")
+	tracker.Set("Deployment/default/b", "gitspace-ws", "10.0.0.2:8080")
+	tracker.Set("Deployment/default/c", "gitspace-other", "10.0.0.3:8080")
+	return tracker
+}
+
+// TestRouteIDTrackerHolds 测试工作负载是否持有指定路由，用于判断失败方是否需要交还路由
+func TestRouteIDTrackerHolds(t *testing.T) {
+	tracker := newTestTracker()
+
+	tests := []struct {
+		name        string
+		workloadKey string
+		routeID     string
+		want        bool
+	}{
+		{"持有该路由", "Deployment/default/a", "gitspace-ws", true},
+		{"持有其他路由", "Deployment/default/c", "gitspace-ws", false},
+		{"未跟踪的工作负载", "Deployment/default/missing", "gitspace-ws", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tracker.Holds(tt.workloadKey, tt.routeID); got != tt.want {
+				t.Errorf("Holds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRouteIDTrackerStaleRoute 测试 identifier 变化时需要删除的旧路由
+func TestRouteIDTrackerStaleRoute(t *testing.T) {
+	tracker := newTestTracker()
+
+	tests := []struct {
+		name        string
+		workloadKey string
+		routeID     string
+		wantID      string
+		wantOK      bool
+	}{
+		{"持有旧 identifier 的路由", "Deployment/default/a", "gitspace-new", "gitspace-ws", true},
+		{"已持有新 identifier 的路由", "Deployment/default/a", "gitspace-ws", "", false},
+		{"未跟踪的工作负载", "Deployment/default/missing", "gitspace-new", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotID, gotOK := tracker.StaleRoute(tt.workloadKey, tt.routeID)
+			if gotID != tt.wantID || gotOK != tt.wantOK {
+				t.Errorf("StaleRoute() = (%q, %v), want (%q, %v)", gotID, gotOK, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestRouteIDTrackerHolders 测试持有同一路由的其他工作负载，用于清理重复声明和判断排空后的路由归属
+func TestRouteIDTrackerHolders(t *testing.T) {
+	tracker := newTestTracker()
+
+	tests := []struct {
+		name      string
+		routeID   string
+		exceptKey string
+		want      []string
+	}{
+		{"排除所有者", "gitspace-ws", "Deployment/default/a", []string{"Deployment/default/b"}},
+		{"不排除任何工作负载", "gitspace-ws", "", []string{"Deployment/default/a", "Deployment/default/b"}},
+		{"只有所有者持有", "gitspace-other", "Deployment/default/c", nil},
+		{"没有工作负载持有", "gitspace-missing", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tracker.Holders(tt.routeID, tt.exceptKey); !slices.Equal(got, tt.want) {
+				t.Errorf("Holders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,481 @@
+package caddy2k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddypki"
+	"github.com/ysicing/caddy2-gitspace/k8s"
+	"go.uber.org/zap"
+)
+
+// upstreamCertCheckInterval 检查 gitspace 证书和客户端证书是否需要轮换的间隔
+const upstreamCertCheckInterval = 5 * time.Minute
+
+// upstreamClientCommonName Caddy 访问上游时使用的客户端证书 CN
+const upstreamClientCommonName = "caddy-gitspace"
+
+// upstreamTLSCA 最近启动的配置中签发上游证书的 pki CA
+// 控制器跨配置重载复用，而 pki app 随每次配置加载重新创建，签发时总是使用最新的 CA
+var upstreamTLSCA atomic.Pointer[caddypki.CA]
+
+// loadUpstreamCA 从 pki app 获取签发上游证书的 CA（local CA 未配置时自动创建）
+func loadUpstreamCA(ctx caddy.Context, id string) (*caddypki.CA, error) {
+	app, err := ctx.App("pki")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pki app: %w", err)
+	}
+	ca, err := app.(*caddypki.PKI).GetCA(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pki CA %q: %w", id, err)
+	}
+	return ca, nil
+}
+
+// upstreamCA 签发证书时使用的根证书、中间证书和中间证书私钥
+type upstreamCA struct {
+	root  *x509.Certificate
+	inter *x509.Certificate
+	key   crypto.Signer
+}
+
+// currentUpstreamCA 返回最新配置中 CA 的证书和签名私钥
+func currentUpstreamCA() (upstreamCA, error) {
+	ca := upstreamTLSCA.Load()
+	if ca == nil {
+		return upstreamCA{}, errors.New("pki CA for upstream TLS is not loaded")
+	}
+	key, ok := ca.IntermediateKey().(crypto.Signer)
+	if !ok {
+		return upstreamCA{}, errors.New("pki intermediate key cannot sign certificates")
+	}
+	root, inter := ca.RootCertificate(), ca.IntermediateCertificate()
+	if root == nil || inter == nil {
+		return upstreamCA{}, errors.New("pki CA has no root or intermediate certificate")
+	}
+	return upstreamCA{root: root, inter: inter, key: key}, nil
+}
+
+// issuedCert 签发的证书、中间证书和私钥
+type issuedCert struct {
+	leaf    *x509.Certificate
+	chain   []*x509.Certificate
+	certPEM []byte // 证书和中间证书
+	keyPEM  []byte
+}
+
+// upstreamIssuer 使用 pki CA 签发 gitspace 的服务端证书和 Caddy 访问上游的客户端证书
+type upstreamIssuer struct {
+	lifetime time.Duration
+	// dir 客户端证书文件目录，reverse_proxy 的 transport 只能从文件加载客户端证书
+	dir string
+
+	mu       sync.Mutex
+	client   *issuedCert
+	certFile string
+	keyFile  string
+}
+
+// newUpstreamIssuer 创建 upstreamIssuer，客户端证书保存在 Caddy 数据目录下
+func newUpstreamIssuer(lifetime time.Duration) *upstreamIssuer {
+	return &upstreamIssuer{
+		lifetime: lifetime,
+		dir:      filepath.Join(caddy.AppDataDir(), "gitspace", "upstream-tls"),
+	}
+}
+
+// issue 使用中间证书签发 ECDSA P-256 证书，有效期不超过中间证书
+func (i *upstreamIssuer) issue(ca upstreamCA, template *x509.Certificate, now time.Time) (*issuedCert, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	template.SerialNumber = serial
+	template.NotBefore = now.Add(-time.Minute) // 容忍少量时钟偏差
+	template.NotAfter = now.Add(i.lifetime)
+	if template.NotAfter.After(ca.inter.NotAfter) {
+		template.NotAfter = ca.inter.NotAfter
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.inter, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse issued certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	certPEM := append(encodeCertPEM(der), encodeCertPEM(ca.inter.Raw)...)
+	return &issuedCert{
+		leaf:    leaf,
+		chain:   []*x509.Certificate{ca.inter},
+		certPEM: certPEM,
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// secretData 返回 gitspace Secret 的新数据；已有证书仍然有效时返回 nil
+// 证书剩余有效期不足三分之一、CA 变化或 SAN 与 identifier 不一致时重新签发
+func (i *upstreamIssuer) secretData(existing map[string][]byte, identifier string, now time.Time) (map[string][]byte, error) {
+	ca, err := currentUpstreamCA()
+	if err != nil {
+		return nil, err
+	}
+
+	rootPEM := encodeCertPEM(ca.root.Raw)
+	if bytes.Equal(existing[k8s.UpstreamTLSCAKey], rootPEM) {
+		_, pairErr := tls.X509KeyPair(existing[k8s.UpstreamTLSCertKey], existing[k8s.UpstreamTLSKeyKey])
+		chain, err := parseCertChain(existing[k8s.UpstreamTLSCertKey])
+		if pairErr == nil && err == nil && !needsRenewal(ca, chain[0], chain[1:], identifier, x509.ExtKeyUsageServerAuth, now) {
+			return nil, nil
+		}
+	}
+
+	cert, err := i.issue(ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: identifier},
+		DNSNames:    []string{identifier},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		k8s.UpstreamTLSCAKey:   rootPEM,
+		k8s.UpstreamTLSCertKey: cert.certPEM,
+		k8s.UpstreamTLSKeyKey:  cert.keyPEM,
+	}, nil
+}
+
+// clientCert 返回当前的客户端证书，不存在、即将过期或不再由当前 CA 签发时重新签发并写入文件
+// 文件名包含证书序列号，路由引用新文件后 Caddy 在配置重载时加载新证书
+func (i *upstreamIssuer) clientCert(now time.Time) (*issuedCert, string, string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	ca, err := currentUpstreamCA()
+	if err != nil {
+		return nil, "", "", err
+	}
+	if i.client != nil && !needsRenewal(ca, i.client.leaf, i.client.chain, "", x509.ExtKeyUsageClientAuth, now) {
+		return i.client, i.certFile, i.keyFile, nil
+	}
+
+	cert, err := i.issue(ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: upstreamClientCommonName},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, now)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if err := os.MkdirAll(i.dir, 0o700); err != nil {
+		return nil, "", "", fmt.Errorf("failed to create upstream TLS directory: %w", err)
+	}
+	name := fmt.Sprintf("client-%x", cert.leaf.SerialNumber)
+	certFile := filepath.Join(i.dir, name+".crt")
+	keyFile := filepath.Join(i.dir, name+".key")
+	if err := os.WriteFile(keyFile, cert.keyPEM, 0o600); err != nil {
+		return nil, "", "", fmt.Errorf("failed to write client key: %w", err)
+	}
+	if err := os.WriteFile(certFile, cert.certPEM, 0o644); err != nil {
+		return nil, "", "", fmt.Errorf("failed to write client certificate: %w", err)
+	}
+
+	i.client, i.certFile, i.keyFile = cert, certFile, keyFile
+	return cert, certFile, keyFile, nil
+}
+
+// pruneClientCerts 删除早已过期的客户端证书文件（调用方需确认路由已引用当前证书）
+func (i *upstreamIssuer) pruneClientCerts(now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	entries, err := os.ReadDir(i.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(i.dir, entry.Name())
+		if !strings.HasPrefix(entry.Name(), "client-") || path == i.certFile || path == i.keyFile {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && now.Sub(info.ModTime()) > 2*i.lifetime {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// transport 返回访问 gitspace 上游的 reverse_proxy transport 配置
+// 只信任 pki 根证书，并要求上游证书的 SAN 与 gitspace identifier 一致
+func (i *upstreamIssuer) transport(identifier string) (map[string]any, error) {
+	_, certFile, keyFile, err := i.clientCert(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ca, err := currentUpstreamCA()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"protocol": "http",
+		"tls": map[string]any{
+			"ca": map[string]any{
+				"provider":         "inline",
+				"trusted_ca_certs": []string{base64.StdEncoding.EncodeToString(ca.root.Raw)},
+			},
+			"server_name":                 identifier,
+			"client_certificate_file":     certFile,
+			"client_certificate_key_file": keyFile,
+		},
+	}, nil
+}
+
+// clientTLSConfig 返回与路由 transport 一致的 TLS 配置，用于 route-ready 上游探测
+func (i *upstreamIssuer) clientTLSConfig(identifier string) (*tls.Config, error) {
+	cert, _, _, err := i.clientCert(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ca, err := currentUpstreamCA()
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(cert.certPEM, cert.keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.root)
+	return &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		RootCAs:      roots,
+		ServerName:   identifier,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// needsRenewal 判断证书是否需要重新签发：剩余有效期不足三分之一，或无法用当前 CA 校验（含 SAN 和用途）
+func needsRenewal(ca upstreamCA, leaf *x509.Certificate, chain []*x509.Certificate, dnsName string, usage x509.ExtKeyUsage, now time.Time) bool {
+	if now.After(leaf.NotAfter.Add(-leaf.NotAfter.Sub(leaf.NotBefore) / 3)) {
+		return true
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.root)
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err != nil
+}
+
+// parseCertChain 解析 PEM 证书链，第一个证书为叶子证书
+func parseCertChain(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs, nil
+}
+
+// encodeCertPEM 将 DER 证书编码为 PEM
+func encodeCertPEM(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// upstreamTransport 返回工作负载路由的上游 transport，未开启 upstream_tls 时返回 nil
+func (h *EventHandler) upstreamTransport(identifier string) (map[string]any, error) {
+	if h.upstreamTLS == nil {
+		return nil, nil
+	}
+	transport, err := h.upstreamTLS.transport(identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare upstream TLS transport: %w", err)
+	}
+	return transport, nil
+}
+
+// syncUpstreamSecret 发布工作负载的上游证书 Secret（调用方需持有工作负载锁）
+// Pod 启动时就需要挂载 Secret，因此不等待工作负载就绪；identifier 被其他工作负载占用时由所有者发布
+func (h *EventHandler) syncUpstreamSecret(workload k8s.Workload) error {
+	if h.upstreamTLS == nil || workload.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	identifier := k8s.GetGitspaceIdentifier(workload)
+	if identifier == "" {
+		return nil
+	}
+	if owner := h.identifierOwner(identifier); owner != nil && k8s.WorkloadKey(owner) != k8s.WorkloadKey(workload) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, 10*time.Second)
+	defer cancel()
+
+	name := k8s.UpstreamTLSSecretName(identifier)
+	secret, err := k8s.GetUpstreamTLSSecret(ctx, h.k8sClient, workload.GetNamespace(), name)
+	if err != nil {
+		return err
+	}
+	var existing map[string][]byte
+	if secret != nil {
+		existing = secret.Data
+	}
+
+	data, err := h.upstreamTLS.secretData(existing, identifier, time.Now())
+	if err != nil || data == nil {
+		return err
+	}
+	if err := k8s.ApplyUpstreamTLSSecret(ctx, h.k8sClient, workload, name, data); err != nil {
+		return err
+	}
+
+	h.logger.Info("Upstream TLS certificate issued",
+		zap.String("workload", k8s.WorkloadKey(workload)),
+		zap.String("gitspace_identifier", identifier),
+		zap.String("secret", workload.GetNamespace()+"/"+name),
+	)
+	return nil
+}
+
+// runUpstreamCertRotation 定期轮换即将过期的 gitspace 证书和 Caddy 客户端证书
+func (c *routerController) runUpstreamCertRotation() {
+	ticker := time.NewTicker(upstreamCertCheckInterval)
+	defer ticker.Stop()
+
+	// appliedCertFile 所有路由已引用的客户端证书文件
+	var appliedCertFile string
+	for {
+		select {
+		case <-ticker.C:
+			appliedCertFile = c.rotateUpstreamCerts(appliedCertFile)
+		case <-c.ctx.Done():
+			c.logger.Info("Stopping upstream certificate rotation")
+			return
+		}
+	}
+}
+
+// rotateUpstreamCerts 检查所有工作负载的证书 Secret，客户端证书变化后重新写入所有路由
+// 返回所有路由已引用的客户端证书文件，部分路由写入失败时返回 applied，下次继续重试
+func (c *routerController) rotateUpstreamCerts(applied string) string {
+	if !c.isActive() {
+		return applied
+	}
+	h := c.eventHandler
+
+	workloads, err := c.watcher.ListWorkloads()
+	if err != nil {
+		c.logger.Warn("Failed to list workloads for certificate rotation", zap.Error(err))
+		return applied
+	}
+	for _, workload := range workloads {
+		lock := h.getWorkloadLock(k8s.WorkloadKey(workload))
+		lock.Lock()
+		err := h.syncUpstreamSecret(workload)
+		lock.Unlock()
+		if err != nil {
+			c.logger.Warn("Failed to rotate upstream TLS certificate",
+				zap.String("workload", k8s.WorkloadKey(workload)),
+				zap.Error(err),
+			)
+		}
+	}
+
+	_, certFile, _, err := h.upstreamTLS.clientCert(time.Now())
+	if err != nil {
+		c.logger.Warn("Failed to rotate upstream client certificate", zap.Error(err))
+		return applied
+	}
+	if certFile == applied {
+		return applied
+	}
+
+	// 重新同步所有已创建路由的工作负载，ApplyRoute 只更新 transport 变化的路由
+	synced := true
+	for workloadKey := range c.tracker.List() {
+		kind, namespace, name, err := k8s.SplitWorkloadKey(workloadKey)
+		if err != nil {
+			continue
+		}
+		workload, err := c.watcher.GetWorkload(kind, namespace, name)
+		if err != nil {
+			continue
+		}
+
+		lock := h.getWorkloadLock(workloadKey)
+		lock.Lock()
+		err = h.syncWorkload(workload)
+		lock.Unlock()
+		if err != nil {
+			c.logger.Warn("Failed to update route with rotated client certificate",
+				zap.String("workload", workloadKey),
+				zap.Error(err),
+			)
+			synced = false
+		}
+	}
+	if !synced {
+		return applied
+	}
+
+	h.upstreamTLS.pruneClientCerts(time.Now())
+	c.logger.Info("Routes use current upstream client certificate",
+		zap.String("certificate_file", certFile),
+	)
+	return certFile
+}